@@ -13,6 +13,7 @@ import (
 	"github.com/berkkaradalan/stackflow/config"
 	"github.com/berkkaradalan/stackflow/database"
 	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/llm"
 	repository "github.com/berkkaradalan/stackflow/repository/postgres"
 	"github.com/berkkaradalan/stackflow/routes"
 	"github.com/berkkaradalan/stackflow/service"
@@ -28,6 +29,21 @@ func main() {
 		log.Fatal("Failed to load config: ", err)
 	}
 
+	if err := config.Providers().Load(cfg.Env.ProviderRegistryPath); err != nil {
+		log.Fatal("Failed to load provider registry: ", err)
+	}
+
+	if err := llm.LoadMockFixtures(cfg.Env.MockFixturesPath); err != nil {
+		log.Fatal("Failed to load mock provider fixtures: ", err)
+	}
+
+	if err := llm.ConfigureCassettes(cfg.Env.CassetteMode, cfg.Env.CassetteDir); err != nil {
+		log.Fatal("Failed to configure provider cassettes: ", err)
+	}
+	if cfg.Env.CassetteMode != llm.CassetteOff {
+		log.Printf("Provider cassettes in %s mode, directory %s", cfg.Env.CassetteMode, cfg.Env.CassetteDir)
+	}
+
 	pool, err := database.Connect(ctx, cfg.Env)
 	if err != nil {
 		log.Fatal("Failed to connect to database: ", err)
@@ -47,37 +63,103 @@ func main() {
 		log.Fatal("Failed to initialize JWT manager: ", err)
 	}
 
+	keyRing, err := utils.NewKeyRing(
+		cfg.Env.EncryptionMasterKey,
+		cfg.Env.EncryptionKeyVersion,
+		cfg.Env.EncryptionPreviousKeys,
+	)
+	if err != nil {
+		log.Fatal("Failed to initialize encryption key ring: ", err)
+	}
+
 	userRepo := repository.NewUserRepository(pool)
 	inviteTokenRepo := repository.NewInviteTokenRepository(pool)
 	projectRepo := repository.NewProjectRepository(pool)
 	agentRepo := repository.NewAgentRepository(pool)
 	taskRepo := repository.NewTaskRepository(pool)
 	executionPlanRepo := repository.NewExecutionPlanRepository(pool)
-
-	authService := service.NewAuthService(userRepo, jwtManager)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(pool)
+	agentTokenRepo := repository.NewAgentTokenRepository(pool)
+	projectMemberRepo := repository.NewProjectMemberRepository(pool)
+	customProviderRepo := repository.NewCustomProviderRepository(pool)
+	usageRepo := repository.NewUsageRepository(pool)
+	budgetRepo := repository.NewBudgetRepository(pool)
+	agentEventRepo := repository.NewAgentEventRepository(pool)
+	agentHealthCheckRepo := repository.NewAgentHealthCheckRepository(pool)
+	promptTemplateRepo := repository.NewPromptTemplateRepository(pool)
+	taskAttachmentRepo := repository.NewTaskAttachmentRepository(pool)
+	agentAPIKeyRepo := repository.NewAgentAPIKeyRepository(pool)
+	providerCredentialRepo := repository.NewProviderCredentialRepository(pool)
+
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, jwtManager)
 	userService := service.NewUserService(userRepo, inviteTokenRepo)
 	projectService := service.NewProjectService(projectRepo)
-	agentService := service.NewAgentService(agentRepo)
-	providerService := service.NewProviderService()
-	taskService := service.NewTaskService(taskRepo, agentRepo, userRepo, projectRepo)
-	executionPlanService := service.NewExecutionPlanService(executionPlanRepo, projectRepo, agentRepo, taskRepo)
+	providerCredentialService := service.NewProviderCredentialService(providerCredentialRepo, projectRepo, keyRing)
+	budgetService := service.NewBudgetService(budgetRepo, usageRepo, agentRepo, agentEventRepo)
+	agentService := service.NewAgentService(agentRepo, agentEventRepo, agentHealthCheckRepo, agentAPIKeyRepo, providerCredentialService, budgetService, usageRepo, keyRing, cfg.Env.HealthFailureThreshold)
+	providerService := service.NewProviderService(customProviderRepo, agentRepo)
+	taskService := service.NewTaskService(taskRepo, agentRepo, userRepo, projectRepo, taskAttachmentRepo, executionPlanRepo)
+	executionPlanService := service.NewExecutionPlanService(executionPlanRepo, projectRepo, agentRepo, taskRepo, time.Duration(cfg.Env.AssignmentLeaseSeconds)*time.Second)
+	agentTokenService := service.NewAgentTokenService(agentTokenRepo, agentRepo)
+	projectMemberService := service.NewProjectMemberService(projectMemberRepo, projectRepo, userRepo)
+	agentAPIKeyService := service.NewAgentAPIKeyService(agentAPIKeyRepo, agentRepo, providerCredentialService, keyRing)
+	agentTools := service.NewAgentTools(taskService, executionPlanService)
+	promptTemplateService := service.NewPromptTemplateService(promptTemplateRepo, agentRepo, projectRepo, taskRepo, executionPlanRepo)
+	chatService := service.NewChatService(agentRepo, budgetService, taskRepo, executionPlanRepo, usageRepo, taskAttachmentRepo, agentAPIKeyService, agentTools, promptTemplateService, keyRing)
+	embeddingService := service.NewEmbeddingService(agentRepo, projectRepo, budgetService, usageRepo, agentAPIKeyService, keyRing)
+	usageService := service.NewUsageService(usageRepo)
+	accessService := service.NewAccessService(taskRepo, agentRepo, projectMemberRepo)
 
 	authHandler := handler.NewAuthHandler(authService, userService)
 	userHandler := handler.NewUserHandler(userService)
 	projectHandler := handler.NewProjectHandler(projectService)
-	agentHandler := handler.NewAgentHandler(agentService)
+	agentHandler := handler.NewAgentHandler(agentService, accessService)
 	providerHandler := handler.NewProviderHandler(providerService)
 	taskHandler := handler.NewTaskHandler(taskService)
 	executionPlanHandler := handler.NewExecutionPlanHandler(executionPlanService)
+	agentTokenHandler := handler.NewAgentTokenHandler(agentTokenService)
+	agentAPIKeyHandler := handler.NewAgentAPIKeyHandler(agentAPIKeyService)
+	projectMemberHandler := handler.NewProjectMemberHandler(projectMemberService)
+	adminHandler := handler.NewAdminHandler(agentService)
+	chatHandler := handler.NewChatHandler(chatService)
+	usageHandler := handler.NewUsageHandler(usageService)
+	budgetHandler := handler.NewBudgetHandler(budgetService)
+	promptTemplateHandler := handler.NewPromptTemplateHandler(promptTemplateService)
+	embeddingHandler := handler.NewEmbeddingHandler(embeddingService, accessService)
+	providerCredentialHandler := handler.NewProviderCredentialHandler(providerCredentialService, agentAPIKeyService)
+
+	// Encrypt keys stored before encryption was enabled or under a retired master key
+	if result, err := agentService.ReencryptAPIKeys(ctx); err != nil {
+		log.Printf("Failed to re-encrypt agent API keys: %v", err)
+	} else if result.Reencrypted > 0 || len(result.Failed) > 0 || len(result.FailedCredentials) > 0 {
+		log.Printf("Re-encrypted %d agent API keys (%d agents and %d credentials failed)", result.Reencrypted, len(result.Failed), len(result.FailedCredentials))
+	}
+
+	if err := providerService.RefreshCustomProviders(ctx); err != nil {
+		log.Fatal("Failed to load custom providers: ", err)
+	}
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	if cfg.Env.ProviderReloadSeconds > 0 {
+		go providerService.Watch(watchCtx, time.Duration(cfg.Env.ProviderReloadSeconds)*time.Second)
+	}
+	if cfg.Env.HealthCheckSeconds > 0 {
+		go agentService.RunHealthChecks(watchCtx, time.Duration(cfg.Env.HealthCheckSeconds)*time.Second)
+	}
+	if cfg.Env.LeaseReclaimSeconds > 0 {
+		go executionPlanService.RunLeaseReclaimer(watchCtx, time.Duration(cfg.Env.LeaseReclaimSeconds)*time.Second)
+	}
 
-	router := routes.SetupRouter(jwtManager, authHandler, userHandler, projectHandler, agentHandler, providerHandler, taskHandler, executionPlanHandler)
+	router := routes.SetupRouter(jwtManager, authHandler, userHandler, projectHandler, agentHandler, providerHandler, taskHandler, executionPlanHandler, agentTokenHandler, agentAPIKeyHandler, projectMemberHandler, adminHandler, chatHandler, usageHandler, budgetHandler, promptTemplateHandler, embeddingHandler, providerCredentialHandler, agentTokenService, accessService)
 
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("%s:%s", cfg.Env.HostName, cfg.Env.HostPort),
 		Handler:      router,
 		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		// Chat requests wait on the model provider
+		WriteTimeout: llm.DefaultTimeout + 10*time.Second,
 	}
 
 	go func() {
@@ -23,6 +23,18 @@ type Env struct {
 	AdminUsername      string `env:"ADMIN_USERNAME" envDefault:"admin"`
 	AdminEmail         string `env:"ADMIN_EMAIL" envDefault:"admin@localhost"`
 	AdminPassword      string `env:"ADMIN_PASSWORD" envDefault:"admin"`
+	EncryptionMasterKey    string `env:"ENCRYPTION_MASTER_KEY"`
+	EncryptionKeyVersion   int    `env:"ENCRYPTION_KEY_VERSION" envDefault:"1"`
+	EncryptionPreviousKeys string `env:"ENCRYPTION_PREVIOUS_KEYS" envDefault:""`
+	ProviderRegistryPath   string `env:"PROVIDER_REGISTRY_PATH" envDefault:""`
+	ProviderReloadSeconds  int    `env:"PROVIDER_REGISTRY_RELOAD_SECONDS" envDefault:"30"`
+	MockFixturesPath       string `env:"MOCK_LLM_FIXTURES" envDefault:""`
+	CassetteMode           string `env:"LLM_CASSETTE_MODE" envDefault:"off"`
+	CassetteDir            string `env:"LLM_CASSETTE_DIR" envDefault:"cassettes"`
+	HealthCheckSeconds     int    `env:"HEALTH_CHECK_INTERVAL_SECONDS" envDefault:"300"`
+	HealthFailureThreshold int    `env:"HEALTH_CHECK_FAILURE_THRESHOLD" envDefault:"3"`
+	AssignmentLeaseSeconds int    `env:"ASSIGNMENT_LEASE_SECONDS" envDefault:"900"`
+	LeaseReclaimSeconds    int    `env:"ASSIGNMENT_RECLAIM_INTERVAL_SECONDS" envDefault:"60"`
 }
 
 func getEnv(key, defaultValue string) string {
@@ -38,6 +50,12 @@ func LoadEnv() (*Env, error) {
 
 	JWTAccessExpiry, _ := strconv.Atoi(os.Getenv("JWT_ACCESS_EXPIRY_HOURS"))
 	JWTRefreshExpiry, _ := strconv.Atoi(os.Getenv("JWT_REFRESH_EXPIRY_DAYS"))
+	EncryptionKeyVersion, _ := strconv.Atoi(getEnv("ENCRYPTION_KEY_VERSION", "1"))
+	ProviderReloadSeconds, _ := strconv.Atoi(getEnv("PROVIDER_REGISTRY_RELOAD_SECONDS", "30"))
+	HealthCheckSeconds, _ := strconv.Atoi(getEnv("HEALTH_CHECK_INTERVAL_SECONDS", "300"))
+	HealthFailureThreshold, _ := strconv.Atoi(getEnv("HEALTH_CHECK_FAILURE_THRESHOLD", "3"))
+	AssignmentLeaseSeconds, _ := strconv.Atoi(getEnv("ASSIGNMENT_LEASE_SECONDS", "900"))
+	LeaseReclaimSeconds, _ := strconv.Atoi(getEnv("ASSIGNMENT_RECLAIM_INTERVAL_SECONDS", "60"))
 
 	return &Env{
 		Environment:      getEnv("ENVIRONMENT", "production"),
@@ -55,5 +73,17 @@ func LoadEnv() (*Env, error) {
 		AdminUsername:    getEnv("ADMIN_USERNAME", "admin"),
 		AdminEmail:       getEnv("ADMIN_EMAIL", "admin@localhost"),
 		AdminPassword:    getEnv("ADMIN_PASSWORD", "admin"),
+		EncryptionMasterKey:    os.Getenv("ENCRYPTION_MASTER_KEY"),
+		EncryptionKeyVersion:   EncryptionKeyVersion,
+		EncryptionPreviousKeys: getEnv("ENCRYPTION_PREVIOUS_KEYS", ""),
+		ProviderRegistryPath:   getEnv("PROVIDER_REGISTRY_PATH", ""),
+		ProviderReloadSeconds:  ProviderReloadSeconds,
+		MockFixturesPath:       getEnv("MOCK_LLM_FIXTURES", ""),
+		CassetteMode:           getEnv("LLM_CASSETTE_MODE", "off"),
+		CassetteDir:            getEnv("LLM_CASSETTE_DIR", "cassettes"),
+		HealthCheckSeconds:     HealthCheckSeconds,
+		HealthFailureThreshold: HealthFailureThreshold,
+		AssignmentLeaseSeconds: AssignmentLeaseSeconds,
+		LeaseReclaimSeconds:    LeaseReclaimSeconds,
 	}, nil
 }
\ No newline at end of file
@@ -1,240 +1,300 @@
 package config
 
-import "github.com/berkkaradalan/stackflow/models"
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/goccy/go-yaml"
+)
+
+//go:embed providers.yaml
+var defaultRegistryFile []byte
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,49}$`)
+
+// Supported provider wire formats
+var apiFormats = map[string]bool{
+	"openai":    true,
+	"anthropic": true,
+	"gemini":    true,
+	"mock":      true,
+}
+
+// registryFile is the on-disk layout of the provider registry
+type registryFile struct {
+	Providers []models.ProviderConfig `json:"providers"`
+}
+
+// Registry holds the provider/model catalog. Providers come from the registry
+// file and from custom OpenAI-compatible providers registered by admins.
+type Registry struct {
+	mu      sync.RWMutex
+	path    string
+	modTime time.Time
+	file    []models.ProviderConfig
+	custom  []models.ProviderConfig
+}
+
+var registry = &Registry{}
+
+func init() {
+	providers, err := ParseRegistry(defaultRegistryFile, ".yaml")
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in provider registry: %v", err))
+	}
+	registry.file = providers
+}
+
+// Providers returns the live provider registry
+func Providers() *Registry {
+	return registry
+}
 
 // GetProviderRegistry returns all available providers with their configurations
 func GetProviderRegistry() []models.ProviderConfig {
-	return []models.ProviderConfig{
-		{
-			Name:               "zai",
-			DisplayName:        "Z.AI",
-			BaseURL:            "https://api.z.ai/api/paas/v4",
-			HealthCheckPath:    "/models",
-			ChatCompletionPath: "/chat/completions",
-			RequiresAPIKey:     true,
-			Models: []models.ModelConfig{
-				{
-					ID:                   "glm-4.7",
-					Name:                 "GLM-4.7",
-					Description:          "Latest GLM model (Jan 2025)",
-					MaxTokens:            128000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  1.0,
-					OutputPricePerMToken: 1.0,
-				},
-				{
-					ID:                   "glm-4.7-flash",
-					Name:                 "GLM-4.7 Flash",
-					Description:          "Fast GLM-4.7 variant",
-					MaxTokens:            128000,
-					SupportsStreaming:    true,
-					SupportsVision:       false,
-					InputPricePerMToken:  0.5,
-					OutputPricePerMToken: 0.5,
-				},
-				{
-					ID:                   "glm-4.6",
-					Name:                 "GLM-4.6",
-					Description:          "GLM model from Dec 2024",
-					MaxTokens:            128000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  1.0,
-					OutputPricePerMToken: 1.0,
-				},
-				{
-					ID:                   "glm-4.5",
-					Name:                 "GLM-4.5",
-					Description:          "Stable GLM-4.5 model",
-					MaxTokens:            128000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  1.0,
-					OutputPricePerMToken: 1.0,
-				},
-				{
-					ID:                   "glm-4.5-air",
-					Name:                 "GLM-4.5 Air",
-					Description:          "Lightweight GLM-4.5 variant",
-					MaxTokens:            128000,
-					SupportsStreaming:    true,
-					SupportsVision:       false,
-					InputPricePerMToken:  0.5,
-					OutputPricePerMToken: 0.5,
-				},
-			},
-		},
-		{
-			Name:               "anthropic",
-			DisplayName:        "Anthropic",
-			BaseURL:            "https://api.anthropic.com/v1",
-			HealthCheckPath:    "/messages",
-			ChatCompletionPath: "/messages",
-			RequiresAPIKey:     true,
-			Models: []models.ModelConfig{
-				{
-					ID:                   "claude-opus-4-5-20251101",
-					Name:                 "Claude Opus 4.5",
-					Description:          "Most capable Claude model",
-					MaxTokens:            200000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  15.0,
-					OutputPricePerMToken: 75.0,
-				},
-				{
-					ID:                   "claude-sonnet-4-5-20250929",
-					Name:                 "Claude Sonnet 4.5",
-					Description:          "Balanced performance and speed",
-					MaxTokens:            200000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  3.0,
-					OutputPricePerMToken: 15.0,
-				},
-				{
-					ID:                   "claude-haiku-4-5-20251001",
-					Name:                 "Claude Haiku 4.5",
-					Description:          "Fastest Claude model",
-					MaxTokens:            200000,
-					SupportsStreaming:    true,
-					SupportsVision:       false,
-					InputPricePerMToken:  0.8,
-					OutputPricePerMToken: 4.0,
-				},
-			},
-		},
-		{
-			Name:               "gemini",
-			DisplayName:        "Google Gemini",
-			BaseURL:            "https://generativelanguage.googleapis.com/v1beta",
-			HealthCheckPath:    "/models",
-			ChatCompletionPath: "/models/{model}:generateContent",
-			RequiresAPIKey:     true,
-			Models: []models.ModelConfig{
-				{
-					ID:                   "gemini-2.0-flash-exp",
-					Name:                 "Gemini 2.0 Flash (Experimental)",
-					Description:          "Latest experimental Gemini model",
-					MaxTokens:            1000000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  0.0,
-					OutputPricePerMToken: 0.0,
-				},
-				{
-					ID:                   "gemini-1.5-pro",
-					Name:                 "Gemini 1.5 Pro",
-					Description:          "Most capable Gemini 1.5 model",
-					MaxTokens:            2000000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  1.25,
-					OutputPricePerMToken: 5.0,
-				},
-				{
-					ID:                   "gemini-1.5-flash",
-					Name:                 "Gemini 1.5 Flash",
-					Description:          "Fast and efficient Gemini model",
-					MaxTokens:            1000000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  0.075,
-					OutputPricePerMToken: 0.3,
-				},
-			},
-		},
-		{
-			Name:               "kimi",
-			DisplayName:        "Kimi (Moonshot AI)",
-			BaseURL:            "https://api.moonshot.cn/v1",
-			HealthCheckPath:    "/models",
-			ChatCompletionPath: "/chat/completions",
-			RequiresAPIKey:     true,
-			Models: []models.ModelConfig{
-				{
-					ID:                   "moonshot-v1-128k",
-					Name:                 "Moonshot v1 128K",
-					Description:          "Moonshot model with 128K context",
-					MaxTokens:            128000,
-					SupportsStreaming:    true,
-					SupportsVision:       false,
-					InputPricePerMToken:  12.0,
-					OutputPricePerMToken: 12.0,
-				},
-				{
-					ID:                   "moonshot-v1-32k",
-					Name:                 "Moonshot v1 32K",
-					Description:          "Moonshot model with 32K context",
-					MaxTokens:            32000,
-					SupportsStreaming:    true,
-					SupportsVision:       false,
-					InputPricePerMToken:  24.0,
-					OutputPricePerMToken: 24.0,
-				},
-			},
-		},
-		{
-			Name:               "openrouter",
-			DisplayName:        "OpenRouter",
-			BaseURL:            "https://openrouter.ai/api/v1",
-			HealthCheckPath:    "/models",
-			ChatCompletionPath: "/chat/completions",
-			RequiresAPIKey:     true,
-			Models: []models.ModelConfig{
-				{
-					ID:                   "anthropic/claude-opus-4-5",
-					Name:                 "Claude Opus 4.5 (via OpenRouter)",
-					Description:          "Access Claude Opus 4.5 through OpenRouter",
-					MaxTokens:            200000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  15.0,
-					OutputPricePerMToken: 75.0,
-				},
-				{
-					ID:                   "anthropic/claude-sonnet-4-5",
-					Name:                 "Claude Sonnet 4.5 (via OpenRouter)",
-					Description:          "Access Claude Sonnet 4.5 through OpenRouter",
-					MaxTokens:            200000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  3.0,
-					OutputPricePerMToken: 15.0,
-				},
-				{
-					ID:                   "openai/gpt-4-turbo",
-					Name:                 "GPT-4 Turbo (via OpenRouter)",
-					Description:          "Access GPT-4 Turbo through OpenRouter",
-					MaxTokens:            128000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  10.0,
-					OutputPricePerMToken: 30.0,
-				},
-				{
-					ID:                   "google/gemini-2.0-flash-exp:free",
-					Name:                 "Gemini 2.0 Flash (Free via OpenRouter)",
-					Description:          "Free access to Gemini 2.0 Flash",
-					MaxTokens:            1000000,
-					SupportsStreaming:    true,
-					SupportsVision:       true,
-					InputPricePerMToken:  0.0,
-					OutputPricePerMToken: 0.0,
-				},
-			},
-		},
-	}
+	return registry.All()
 }
 
 // GetProviderByName returns a specific provider configuration by name
 func GetProviderByName(name string) *models.ProviderConfig {
-	providers := GetProviderRegistry()
-	for _, provider := range providers {
-		if provider.Name == name {
-			return &provider
+	return registry.Get(name)
+}
+
+// Load replaces the file-based providers with the contents of path.
+// An empty path keeps the built-in registry.
+func (r *Registry) Load(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read provider registry: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read provider registry: %w", err)
+	}
+
+	providers, err := ParseRegistry(data, filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("invalid provider registry %s: %w", path, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := checkCustomClashes(providers, r.custom); err != nil {
+		return err
+	}
+
+	r.path = path
+	r.modTime = info.ModTime()
+	r.file = providers
+	return nil
+}
+
+// ReloadIfChanged reloads the registry file when it was modified since the last load.
+// On error the previous registry stays in place.
+func (r *Registry) ReloadIfChanged() (bool, error) {
+	r.mu.RLock()
+	path, modTime := r.path, r.modTime
+	r.mu.RUnlock()
+
+	if path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read provider registry: %w", err)
+	}
+	if info.ModTime().Equal(modTime) {
+		return false, nil
+	}
+
+	if err := r.Load(path); err != nil {
+		// Remember the broken revision so it is reported once, not on every check
+		r.mu.Lock()
+		r.modTime = info.ModTime()
+		r.mu.Unlock()
+		return false, err
+	}
+	return true, nil
+}
+
+// SetCustomProviders replaces the admin-registered providers
+func (r *Registry) SetCustomProviders(providers []models.ProviderConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.custom = providers
+}
+
+// IsBuiltIn reports whether a provider name is defined by the registry file
+func (r *Registry) IsBuiltIn(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.file {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// All returns a copy of every registered provider, file providers first
+func (r *Registry) All() []models.ProviderConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	providers := make([]models.ProviderConfig, 0, len(r.file)+len(r.custom))
+	for _, p := range r.file {
+		providers = append(providers, copyProvider(p))
+	}
+	for _, p := range r.custom {
+		providers = append(providers, copyProvider(p))
+	}
+	return providers
+}
+
+// Get returns a copy of a provider by name, or nil if it is not registered
+func (r *Registry) Get(name string) *models.ProviderConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, list := range [][]models.ProviderConfig{r.file, r.custom} {
+		for _, p := range list {
+			if p.Name == name {
+				provider := copyProvider(p)
+				return &provider
+			}
 		}
 	}
 	return nil
 }
+
+// ParseRegistry decodes and validates a registry file. ext selects JSON
+// (".json") or YAML (anything else). Unknown fields are rejected.
+func ParseRegistry(data []byte, ext string) ([]models.ProviderConfig, error) {
+	if !strings.EqualFold(ext, ".json") {
+		converted, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse yaml: %w", err)
+		}
+		data = converted
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var file registryFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode registry: %w", err)
+	}
+
+	if len(file.Providers) == 0 {
+		return nil, errors.New("registry defines no providers")
+	}
+
+	seen := make(map[string]bool)
+	for i := range file.Providers {
+		p := &file.Providers[i]
+		if p.APIFormat == "" {
+			p.APIFormat = "openai"
+		}
+		p.Custom = false
+		if err := ValidateProvider(p); err != nil {
+			return nil, err
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate provider %q", p.Name)
+		}
+		seen[p.Name] = true
+	}
+
+	return file.Providers, nil
+}
+
+// ValidateProvider checks a single provider definition
+func ValidateProvider(p *models.ProviderConfig) error {
+	if !providerNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("provider name %q must be 2-50 lowercase letters, digits, '-' or '_'", p.Name)
+	}
+	if p.DisplayName == "" {
+		return fmt.Errorf("provider %q: display_name is required", p.Name)
+	}
+
+	if !apiFormats[p.APIFormat] {
+		return fmt.Errorf("provider %q: unsupported api_format %q", p.Name, p.APIFormat)
+	}
+
+	// The mock provider answers from fixtures and has no endpoint
+	if p.APIFormat != "mock" {
+		baseURL, err := url.Parse(p.BaseURL)
+		if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+			return fmt.Errorf("provider %q: base_url must be an absolute http(s) URL", p.Name)
+		}
+		if !strings.HasPrefix(p.ChatCompletionPath, "/") {
+			return fmt.Errorf("provider %q: chat_completion_path must start with '/'", p.Name)
+		}
+		if p.HealthCheckPath != "" && !strings.HasPrefix(p.HealthCheckPath, "/") {
+			return fmt.Errorf("provider %q: health_check_path must start with '/'", p.Name)
+		}
+		if p.EmbeddingPath != "" && !strings.HasPrefix(p.EmbeddingPath, "/") {
+			return fmt.Errorf("provider %q: embedding_path must start with '/'", p.Name)
+		}
+	}
+	if p.EmbeddingPath != "" && p.APIFormat == "anthropic" {
+		return fmt.Errorf("provider %q: the anthropic api_format has no embeddings endpoint", p.Name)
+	}
+
+	modelIDs := make(map[string]bool)
+	for _, m := range p.Models {
+		if m.ID == "" {
+			return fmt.Errorf("provider %q: model id is required", p.Name)
+		}
+		if modelIDs[m.ID] {
+			return fmt.Errorf("provider %q: duplicate model %q", p.Name, m.ID)
+		}
+		modelIDs[m.ID] = true
+
+		if m.MaxTokens < 0 || m.Dimensions < 0 || m.InputPricePerMToken < 0 || m.OutputPricePerMToken < 0 {
+			return fmt.Errorf("provider %q: model %q has negative limits or prices", p.Name, m.ID)
+		}
+		if m.Embedding && p.EmbeddingPath == "" && p.APIFormat != "mock" {
+			return fmt.Errorf("provider %q: embedding model %q needs an embedding_path", p.Name, m.ID)
+		}
+	}
+
+	return nil
+}
+
+func checkCustomClashes(file, custom []models.ProviderConfig) error {
+	for _, c := range custom {
+		for _, f := range file {
+			if c.Name == f.Name {
+				return fmt.Errorf("provider %q clashes with a custom provider of the same name", f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func copyProvider(p models.ProviderConfig) models.ProviderConfig {
+	p.Models = append([]models.ModelConfig(nil), p.Models...)
+	return p
+}
@@ -151,6 +151,207 @@ func Migrate(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config) error
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_execution_reports_project_id ON execution_reports(project_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_execution_reports_report_type ON execution_reports(report_type)`,
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_id VARCHAR(64) UNIQUE NOT NULL,
+			family_id VARCHAR(64) NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			replaced_by VARCHAR(64),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id)`,
+		`CREATE TABLE IF NOT EXISTS agent_tokens (
+			id SERIAL PRIMARY KEY,
+			agent_id INTEGER NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			token_prefix VARCHAR(20) NOT NULL,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			created_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			last_used_at TIMESTAMP,
+			expires_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_tokens_agent_id ON agent_tokens(agent_id)`,
+		`CREATE TABLE IF NOT EXISTS project_members (
+			project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			role VARCHAR(20) NOT NULL DEFAULT 'contributor',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (project_id, user_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_project_members_user_id ON project_members(user_id)`,
+		// Agent API keys are encrypted at rest; version 0 marks keys stored before encryption
+		`ALTER TABLE agents ADD COLUMN IF NOT EXISTS api_key_version INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE agents ADD COLUMN IF NOT EXISTS api_key_hint VARCHAR(8) NOT NULL DEFAULT ''`,
+		// Projects created before memberships existed are owned by their creator
+		`INSERT INTO project_members (project_id, user_id, role)
+			SELECT p.id, p.created_by, 'owner' FROM projects p
+			WHERE NOT EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = p.id)`,
+		// OpenAI-compatible providers registered by admins, merged into the provider registry
+		`CREATE TABLE IF NOT EXISTS custom_providers (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(50) UNIQUE NOT NULL,
+			display_name VARCHAR(100) NOT NULL,
+			base_url TEXT NOT NULL,
+			chat_completion_path VARCHAR(255) NOT NULL DEFAULT '/chat/completions',
+			health_check_path VARCHAR(255) NOT NULL DEFAULT '/models',
+			requires_api_key BOOLEAN NOT NULL DEFAULT true,
+			models JSONB NOT NULL DEFAULT '[]'::jsonb,
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// One row per provider call; the agents usage counters are derived from it
+		`CREATE TABLE IF NOT EXISTS llm_usage (
+			id BIGSERIAL PRIMARY KEY,
+			agent_id INTEGER NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+			project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+			task_id INTEGER REFERENCES tasks(id) ON DELETE SET NULL,
+			assignment_id INTEGER REFERENCES agent_assignments(id) ON DELETE SET NULL,
+			provider VARCHAR(50) NOT NULL,
+			model VARCHAR(100) NOT NULL,
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			total_tokens INTEGER NOT NULL DEFAULT 0,
+			cost DECIMAL(12, 6) NOT NULL DEFAULT 0,
+			latency_ms INTEGER NOT NULL DEFAULT 0,
+			status VARCHAR(20) NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_llm_usage_agent_created ON llm_usage(agent_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_llm_usage_project_created ON llm_usage(project_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_llm_usage_task_id ON llm_usage(task_id)`,
+		// The lifetime cost counter keeps the ledger's precision, so sub-cent calls are not rounded away
+		`ALTER TABLE agents ALTER COLUMN total_cost TYPE DECIMAL(12, 6)`,
+		// Spending caps, set on either an agent or a whole project
+		`CREATE TABLE IF NOT EXISTS budgets (
+			id SERIAL PRIMARY KEY,
+			agent_id INTEGER REFERENCES agents(id) ON DELETE CASCADE,
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			period VARCHAR(20) NOT NULL,
+			max_tokens BIGINT,
+			max_cost DECIMAL(12, 4),
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK ((agent_id IS NULL) <> (project_id IS NULL))
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_agent_period ON budgets(agent_id, period) WHERE agent_id IS NOT NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_project_period ON budgets(project_id, period) WHERE project_id IS NOT NULL`,
+		`CREATE TABLE IF NOT EXISTS agent_events (
+			id SERIAL PRIMARY KEY,
+			agent_id INTEGER NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+			event_type VARCHAR(50) NOT NULL,
+			message TEXT NOT NULL,
+			data JSONB,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_events_agent_id ON agent_events(agent_id, created_at)`,
+		// Ordered fallback models, each with its own encrypted key when on another provider
+		`ALTER TABLE agents ADD COLUMN IF NOT EXISTS fallback_models JSONB NOT NULL DEFAULT '[]'::jsonb`,
+		// Results of real provider health checks, scheduled or manual
+		`CREATE TABLE IF NOT EXISTS agent_health_checks (
+			id BIGSERIAL PRIMARY KEY,
+			agent_id INTEGER NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+			provider VARCHAR(50) NOT NULL,
+			model VARCHAR(100) NOT NULL,
+			healthy BOOLEAN NOT NULL,
+			latency_ms BIGINT NOT NULL DEFAULT 0,
+			error TEXT,
+			source VARCHAR(20) NOT NULL,
+			checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_health_checks_agent_id ON agent_health_checks(agent_id, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_health_checks_checked_at ON agent_health_checks(checked_at)`,
+		// Versioned system prompts per agent role and level; rows without a project are the defaults
+		`CREATE TABLE IF NOT EXISTS prompt_templates (
+			id SERIAL PRIMARY KEY,
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			role VARCHAR(50) NOT NULL,
+			level VARCHAR(20) NOT NULL DEFAULT '',
+			version INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			change_note TEXT NOT NULL DEFAULT '',
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_templates_version ON prompt_templates(COALESCE(project_id, 0), role, level, version)`,
+		// The template version each provider call was prompted with
+		`ALTER TABLE llm_usage ADD COLUMN IF NOT EXISTS prompt_template_id INTEGER REFERENCES prompt_templates(id) ON DELETE SET NULL`,
+		// Embedding calls are ledgered too, and may be billed to a project without an agent
+		`ALTER TABLE llm_usage ADD COLUMN IF NOT EXISTS operation VARCHAR(20) NOT NULL DEFAULT 'chat'`,
+		`ALTER TABLE llm_usage ALTER COLUMN agent_id DROP NOT NULL`,
+		`ALTER TABLE custom_providers ADD COLUMN IF NOT EXISTS embedding_path VARCHAR(255) NOT NULL DEFAULT ''`,
+		// Images attached to tasks, such as screenshots and mockups, that agents can be shown
+		`CREATE TABLE IF NOT EXISTS task_attachments (
+			id SERIAL PRIMARY KEY,
+			task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+			file_name VARCHAR(255) NOT NULL,
+			content_type VARCHAR(100) NOT NULL,
+			size_bytes INTEGER NOT NULL,
+			data BYTEA NOT NULL,
+			uploaded_by INTEGER NOT NULL,
+			uploader_type VARCHAR(10) NOT NULL DEFAULT 'user',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_task_attachments_task_id ON task_attachments(task_id)`,
+		// Extra provider keys agents rotate over to spread load across rate limits
+		`CREATE TABLE IF NOT EXISTS agent_api_keys (
+			id SERIAL PRIMARY KEY,
+			agent_id INTEGER NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+			provider VARCHAR(50) NOT NULL,
+			label VARCHAR(100) NOT NULL DEFAULT '',
+			api_key TEXT NOT NULL,
+			api_key_version INTEGER NOT NULL DEFAULT 0,
+			api_key_hint VARCHAR(8) NOT NULL DEFAULT '',
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			total_requests BIGINT NOT NULL DEFAULT 0,
+			total_tokens_used BIGINT NOT NULL DEFAULT 0,
+			total_cost DECIMAL(12, 6) NOT NULL DEFAULT 0,
+			rate_limited_count BIGINT NOT NULL DEFAULT 0,
+			cooldown_until TIMESTAMP,
+			last_used_at TIMESTAMP,
+			created_by INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_api_keys_agent_id ON agent_api_keys(agent_id)`,
+		// The key that served each call: pool keys by ID, every key in masked form
+		`ALTER TABLE llm_usage ADD COLUMN IF NOT EXISTS api_key_id INTEGER REFERENCES agent_api_keys(id) ON DELETE SET NULL`,
+		`ALTER TABLE llm_usage ADD COLUMN IF NOT EXISTS api_key_masked VARCHAR(20) NOT NULL DEFAULT ''`,
+		// Provider keys shared by agents; rows without a project are organization-wide
+		`CREATE TABLE IF NOT EXISTS provider_credentials (
+			id SERIAL PRIMARY KEY,
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			provider VARCHAR(50) NOT NULL,
+			api_key TEXT NOT NULL,
+			api_key_version INTEGER NOT NULL DEFAULT 0,
+			api_key_hint VARCHAR(8) NOT NULL DEFAULT '',
+			last_tested_at TIMESTAMP,
+			last_test_healthy BOOLEAN,
+			created_by INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_provider_credentials_project_id ON provider_credentials(project_id)`,
+		`ALTER TABLE agents ADD COLUMN IF NOT EXISTS credential_id INTEGER REFERENCES provider_credentials(id)`,
+		`CREATE INDEX IF NOT EXISTS idx_agents_credential_id ON agents(credential_id)`,
+		// Credentials hold key pools of their own, shared by every agent using the credential
+		`ALTER TABLE agent_api_keys ALTER COLUMN agent_id DROP NOT NULL`,
+		`ALTER TABLE agent_api_keys ADD COLUMN IF NOT EXISTS credential_id INTEGER REFERENCES provider_credentials(id) ON DELETE CASCADE`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_api_keys_credential_id ON agent_api_keys(credential_id)`,
+		// Claimed assignments are leased to their agent and kept alive by heartbeats
+		`ALTER TABLE agent_assignments ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP`,
+		`ALTER TABLE agent_assignments ADD COLUMN IF NOT EXISTS last_heartbeat_at TIMESTAMP`,
+		`ALTER TABLE agent_assignments ADD COLUMN IF NOT EXISTS reclaim_count INTEGER NOT NULL DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_assignments_lease ON agent_assignments(lease_expires_at) WHERE status = 'in_progress'`,
 	}
 
 	for i, query := range queries {
@@ -163,6 +364,10 @@ func Migrate(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config) error
 		return fmt.Errorf("failed to create default admin: %w", err)
 	}
 
+	if err := createDefaultPromptTemplates(ctx, pool); err != nil {
+		return fmt.Errorf("failed to create default prompt templates: %w", err)
+	}
+
 	return nil
 }
 
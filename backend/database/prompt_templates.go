@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultRolePrompts describes what each agent role is responsible for
+var defaultRolePrompts = map[string]struct {
+	title string
+	body  string
+}{
+	models.AgentRoleBackendDeveloper: {
+		title: "backend developer",
+		body:  "You build and maintain the server side: APIs, business logic, data models and integrations. Keep interfaces stable, validate input at the boundaries and handle errors explicitly.",
+	},
+	models.AgentRoleFrontendDeveloper: {
+		title: "frontend developer",
+		body:  "You build the user interface: components, state management and the integration with the backend APIs. Keep the UI accessible and responsive and reuse existing components.",
+	},
+	models.AgentRoleFullstackDeveloper: {
+		title: "fullstack developer",
+		body:  "You deliver features end to end, from the database and APIs to the user interface. Keep the backend and frontend consistent and change both sides together.",
+	},
+	models.AgentRoleTester: {
+		title: "tester",
+		body:  "You verify that the work meets its requirements. Write and run tests, reproduce reported bugs and report failures with the steps to reproduce them.",
+	},
+	models.AgentRoleDevops: {
+		title: "DevOps engineer",
+		body:  "You own builds, deployments and infrastructure. Keep pipelines fast and reliable, automate repetitive work and watch the health of the running services.",
+	},
+	models.AgentRoleProjectManager: {
+		title: "project manager",
+		body:  "You plan and coordinate the work of the team. Break goals into tasks, set priorities, assign tasks to the right agents and keep the execution plan up to date.",
+	},
+}
+
+// defaultLevelPrompts sets the expected autonomy for each agent level
+var defaultLevelPrompts = map[string]struct {
+	title string
+	body  string
+}{
+	models.AgentLevelJunior: {
+		title: "junior",
+		body:  "Work in small, well-defined steps. Follow the existing patterns of the codebase, and when requirements are unclear, ask instead of guessing.",
+	},
+	models.AgentLevelMid: {
+		title: "mid-level",
+		body:  "Work independently on tasks of moderate scope. Make sensible decisions within the existing architecture and explain the trade-offs you make.",
+	},
+	models.AgentLevelSenior: {
+		title: "senior",
+		body:  "Take ownership of complex tasks. Weigh design alternatives, anticipate edge cases and their impact on the rest of the system, and leave the code better than you found it.",
+	},
+}
+
+// defaultPromptContext renders the project, plan and task variables shared by every default template
+const defaultPromptContext = `
+{{- with .Plan}}
+
+Execution plan:
+{{- if .FocusAreas}}
+- Focus areas: {{join .FocusAreas ", "}}
+{{- end}}
+{{- if .Constraints.MaxParallelTasks}}
+- Work on at most {{.Constraints.MaxParallelTasks}} tasks at a time
+{{- end}}
+{{- if .Constraints.CodeReviewRequired}}
+- Every change must pass code review before it is done
+{{- end}}
+{{- if .Constraints.TestCoverageMin}}
+- Keep test coverage at or above {{.Constraints.TestCoverageMin}}%
+{{- end}}
+{{- with .Notes}}
+- Notes: {{.}}
+{{- end}}
+{{- end}}
+{{- with .Task}}
+
+Current task #{{.ID}}: {{.Title}} ({{.Priority}} priority)
+{{- with .Description}}
+{{.}}
+{{- end}}
+{{- with .Notes}}
+Plan notes: {{.}}
+{{- end}}
+{{- end}}`
+
+// defaultPromptTemplate builds the built-in template of a role and level
+func defaultPromptTemplate(role, level string) string {
+	r := defaultRolePrompts[role]
+	l := defaultLevelPrompts[level]
+
+	return fmt.Sprintf(`You are {{.Agent.Name}}, a %s %s working on the project "{{.Project.Name}}".
+{{- with .Project.Description}}
+Project description: {{.}}
+{{- end}}
+
+%s
+
+%s`, l.title, r.title, r.body, l.body) + defaultPromptContext
+}
+
+// createDefaultPromptTemplates stores version 1 of the built-in template for every role
+// and level that has no default yet. Later versions are saved through the API.
+func createDefaultPromptTemplates(ctx context.Context, pool *pgxpool.Pool) error {
+	query := `INSERT INTO prompt_templates (project_id, role, level, version, content, change_note)
+	          SELECT NULL, $1, $2, 1, $3, 'Built-in default'
+	          WHERE NOT EXISTS (
+	              SELECT 1 FROM prompt_templates WHERE project_id IS NULL AND role = $1 AND level = $2
+	          )
+	          ON CONFLICT (COALESCE(project_id, 0), role, level, version) DO NOTHING`
+
+	for role := range defaultRolePrompts {
+		for level := range defaultLevelPrompts {
+			if _, err := pool.Exec(ctx, query, role, level, defaultPromptTemplate(role, level)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	agentService *service.AgentService
+}
+
+func NewAdminHandler(agentService *service.AgentService) *AdminHandler {
+	return &AdminHandler{
+		agentService: agentService,
+	}
+}
+
+// ReencryptKeys handles POST /api/admin/encryption/reencrypt
+// Re-encrypts every stored provider API key under the current master key
+func (h *AdminHandler) ReencryptKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	result, err := h.agentService.ReencryptAPIKeys(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to re-encrypt API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AgentAPIKeyHandler struct {
+	keyService *service.AgentAPIKeyService
+}
+
+func NewAgentAPIKeyHandler(keyService *service.AgentAPIKeyService) *AgentAPIKeyHandler {
+	return &AgentAPIKeyHandler{
+		keyService: keyService,
+	}
+}
+
+// CreateKey handles POST /api/agents/:id/api-keys
+// Adds a key for the agent's provider to the pool its calls rotate over
+func (h *AgentAPIKeyHandler) CreateKey(c *gin.Context) {
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	var req models.CreateAgentAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.keyService.CreateKey(c.Request.Context(), agentID, &req, c.GetInt("user_id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to add agent api key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// GetKeys handles GET /api/agents/:id/api-keys
+// Lists the pool keys with their usage, rate limits and cooldowns
+func (h *AgentAPIKeyHandler) GetKeys(c *gin.Context) {
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	keys, err := h.keyService.GetKeys(c.Request.Context(), agentID)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch agent api keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// UpdateKey handles PUT /api/agents/:id/api-keys/:keyId
+func (h *AgentAPIKeyHandler) UpdateKey(c *gin.Context) {
+	agentID, keyID, ok := agentKeyParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateAgentAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.keyService.UpdateKey(c.Request.Context(), agentID, keyID, &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to update agent api key")
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// DeleteKey handles DELETE /api/agents/:id/api-keys/:keyId
+func (h *AgentAPIKeyHandler) DeleteKey(c *gin.Context) {
+	agentID, keyID, ok := agentKeyParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.keyService.DeleteKey(c.Request.Context(), agentID, keyID); err != nil {
+		h.handleError(c, err, "Failed to delete agent api key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Agent api key deleted successfully"})
+}
+
+func (h *AgentAPIKeyHandler) handleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrAgentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+	case errors.Is(err, service.ErrAgentAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent api key not found"})
+	case errors.Is(err, service.ErrInvalidAgentAPIKey), errors.Is(err, service.ErrProviderNotConfigured):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// agentKeyParams parses the :id and :keyId route parameters
+func agentKeyParams(c *gin.Context) (int, int, bool) {
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return 0, 0, false
+	}
+	keyID, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api key ID"})
+		return 0, 0, false
+	}
+	return agentID, keyID, true
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -10,12 +11,14 @@ import (
 )
 
 type AgentHandler struct {
-	agentService *service.AgentService
+	agentService  *service.AgentService
+	accessService *service.AccessService
 }
 
-func NewAgentHandler(agentService *service.AgentService) *AgentHandler {
+func NewAgentHandler(agentService *service.AgentService, accessService *service.AccessService) *AgentHandler {
 	return &AgentHandler{
-		agentService: agentService,
+		agentService:  agentService,
+		accessService: accessService,
 	}
 }
 
@@ -36,7 +39,25 @@ func (h *AgentHandler) CreateAgent(c *gin.Context) {
 		return
 	}
 
+	// Only project maintainers and owners may add agents to a project
+	if c.GetString("role") != "admin" {
+		projectRole, err := h.accessService.ProjectRole(ctx, req.ProjectID, userID.(int))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project membership"})
+			return
+		}
+		if models.ProjectRoleRank(projectRole) < models.ProjectRoleRank(models.ProjectRoleMaintainer) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient project permissions"})
+			return
+		}
+	}
+
 	agent, err := h.agentService.CreateAgent(ctx, &req, userID.(int))
+	var validationErr *service.AgentValidationError
+	if errors.As(err, &validationErr) {
+		respondAgentValidationError(c, validationErr)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agent"})
 		return
@@ -49,7 +70,7 @@ func (h *AgentHandler) CreateAgent(c *gin.Context) {
 func (h *AgentHandler) GetAllAgents(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	agents, err := h.agentService.GetAllAgents(ctx)
+	agents, err := h.agentService.GetAllAgents(ctx, c.GetInt("user_id"), c.GetString("role"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch agents"})
 		return
@@ -116,6 +137,11 @@ func (h *AgentHandler) UpdateAgent(c *gin.Context) {
 	}
 
 	agent, err := h.agentService.UpdateAgent(ctx, id, &req)
+	var validationErr *service.AgentValidationError
+	if errors.As(err, &validationErr) {
+		respondAgentValidationError(c, validationErr)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agent"})
 		return
@@ -208,6 +234,36 @@ func (h *AgentHandler) GetAgentPerformance(c *gin.Context) {
 	c.JSON(http.StatusOK, performance)
 }
 
+// GetAgentEvents handles GET /api/agents/:id/events
+// Returns the most recent events, up to ?limit= (default 50, max 200)
+func (h *AgentHandler) GetAgentEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	events, err := h.agentService.GetAgentEvents(ctx, id, limit)
+	if errors.Is(err, service.ErrAgentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch agent events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
 // HealthCheck handles GET /api/agents/:id/health
 // Performs a real API test to the provider to verify connectivity
 func (h *AgentHandler) HealthCheck(c *gin.Context) {
@@ -223,9 +279,61 @@ func (h *AgentHandler) HealthCheck(c *gin.Context) {
 	// Perform real health check with actual API test
 	health, err := h.agentService.PerformRealHealthCheck(ctx, id)
 	if err != nil {
+		// The test message is a paid call and obeys the agent's budgets
+		var budgetErr *service.BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": budgetErr.Error(), "budgets": budgetErr.Budgets})
+			return
+		}
+		if errors.Is(err, service.ErrAgentDisabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, health)
 }
+
+// GetHealthHistory handles GET /api/agents/:id/health/history
+// Returns the most recent health checks, up to ?limit= (default 50, max 200)
+func (h *AgentHandler) GetHealthHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	history, err := h.agentService.GetHealthHistory(ctx, id, limit)
+	if errors.Is(err, service.ErrAgentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch health history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// respondAgentValidationError returns field-level errors and, for unknown models, the valid ones
+func respondAgentValidationError(c *gin.Context, err *service.AgentValidationError) {
+	body := gin.H{
+		"error":  "Invalid agent configuration",
+		"fields": err.Fields,
+	}
+	if len(err.ValidModels) > 0 {
+		body["valid_models"] = err.ValidModels
+	}
+	c.JSON(http.StatusBadRequest, body)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AgentTokenHandler struct {
+	agentTokenService *service.AgentTokenService
+}
+
+func NewAgentTokenHandler(agentTokenService *service.AgentTokenService) *AgentTokenHandler {
+	return &AgentTokenHandler{
+		agentTokenService: agentTokenService,
+	}
+}
+
+// CreateToken handles POST /api/agents/:id/tokens
+func (h *AgentTokenHandler) CreateToken(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	idParam := c.Param("id")
+	agentID, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	var req models.CreateAgentTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	token, err := h.agentTokenService.CreateToken(ctx, agentID, &req, userID.(int))
+	if err != nil {
+		if errors.Is(err, service.ErrAgentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agent token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// GetTokens handles GET /api/agents/:id/tokens
+func (h *AgentTokenHandler) GetTokens(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	idParam := c.Param("id")
+	agentID, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	tokens, err := h.agentTokenService.GetTokens(ctx, agentID)
+	if err != nil {
+		if errors.Is(err, service.ErrAgentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch agent tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokeToken handles DELETE /api/agents/:id/tokens/:tokenId
+func (h *AgentTokenHandler) RevokeToken(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	idParam := c.Param("id")
+	agentID, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	tokenIDParam := c.Param("tokenId")
+	tokenID, err := strconv.Atoi(tokenIDParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	err = h.agentTokenService.RevokeToken(ctx, agentID, tokenID)
+	if err != nil {
+		if errors.Is(err, service.ErrAgentTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Agent token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke agent token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Agent token revoked successfully"})
+}
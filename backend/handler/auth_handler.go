@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/berkkaradalan/stackflow/models"
@@ -53,7 +54,14 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	if err := h.authService.Logout(c, userID.(int)); err != nil {
+	var req models.LogoutRequest
+	_ = c.ShouldBindJSON(&req) // Optional refresh token
+
+	if err := h.authService.Logout(c, userID.(int), req.RefreshToken); err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetHandler struct {
+	budgetService *service.BudgetService
+}
+
+func NewBudgetHandler(budgetService *service.BudgetService) *BudgetHandler {
+	return &BudgetHandler{
+		budgetService: budgetService,
+	}
+}
+
+// GetAgentBudget handles GET /api/agents/:id/budgets
+// Shows the remaining budget of the agent and its project
+func (h *BudgetHandler) GetAgentBudget(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	budget, err := h.budgetService.GetAgentBudget(ctx, agentID)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch agent budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// SetAgentBudget handles PUT /api/agents/:id/budgets
+func (h *BudgetHandler) SetAgentBudget(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	var req models.SetBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget, err := h.budgetService.SetAgentBudget(ctx, agentID, &req, c.GetInt("user_id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to save agent budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// DeleteAgentBudget handles DELETE /api/agents/:id/budgets/:period
+func (h *BudgetHandler) DeleteAgentBudget(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	if err := h.budgetService.DeleteAgentBudget(ctx, agentID, c.Param("period")); err != nil {
+		h.handleError(c, err, "Failed to delete agent budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+}
+
+// GetProjectBudget handles GET /api/projects/:id/budgets
+func (h *BudgetHandler) GetProjectBudget(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	budget, err := h.budgetService.GetProjectBudget(ctx, projectID)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch project budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// SetProjectBudget handles PUT /api/projects/:id/budgets
+func (h *BudgetHandler) SetProjectBudget(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.SetBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget, err := h.budgetService.SetProjectBudget(ctx, projectID, &req, c.GetInt("user_id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to save project budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// DeleteProjectBudget handles DELETE /api/projects/:id/budgets/:period
+func (h *BudgetHandler) DeleteProjectBudget(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	if err := h.budgetService.DeleteProjectBudget(ctx, projectID, c.Param("period")); err != nil {
+		h.handleError(c, err, "Failed to delete project budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+}
+
+func (h *BudgetHandler) handleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrAgentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+	case errors.Is(err, service.ErrBudgetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrBudgetLimitMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
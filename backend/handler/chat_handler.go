@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/llm"
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ChatHandler struct {
+	chatService *service.ChatService
+}
+
+func NewChatHandler(chatService *service.ChatService) *ChatHandler {
+	return &ChatHandler{
+		chatService: chatService,
+	}
+}
+
+// Chat handles POST /api/agents/:id/chat
+func (h *ChatHandler) Chat(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	var req models.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Retries and fallback models can outlast the server's write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	resp, err := h.chatService.Chat(ctx, agentID, &req)
+	if err != nil {
+		handleChatError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ChatStream handles POST /api/agents/:id/chat/stream
+// Relays the completion as Server-Sent Events: "delta" events carry text chunks,
+// a final "done" event carries the full response with usage and tool calls, and "error" reports failures.
+func (h *ChatHandler) ChatStream(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	var req models.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	started := false
+	startStream := func() {
+		if started {
+			return
+		}
+		started = true
+
+		// Streams outlive the server's write timeout
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+	}
+
+	resp, err := h.chatService.ChatStream(ctx, agentID, &req, func(delta string) error {
+		startStream()
+		c.SSEvent("delta", gin.H{"content": delta})
+		c.Writer.Flush()
+		return ctx.Err()
+	})
+	if err != nil {
+		if !started {
+			handleChatError(c, err)
+			return
+		}
+		if ctx.Err() == nil {
+			c.SSEvent("error", gin.H{"error": err.Error()})
+			c.Writer.Flush()
+		}
+		return
+	}
+
+	startStream()
+	c.SSEvent("done", resp)
+	c.Writer.Flush()
+}
+
+// GetBuiltinTools handles GET /api/agents/:id/tools
+// Lists the built-in tools a chat request can enable with builtin_tools
+func (h *ChatHandler) GetBuiltinTools(c *gin.Context) {
+	c.JSON(http.StatusOK, h.chatService.GetBuiltinTools())
+}
+
+// handleChatError maps service and provider errors to HTTP responses
+func handleChatError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAgentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	case errors.Is(err, service.ErrTaskNotFound), errors.Is(err, service.ErrAssignmentNotFound), errors.Is(err, service.ErrAttachmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrAgentInactive), errors.Is(err, service.ErrAgentDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrProviderNotConfigured), errors.Is(err, service.ErrStreamingNotSupported), errors.Is(err, service.ErrInvalidTool),
+		errors.Is(err, service.ErrVisionNotSupported), errors.Is(err, service.ErrInvalidAttachment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, llm.ErrCircuitOpen):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "provider request timed out"})
+		return
+	}
+
+	var budgetErr *service.BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": budgetErr.Error(), "budgets": budgetErr.Budgets})
+		return
+	}
+
+	if providerErr, ok := llm.AsError(err); ok {
+		if providerErr.StatusCode == http.StatusTooManyRequests {
+			if providerErr.RetryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(providerErr.RetryAfter.Seconds())))
+			}
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": providerErr.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": providerErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete chat request"})
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/berkkaradalan/stackflow/llm"
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type EmbeddingHandler struct {
+	embeddingService *service.EmbeddingService
+	accessService    *service.AccessService
+}
+
+func NewEmbeddingHandler(embeddingService *service.EmbeddingService, accessService *service.AccessService) *EmbeddingHandler {
+	return &EmbeddingHandler{
+		embeddingService: embeddingService,
+		accessService:    accessService,
+	}
+}
+
+// CreateEmbeddings handles POST /api/embeddings
+// Agent tokens are billed to their own agent; users bill an agent or project they contribute to
+func (h *EmbeddingHandler) CreateEmbeddings(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req models.EmbeddingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.GetString("actor_type") == models.CreatorTypeAgent {
+		agentID := c.GetInt("agent_id")
+		if (req.AgentID != nil && *req.AgentID != agentID) || req.ProjectID != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "agent tokens may only bill their own agent"})
+			return
+		}
+		req.AgentID = &agentID
+	} else if c.GetString("role") != "admin" && (req.AgentID == nil) != (req.ProjectID == nil) {
+		projectID, err := h.billedProjectID(ctx, &req)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+			return
+		}
+
+		projectRole, err := h.accessService.ProjectRole(ctx, projectID, c.GetInt("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project membership"})
+			return
+		}
+		if models.ProjectRoleRank(projectRole) < models.ProjectRoleRank(models.ProjectRoleContributor) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient project permissions"})
+			return
+		}
+	}
+
+	resp, err := h.embeddingService.Embed(ctx, &req)
+	if err != nil {
+		handleEmbeddingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// billedProjectID returns the project a user's request is billed to
+func (h *EmbeddingHandler) billedProjectID(ctx context.Context, req *models.EmbeddingRequest) (int, error) {
+	if req.ProjectID != nil {
+		return *req.ProjectID, nil
+	}
+	return h.accessService.ProjectIDForAgent(ctx, *req.AgentID)
+}
+
+func handleEmbeddingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAgentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	case errors.Is(err, service.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	case errors.Is(err, service.ErrAgentInactive), errors.Is(err, service.ErrAgentDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrInvalidEmbeddingRequest), errors.Is(err, service.ErrNotEmbeddingModel),
+		errors.Is(err, service.ErrProviderNotConfigured), errors.Is(err, llm.ErrEmbeddingsNotSupported):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, llm.ErrCircuitOpen):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "provider request timed out"})
+		return
+	}
+
+	var budgetErr *service.BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": budgetErr.Error(), "budgets": budgetErr.Budgets})
+		return
+	}
+
+	if providerErr, ok := llm.AsError(err); ok {
+		if providerErr.StatusCode == http.StatusTooManyRequests {
+			if providerErr.RetryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(providerErr.RetryAfter.Seconds())))
+			}
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": providerErr.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": providerErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create embeddings"})
+}
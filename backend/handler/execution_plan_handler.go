@@ -22,11 +22,12 @@ func NewExecutionPlanHandler(planService *service.ExecutionPlanService) *Executi
 
 // getActorInfo extracts actor ID and type from context
 func (h *ExecutionPlanHandler) getActorInfo(c *gin.Context) (int, string, bool) {
-	userID, exists := c.Get("user_id")
+	actorID, exists := c.Get("actor_id")
 	if !exists {
 		return 0, "", false
 	}
-	return userID.(int), models.CreatorTypeUser, true
+	// Users authenticate with JWTs, agents with their own API tokens
+	return actorID.(int), c.GetString("actor_type"), true
 }
 
 // --- Execution Plan Endpoints ---
@@ -56,6 +57,10 @@ func (h *ExecutionPlanHandler) CreatePlan(c *gin.Context) {
 
 	plan, err := h.planService.CreatePlan(ctx, projectID, &req, actorID, actorType)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidExecutionPlan) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create execution plan"})
 		return
 	}
@@ -136,6 +141,10 @@ func (h *ExecutionPlanHandler) UpdatePlan(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "No active execution plan found"})
 			return
 		}
+		if errors.Is(err, service.ErrInvalidExecutionPlan) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update execution plan"})
 		return
 	}
@@ -196,6 +205,14 @@ func (h *ExecutionPlanHandler) TaskComplete(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "No active assignment found for this agent and task"})
 			return
 		}
+		if errors.Is(err, service.ErrAssignmentNotClaimed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrTestCoverageTooLow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete task"})
 		return
 	}
@@ -203,6 +220,40 @@ func (h *ExecutionPlanHandler) TaskComplete(c *gin.Context) {
 	c.JSON(http.StatusOK, assignment)
 }
 
+// HeartbeatAssignment handles POST /api/agents/:id/assignments/:assignmentId/heartbeat
+// Extends the lease of an assignment the agent is working on
+func (h *ExecutionPlanHandler) HeartbeatAssignment(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+	assignmentID, err := strconv.Atoi(c.Param("assignmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment ID"})
+		return
+	}
+
+	assignment, err := h.planService.HeartbeatAssignment(ctx, agentID, assignmentID)
+	if err != nil {
+		if errors.Is(err, service.ErrAssignmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+			return
+		}
+		if errors.Is(err, service.ErrAssignmentNotClaimed) {
+			// The lease lapsed and the assignment was reclaimed, or it was completed
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to extend assignment lease"})
+		return
+	}
+
+	c.JSON(http.StatusOK, assignment)
+}
+
 // GetAgentContext handles GET /api/agents/:id/context
 func (h *ExecutionPlanHandler) GetAgentContext(c *gin.Context) {
 	ctx := c.Request.Context()
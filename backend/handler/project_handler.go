@@ -49,7 +49,7 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 func (h *ProjectHandler) GetAllProjects(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	projects, err := h.projectService.GetAllProjects(ctx)
+	projects, err := h.projectService.GetAllProjects(ctx, c.GetInt("user_id"), c.GetString("role"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
 		return
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ProjectMemberHandler struct {
+	memberService *service.ProjectMemberService
+}
+
+func NewProjectMemberHandler(memberService *service.ProjectMemberService) *ProjectMemberHandler {
+	return &ProjectMemberHandler{
+		memberService: memberService,
+	}
+}
+
+// GetMembers handles GET /api/projects/:id/members
+func (h *ProjectMemberHandler) GetMembers(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	members, err := h.memberService.GetMembers(ctx, projectID)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch project members")
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddMember handles POST /api/projects/:id/members
+func (h *ProjectMemberHandler) AddMember(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var req models.AddProjectMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.memberService.AddMember(ctx, projectID, &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to add project member")
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// UpdateMemberRole handles PUT /api/projects/:id/members/:userId
+func (h *ProjectMemberHandler) UpdateMemberRole(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateProjectMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.memberService.UpdateMemberRole(ctx, projectID, userID, &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to update project member")
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember handles DELETE /api/projects/:id/members/:userId
+func (h *ProjectMemberHandler) RemoveMember(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.memberService.RemoveMember(ctx, projectID, userID); err != nil {
+		h.handleError(c, err, "Failed to remove project member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project member removed successfully"})
+}
+
+func (h *ProjectMemberHandler) handleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, service.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project member not found"})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, service.ErrMemberExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLastProjectOwner):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PromptTemplateHandler struct {
+	promptService *service.PromptTemplateService
+}
+
+func NewPromptTemplateHandler(promptService *service.PromptTemplateService) *PromptTemplateHandler {
+	return &PromptTemplateHandler{
+		promptService: promptService,
+	}
+}
+
+// GetDefaultTemplates handles GET /api/prompt-templates
+// Lists the newest version of every default template
+func (h *PromptTemplateHandler) GetDefaultTemplates(c *gin.Context) {
+	templates, err := h.promptService.GetTemplates(c.Request.Context(), nil)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch prompt templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetDefaultVersions handles GET /api/prompt-templates/versions?role=&level=
+func (h *PromptTemplateHandler) GetDefaultVersions(c *gin.Context) {
+	h.getVersions(c, nil)
+}
+
+// DiffDefaults handles GET /api/prompt-templates/diff?from=&to=
+func (h *PromptTemplateHandler) DiffDefaults(c *gin.Context) {
+	h.diff(c, nil)
+}
+
+// CreateDefaultTemplate handles POST /api/admin/prompt-templates
+// Saves a new version of a default template
+func (h *PromptTemplateHandler) CreateDefaultTemplate(c *gin.Context) {
+	h.create(c, nil)
+}
+
+// GetProjectTemplates handles GET /api/projects/:id/prompt-templates
+// Lists the newest version of every template the project overrides
+func (h *PromptTemplateHandler) GetProjectTemplates(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	templates, err := h.promptService.GetTemplates(c.Request.Context(), &projectID)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch prompt templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetProjectVersions handles GET /api/projects/:id/prompt-templates/versions?role=&level=
+func (h *PromptTemplateHandler) GetProjectVersions(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.getVersions(c, &projectID)
+}
+
+// DiffProjectTemplates handles GET /api/projects/:id/prompt-templates/diff?from=&to=
+// Either side may be one of the project's versions or a default
+func (h *PromptTemplateHandler) DiffProjectTemplates(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.diff(c, &projectID)
+}
+
+// CreateProjectTemplate handles POST /api/projects/:id/prompt-templates
+// Saves a new version of the project's override for a role and level
+func (h *PromptTemplateHandler) CreateProjectTemplate(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.create(c, &projectID)
+}
+
+// GetAgentPrompt handles GET /api/agents/:id/prompt?task_id=
+// Renders the system prompt the agent gets, optionally for a run on a task
+func (h *PromptTemplateHandler) GetAgentPrompt(c *gin.Context) {
+	agentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	var taskID *int
+	if raw := c.Query("task_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+			return
+		}
+		taskID = &id
+	}
+
+	prompt, err := h.promptService.PreviewAgentPrompt(c.Request.Context(), agentID, taskID)
+	if err != nil {
+		h.handleError(c, err, "Failed to render agent prompt")
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+func (h *PromptTemplateHandler) getVersions(c *gin.Context, projectID *int) {
+	var query models.PromptTemplateQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	versions, err := h.promptService.GetVersions(c.Request.Context(), projectID, &query)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch prompt template versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+func (h *PromptTemplateHandler) diff(c *gin.Context, projectID *int) {
+	var query models.PromptTemplateDiffQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diff, err := h.promptService.Diff(c.Request.Context(), projectID, query.From, query.To)
+	if err != nil {
+		h.handleError(c, err, "Failed to diff prompt templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+func (h *PromptTemplateHandler) create(c *gin.Context, projectID *int) {
+	var req models.CreatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.promptService.CreateTemplate(c.Request.Context(), projectID, &req, c.GetInt("user_id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to save prompt template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *PromptTemplateHandler) handleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, service.ErrAgentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+	case errors.Is(err, service.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	case errors.Is(err, service.ErrPromptTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prompt template not found"})
+	case errors.Is(err, service.ErrInvalidPromptTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// projectIDParam parses the :id route parameter of project routes
+func projectIDParam(c *gin.Context) (int, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return 0, false
+	}
+	return projectID, true
+}
@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ProviderCredentialHandler struct {
+	credentialService *service.ProviderCredentialService
+	keyService        *service.AgentAPIKeyService
+}
+
+func NewProviderCredentialHandler(credentialService *service.ProviderCredentialService, keyService *service.AgentAPIKeyService) *ProviderCredentialHandler {
+	return &ProviderCredentialHandler{
+		credentialService: credentialService,
+		keyService:        keyService,
+	}
+}
+
+// GetOrganizationCredentials handles GET /api/admin/credentials
+func (h *ProviderCredentialHandler) GetOrganizationCredentials(c *gin.Context) {
+	h.list(c, nil)
+}
+
+// CreateOrganizationCredential handles POST /api/admin/credentials
+// Stores a credential agents of every project can use
+func (h *ProviderCredentialHandler) CreateOrganizationCredential(c *gin.Context) {
+	h.create(c, nil)
+}
+
+// UpdateOrganizationCredential handles PUT /api/admin/credentials/:credentialId
+func (h *ProviderCredentialHandler) UpdateOrganizationCredential(c *gin.Context) {
+	h.update(c, nil)
+}
+
+// DeleteOrganizationCredential handles DELETE /api/admin/credentials/:credentialId
+func (h *ProviderCredentialHandler) DeleteOrganizationCredential(c *gin.Context) {
+	h.delete(c, nil)
+}
+
+// TestOrganizationCredential handles POST /api/admin/credentials/:credentialId/test
+func (h *ProviderCredentialHandler) TestOrganizationCredential(c *gin.Context) {
+	h.test(c, nil)
+}
+
+// GetOrganizationCredentialKeys handles GET /api/admin/credentials/:credentialId/api-keys
+// Lists the extra keys agents using the credential rotate over
+func (h *ProviderCredentialHandler) GetOrganizationCredentialKeys(c *gin.Context) {
+	h.listKeys(c, nil)
+}
+
+// CreateOrganizationCredentialKey handles POST /api/admin/credentials/:credentialId/api-keys
+func (h *ProviderCredentialHandler) CreateOrganizationCredentialKey(c *gin.Context) {
+	h.createKey(c, nil)
+}
+
+// UpdateOrganizationCredentialKey handles PUT /api/admin/credentials/:credentialId/api-keys/:keyId
+func (h *ProviderCredentialHandler) UpdateOrganizationCredentialKey(c *gin.Context) {
+	h.updateKey(c, nil)
+}
+
+// DeleteOrganizationCredentialKey handles DELETE /api/admin/credentials/:credentialId/api-keys/:keyId
+func (h *ProviderCredentialHandler) DeleteOrganizationCredentialKey(c *gin.Context) {
+	h.deleteKey(c, nil)
+}
+
+// GetProjectCredentials handles GET /api/projects/:id/credentials
+// Lists the project's credentials followed by the organization-wide ones
+func (h *ProviderCredentialHandler) GetProjectCredentials(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.list(c, &projectID)
+}
+
+// CreateProjectCredential handles POST /api/projects/:id/credentials
+func (h *ProviderCredentialHandler) CreateProjectCredential(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.create(c, &projectID)
+}
+
+// UpdateProjectCredential handles PUT /api/projects/:id/credentials/:credentialId
+func (h *ProviderCredentialHandler) UpdateProjectCredential(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.update(c, &projectID)
+}
+
+// DeleteProjectCredential handles DELETE /api/projects/:id/credentials/:credentialId
+func (h *ProviderCredentialHandler) DeleteProjectCredential(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.delete(c, &projectID)
+}
+
+// TestProjectCredential handles POST /api/projects/:id/credentials/:credentialId/test
+func (h *ProviderCredentialHandler) TestProjectCredential(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.test(c, &projectID)
+}
+
+// GetProjectCredentialKeys handles GET /api/projects/:id/credentials/:credentialId/api-keys
+func (h *ProviderCredentialHandler) GetProjectCredentialKeys(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.listKeys(c, &projectID)
+}
+
+// CreateProjectCredentialKey handles POST /api/projects/:id/credentials/:credentialId/api-keys
+func (h *ProviderCredentialHandler) CreateProjectCredentialKey(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.createKey(c, &projectID)
+}
+
+// UpdateProjectCredentialKey handles PUT /api/projects/:id/credentials/:credentialId/api-keys/:keyId
+func (h *ProviderCredentialHandler) UpdateProjectCredentialKey(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.updateKey(c, &projectID)
+}
+
+// DeleteProjectCredentialKey handles DELETE /api/projects/:id/credentials/:credentialId/api-keys/:keyId
+func (h *ProviderCredentialHandler) DeleteProjectCredentialKey(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+	h.deleteKey(c, &projectID)
+}
+
+func (h *ProviderCredentialHandler) list(c *gin.Context, projectID *int) {
+	credentials, err := h.credentialService.GetCredentials(c.Request.Context(), projectID)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch provider credentials")
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+func (h *ProviderCredentialHandler) create(c *gin.Context, projectID *int) {
+	var req models.CreateProviderCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.credentialService.CreateCredential(c.Request.Context(), projectID, &req, c.GetInt("user_id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to create provider credential")
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+func (h *ProviderCredentialHandler) update(c *gin.Context, projectID *int) {
+	credentialID, ok := credentialIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProviderCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.credentialService.UpdateCredential(c.Request.Context(), projectID, credentialID, &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to update provider credential")
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+func (h *ProviderCredentialHandler) delete(c *gin.Context, projectID *int) {
+	credentialID, ok := credentialIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.credentialService.DeleteCredential(c.Request.Context(), projectID, credentialID); err != nil {
+		h.handleError(c, err, "Failed to delete provider credential")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Provider credential deleted successfully"})
+}
+
+func (h *ProviderCredentialHandler) test(c *gin.Context, projectID *int) {
+	credentialID, ok := credentialIDParam(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional; without one the provider's first chat model is tested
+	var req models.TestProviderCredentialRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.credentialService.TestCredential(c.Request.Context(), projectID, credentialID, &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to test provider credential")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *ProviderCredentialHandler) listKeys(c *gin.Context, projectID *int) {
+	credentialID, ok := credentialIDParam(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.keyService.GetCredentialKeys(c.Request.Context(), projectID, credentialID)
+	if err != nil {
+		h.handleError(c, err, "Failed to fetch credential api keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *ProviderCredentialHandler) createKey(c *gin.Context, projectID *int) {
+	credentialID, ok := credentialIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAgentAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.keyService.CreateCredentialKey(c.Request.Context(), projectID, credentialID, &req, c.GetInt("user_id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to add credential api key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *ProviderCredentialHandler) updateKey(c *gin.Context, projectID *int) {
+	credentialID, keyID, ok := credentialKeyParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateAgentAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.keyService.UpdateCredentialKey(c.Request.Context(), projectID, credentialID, keyID, &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to update credential api key")
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func (h *ProviderCredentialHandler) deleteKey(c *gin.Context, projectID *int) {
+	credentialID, keyID, ok := credentialKeyParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.keyService.DeleteCredentialKey(c.Request.Context(), projectID, credentialID, keyID); err != nil {
+		h.handleError(c, err, "Failed to delete credential api key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Credential api key deleted successfully"})
+}
+
+func (h *ProviderCredentialHandler) handleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, service.ErrProviderCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider credential not found"})
+	case errors.Is(err, service.ErrAgentAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Credential api key not found"})
+	case errors.Is(err, service.ErrProviderCredentialInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidProviderCredential), errors.Is(err, service.ErrProviderNotConfigured):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// credentialIDParam parses the :credentialId route parameter
+func credentialIDParam(c *gin.Context) (int, bool) {
+	credentialID, err := strconv.Atoi(c.Param("credentialId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential ID"})
+		return 0, false
+	}
+	return credentialID, true
+}
+
+// credentialKeyParams parses the :credentialId and :keyId route parameters
+func credentialKeyParams(c *gin.Context) (int, int, bool) {
+	credentialID, ok := credentialIDParam(c)
+	if !ok {
+		return 0, 0, false
+	}
+	keyID, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api key ID"})
+		return 0, 0, false
+	}
+	return credentialID, keyID, true
+}
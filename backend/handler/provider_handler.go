@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/berkkaradalan/stackflow/models"
 	"github.com/berkkaradalan/stackflow/service"
 	"github.com/gin-gonic/gin"
 )
@@ -48,3 +50,84 @@ func (h *ProviderHandler) GetProviderByName(c *gin.Context) {
 
 	c.JSON(http.StatusOK, provider)
 }
+
+// GetCustomProviders handles GET /api/admin/providers
+func (h *ProviderHandler) GetCustomProviders(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	providers, err := h.providerService.GetCustomProviders(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch custom providers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, providers)
+}
+
+// CreateCustomProvider handles POST /api/admin/providers
+func (h *ProviderHandler) CreateCustomProvider(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req models.CustomProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	provider, err := h.providerService.CreateCustomProvider(ctx, &req, c.GetInt("user_id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to create custom provider")
+		return
+	}
+
+	c.JSON(http.StatusCreated, provider)
+}
+
+// UpdateCustomProvider handles PUT /api/admin/providers/:name
+func (h *ProviderHandler) UpdateCustomProvider(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req models.CustomProviderRequest
+	// The name comes from the path
+	req.Name = c.Param("name")
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	provider, err := h.providerService.UpdateCustomProvider(ctx, c.Param("name"), &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to update custom provider")
+		return
+	}
+
+	c.JSON(http.StatusOK, provider)
+}
+
+// DeleteCustomProvider handles DELETE /api/admin/providers/:name
+func (h *ProviderHandler) DeleteCustomProvider(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if err := h.providerService.DeleteCustomProvider(ctx, c.Param("name")); err != nil {
+		h.handleError(c, err, "Failed to delete custom provider")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom provider deleted successfully"})
+}
+
+func (h *ProviderHandler) handleError(c *gin.Context, err error, fallback string) {
+	var notFound *service.ProviderNotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidProvider):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrBuiltInProvider):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrProviderExists), errors.Is(err, service.ErrProviderInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 
@@ -22,12 +24,12 @@ func NewTaskHandler(taskService *service.TaskService) *TaskHandler {
 
 // getActorInfo extracts actor ID and type from context
 func (h *TaskHandler) getActorInfo(c *gin.Context) (int, string, bool) {
-	userID, exists := c.Get("user_id")
+	actorID, exists := c.Get("actor_id")
 	if !exists {
 		return 0, "", false
 	}
-	// For now, all requests come from users (agents will use a different auth mechanism)
-	return userID.(int), models.CreatorTypeUser, true
+	// Users authenticate with JWTs, agents with their own API tokens
+	return actorID.(int), c.GetString("actor_type"), true
 }
 
 // GetAllTasks handles GET /api/tasks
@@ -63,6 +65,16 @@ func (h *TaskHandler) GetAllTasks(c *gin.Context) {
 		}
 	}
 
+	// Agents can only list tasks of their own project
+	if c.GetString("actor_type") == models.CreatorTypeAgent {
+		projectID := c.GetInt("agent_project_id")
+		filters.ProjectID = &projectID
+	} else if c.GetString("role") != "admin" {
+		// Users only see tasks of projects they are a member of
+		userID := c.GetInt("user_id")
+		filters.MemberUserID = &userID
+	}
+
 	tasks, err := h.taskService.GetAllTasks(ctx, &filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
@@ -353,14 +365,24 @@ func (h *TaskHandler) CompleteTask(c *gin.Context) {
 		return
 	}
 
-	task, err := h.taskService.CompleteTask(ctx, id, req.Message, actorID, actorType)
+	// Admins skip project membership checks and approve reviews like owners
+	projectRole := c.GetString("project_role")
+	if c.GetString("role") == "admin" {
+		projectRole = models.ProjectRoleOwner
+	}
+
+	task, err := h.taskService.CompleteTask(ctx, id, req.Message, actorID, actorType, projectRole)
 	if err != nil {
 		if errors.Is(err, service.ErrTaskNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 			return
 		}
+		if errors.Is(err, service.ErrReviewNotAllowed) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if errors.Is(err, service.ErrInvalidStatusTransition) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Task can only be completed from in_progress status"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Task can only be completed from in_progress or in_review status"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete task"})
@@ -471,7 +493,7 @@ func (h *TaskHandler) ReopenTask(c *gin.Context) {
 			return
 		}
 		if errors.Is(err, service.ErrInvalidStatusTransition) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Task can only be reopened from closed, done, or wont_do status"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Task can only be reopened from closed, done, wont_do, or in_review status"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reopen task"})
@@ -540,3 +562,136 @@ func (h *TaskHandler) AddProgress(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, activity)
 }
+
+// UploadAttachment handles POST /api/tasks/:id/attachments
+// Expects a multipart form with the image in the "file" field
+func (h *TaskHandler) UploadAttachment(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	actorID, actorType, ok := h.getActorInfo(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxAttachmentBytes+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a file field with the image is required"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, models.MaxAttachmentBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	attachment, err := h.taskService.AddAttachment(ctx, id, fileHeader.Filename, data, actorID, actorType)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, service.ErrInvalidAttachment):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload attachment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachments handles GET /api/tasks/:id/attachments
+func (h *TaskHandler) GetAttachments(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	attachments, err := h.taskService.GetAttachments(ctx, id)
+	if err != nil {
+		if errors.Is(err, service.ErrTaskNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get attachments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// DownloadAttachment handles GET /api/tasks/:id/attachments/:attachmentId
+// Returns the image itself
+func (h *TaskHandler) DownloadAttachment(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+	attachmentID, err := strconv.Atoi(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, err := h.taskService.GetAttachment(ctx, id, attachmentID)
+	if err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get attachment"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}))
+	c.Data(http.StatusOK, attachment.ContentType, attachment.Data)
+}
+
+// DeleteAttachment handles DELETE /api/tasks/:id/attachments/:attachmentId
+func (h *TaskHandler) DeleteAttachment(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+	attachmentID, err := strconv.Atoi(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	if err := h.taskService.DeleteAttachment(ctx, id, attachmentID); err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
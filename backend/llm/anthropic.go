@@ -0,0 +1,278 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+const (
+	anthropicVersion = "2023-06-01"
+	// anthropicDefaultMaxTokens is used when the request leaves MaxTokens unset, since the API requires it
+	anthropicDefaultMaxTokens = 1024
+)
+
+// anthropicProvider speaks the Anthropic Messages API
+type anthropicProvider struct {
+	cfg    *models.ProviderConfig
+	apiKey string
+	client *http.Client
+	// streamClient has no overall deadline, streams are bounded by openStream's idle timeout
+	streamClient *http.Client
+}
+
+// anthropicContentBlock is a text, image, tool_use or tool_result block
+type anthropicContentBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   string                `json:"content,omitempty"`
+}
+
+// anthropicImageSource is the base64 source of an image block
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        *float64           `json:"top_p,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	Model      string                  `json:"model"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+}
+
+func (p *anthropicProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *anthropicProvider) breakerKey() string {
+	return breakerKey(p.cfg, p.apiKey)
+}
+
+func (p *anthropicProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var result anthropicResponse
+	if err := postJSON(ctx, p.client, p.Name(), p.cfg.BaseURL+p.cfg.ChatCompletionPath, p.headers(), p.buildRequest(req), &result); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: toolArguments(string(block.Input))})
+		}
+	}
+
+	resp := &ChatResponse{
+		Model:        result.Model,
+		Content:      text.String(),
+		ToolCalls:    toolCalls,
+		FinishReason: result.StopReason,
+		Usage: Usage{
+			InputTokens:  result.Usage.InputTokens,
+			OutputTokens: result.Usage.OutputTokens,
+			TotalTokens:  result.Usage.InputTokens + result.Usage.OutputTokens,
+		},
+	}
+	if resp.Model == "" {
+		resp.Model = req.Model
+	}
+
+	return resp, nil
+}
+
+// anthropicStreamEvent covers the fields used from message_start, content_block_start,
+// content_block_delta, message_delta and error events
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		Model string         `json:"model"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock anthropicContentBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *anthropicProvider) ChatStream(ctx context.Context, req *ChatRequest, onChunk func(StreamChunk) error) (*ChatResponse, error) {
+	body := p.buildRequest(req)
+	body.Stream = true
+
+	resp := &ChatResponse{Model: req.Model}
+	var content strings.Builder
+	// tool_use blocks by content block index; their input arrives as partial JSON
+	var toolIndexes []int
+	toolCalls := make(map[int]*ToolCall)
+	toolInputs := make(map[int]*strings.Builder)
+
+	err := openStream(ctx, p.streamClient, p.Name(), p.cfg.BaseURL+p.cfg.ChatCompletionPath, p.headers(), body, func(_, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return &Error{Provider: p.Name(), Message: fmt.Sprintf("failed to decode stream event: %v", err)}
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message.Model != "" {
+				resp.Model = event.Message.Model
+			}
+			resp.Usage.InputTokens = event.Message.Usage.InputTokens
+			resp.Usage.OutputTokens = event.Message.Usage.OutputTokens
+		case "content_block_start":
+			if event.ContentBlock.Type == "tool_use" {
+				toolIndexes = append(toolIndexes, event.Index)
+				toolCalls[event.Index] = &ToolCall{ID: event.ContentBlock.ID, Name: event.ContentBlock.Name}
+				toolInputs[event.Index] = &strings.Builder{}
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				content.WriteString(event.Delta.Text)
+				return onChunk(StreamChunk{Delta: event.Delta.Text})
+			}
+			if event.Delta.Type == "input_json_delta" && toolInputs[event.Index] != nil {
+				toolInputs[event.Index].WriteString(event.Delta.PartialJSON)
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				resp.FinishReason = event.Delta.StopReason
+			}
+			if event.Usage != nil {
+				// message_delta carries the cumulative output token count
+				resp.Usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "message_stop":
+			return io.EOF
+		case "error":
+			return &Error{
+				Provider:  p.Name(),
+				Message:   event.Error.Message,
+				Retryable: event.Error.Type == "overloaded_error",
+			}
+		}
+		return nil
+	})
+
+	resp.Content = content.String()
+	for _, index := range toolIndexes {
+		call := toolCalls[index]
+		call.Arguments = toolArguments(toolInputs[index].String())
+		resp.ToolCalls = append(resp.ToolCalls, *call)
+	}
+	resp.Usage.TotalTokens = resp.Usage.InputTokens + resp.Usage.OutputTokens
+	return resp, err
+}
+
+func (p *anthropicProvider) buildRequest(req *ChatRequest) anthropicRequest {
+	system, conversation := splitSystem(req.Messages)
+
+	messages := make([]anthropicMessage, 0, len(conversation))
+	for _, m := range conversation {
+		role := m.Role
+		var blocks []anthropicContentBlock
+
+		switch {
+		case m.Role == RoleTool:
+			// Tool results are sent back as user content
+			role = RoleUser
+			blocks = append(blocks, anthropicContentBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content})
+		default:
+			if m.Content != "" || len(m.ToolCalls) == 0 {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: m.Content})
+			}
+			for _, image := range m.Images {
+				blocks = append(blocks, anthropicContentBlock{
+					Type:   "image",
+					Source: &anthropicImageSource{Type: "base64", MediaType: image.MediaType, Data: image.base64()},
+				})
+			}
+			for _, call := range m.ToolCalls {
+				blocks = append(blocks, anthropicContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: toolInput(call.Arguments)})
+			}
+		}
+
+		// Consecutive messages of one role, such as several tool results, form a single turn
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, blocks...)
+			continue
+		}
+		messages = append(messages, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	var tools []anthropicTool
+	for _, tool := range req.Tools {
+		tools = append(tools, anthropicTool{Name: tool.Name, Description: tool.Description, InputSchema: toolSchema(tool)})
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+
+	return anthropicRequest{
+		Model:       req.Model,
+		System:      system,
+		Messages:    messages,
+		Tools:       tools,
+		MaxTokens:   maxTokens,
+		Temperature: optional(req.Temperature),
+		TopP:        optional(req.TopP),
+	}
+}
+
+func (p *anthropicProvider) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+}
@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestAnthropicChat(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *ChatRequest
+		resp          string
+		wantRequest   string
+		wantContent   string
+		wantToolCalls []ToolCall
+		wantFinish    string
+		wantUsage     Usage
+	}{
+		{
+			name: "system prompt and default max tokens",
+			req: &ChatRequest{
+				Model:       "claude-test",
+				Messages:    []Message{{Role: RoleSystem, Content: "be brief"}, {Role: RoleUser, Content: "hi"}},
+				Temperature: 0.2,
+			},
+			resp: `{"model":"claude-test-20250101","content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn",
+				"usage":{"input_tokens":10,"output_tokens":2}}`,
+			wantRequest: `{"model":"claude-test","system":"be brief","max_tokens":1024,"temperature":0.2,
+				"messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`,
+			wantContent: "hello",
+			wantFinish:  "end_turn",
+			wantUsage:   Usage{InputTokens: 10, OutputTokens: 2, TotalTokens: 12},
+		},
+		{
+			name: "tool use round trip",
+			req: &ChatRequest{
+				Model:     "claude-test",
+				MaxTokens: 256,
+				Messages: []Message{
+					{Role: RoleUser, Content: "weather?"},
+					{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "toolu_1", Name: "weather", Arguments: json.RawMessage(`{"city":"Izmir"}`)}}},
+					{Role: RoleTool, ToolCallID: "toolu_1", Content: "sunny"},
+				},
+				Tools: []Tool{{Name: "weather", Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`)}},
+			},
+			resp: `{"content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_2","name":"weather","input":{"city":"Ankara"}}],
+				"stop_reason":"tool_use","usage":{"input_tokens":30,"output_tokens":8}}`,
+			wantRequest: `{"model":"claude-test","max_tokens":256,
+				"messages":[
+					{"role":"user","content":[{"type":"text","text":"weather?"}]},
+					{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Izmir"}}]},
+					{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"sunny"}]}],
+				"tools":[{"name":"weather","input_schema":{"type":"object","properties":{"city":{"type":"string"}}}}]}`,
+			wantContent:   "Checking.",
+			wantToolCalls: []ToolCall{{ID: "toolu_2", Name: "weather", Arguments: json.RawMessage(`{"city":"Ankara"}`)}},
+			wantFinish:    "tool_use",
+			wantUsage:     Usage{InputTokens: 30, OutputTokens: 8, TotalTokens: 38},
+		},
+		{
+			name: "image",
+			req: &ChatRequest{
+				Model:    "claude-test",
+				Messages: []Message{{Role: RoleUser, Content: "what is this?", Images: []Image{{MediaType: "image/png", Data: []byte("png")}}}},
+			},
+			resp: `{"content":[{"type":"text","text":"a logo"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}`,
+			wantRequest: `{"model":"claude-test","max_tokens":1024,"messages":[{"role":"user","content":[
+				{"type":"text","text":"what is this?"},
+				{"type":"image","source":{"type":"base64","media_type":"image/png","data":"cG5n"}}]}]}`,
+			wantContent: "a logo",
+			wantFinish:  "end_turn",
+			wantUsage:   Usage{InputTokens: 5, OutputTokens: 2, TotalTokens: 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, captured := fakeProvider(t, FormatAnthropic, fakeResponse{body: tt.resp})
+
+			resp, err := newTestProvider(t, cfg).Chat(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Chat: %v", err)
+			}
+
+			if captured.path != "/messages" {
+				t.Errorf("path = %q, want /messages", captured.path)
+			}
+			if got := captured.header.Get("x-api-key"); got != "test-key" {
+				t.Errorf("x-api-key = %q, want test-key", got)
+			}
+			if got := captured.header.Get("anthropic-version"); got != anthropicVersion {
+				t.Errorf("anthropic-version = %q, want %q", got, anthropicVersion)
+			}
+			if got := captured.header.Get("Authorization"); got != "" {
+				t.Errorf("Authorization = %q, want none", got)
+			}
+			if !jsonEqual(t, captured.body, tt.wantRequest) {
+				t.Errorf("request body = %v, want %s", captured.body, tt.wantRequest)
+			}
+
+			if resp.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", resp.Content, tt.wantContent)
+			}
+			if !jsonEqual(t, resp.ToolCalls, mustJSON(t, tt.wantToolCalls)) {
+				t.Errorf("tool calls = %+v, want %+v", resp.ToolCalls, tt.wantToolCalls)
+			}
+			if resp.FinishReason != tt.wantFinish {
+				t.Errorf("finish reason = %q, want %q", resp.FinishReason, tt.wantFinish)
+			}
+			if resp.Usage != tt.wantUsage {
+				t.Errorf("usage = %+v, want %+v", resp.Usage, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestAnthropicChatStream(t *testing.T) {
+	stream := `event: message_start
+data: {"type":"message_start","message":{"model":"claude-test-20250101","usage":{"input_tokens":11,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Izmir\"}"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":15}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+	cfg, captured := fakeProvider(t, FormatAnthropic, fakeResponse{headers: map[string]string{"Content-Type": "text/event-stream"}, body: stream})
+
+	var deltas []string
+	resp, err := newTestProvider(t, cfg).ChatStream(context.Background(), &ChatRequest{Model: "claude-test", Messages: []Message{{Role: RoleUser, Content: "hi"}}}, func(chunk StreamChunk) error {
+		deltas = append(deltas, chunk.Delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	if captured.body["stream"] != true {
+		t.Errorf("request body = %v, want stream", captured.body)
+	}
+	if len(deltas) != 2 || resp.Content != "Hello" {
+		t.Errorf("deltas = %q, content = %q, want two deltas forming Hello", deltas, resp.Content)
+	}
+	if resp.Model != "claude-test-20250101" || resp.FinishReason != "tool_use" {
+		t.Errorf("model = %q, finish = %q", resp.Model, resp.FinishReason)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "toolu_1" || string(resp.ToolCalls[0].Arguments) != `{"city":"Izmir"}` {
+		t.Errorf("tool calls = %+v, want the reassembled weather call", resp.ToolCalls)
+	}
+	// message_delta carries the cumulative output count
+	if want := (Usage{InputTokens: 11, OutputTokens: 15, TotalTokens: 26}); resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	stream := `event: error
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+
+`
+	cfg, _ := fakeProvider(t, FormatAnthropic, fakeResponse{headers: map[string]string{"Content-Type": "text/event-stream"}, body: stream})
+
+	_, err := newTestProvider(t, cfg).ChatStream(context.Background(), &ChatRequest{Model: "claude-test"}, func(StreamChunk) error { return nil })
+	providerErr, ok := AsError(err)
+	if !ok {
+		t.Fatalf("got %v, want a provider error", err)
+	}
+	if providerErr.Message != "Overloaded" || !providerErr.Retryable {
+		t.Errorf("got %+v, want a retryable overloaded error", providerErr)
+	}
+}
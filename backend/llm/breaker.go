@@ -0,0 +1,158 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+// ErrCircuitOpen is returned without calling the provider while its circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Circuit breaker defaults: open after 5 consecutive failures, probe again after 30s.
+// Breakers unused for breakerIdleTTL are dropped from the registry.
+const (
+	breakerFailureThreshold = 5
+	breakerCooldown         = 30 * time.Second
+	breakerIdleTTL          = 10 * time.Minute
+)
+
+// CircuitBreaker stops calls to a provider after repeated retryable failures.
+// After the cooldown a single probe call is let through; its outcome closes or re-opens the circuit.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+	lastUsed  time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+var breakers = struct {
+	sync.Mutex
+	byKey map[string]*CircuitBreaker
+	swept time.Time
+}{byKey: make(map[string]*CircuitBreaker)}
+
+// BreakerFor returns the shared circuit breaker of key. Keys change with every rotated
+// credential, so breakers left idle are swept out while looking one up.
+func BreakerFor(key string) *CircuitBreaker {
+	now := time.Now()
+
+	breakers.Lock()
+	defer breakers.Unlock()
+
+	if now.Sub(breakers.swept) >= breakerIdleTTL {
+		for k, b := range breakers.byKey {
+			if b.idle(now) {
+				delete(breakers.byKey, k)
+			}
+		}
+		breakers.swept = now
+	}
+
+	breaker, ok := breakers.byKey[key]
+	if !ok {
+		breaker = NewCircuitBreaker(breakerFailureThreshold, breakerCooldown)
+		breakers.byKey[key] = breaker
+	}
+	breaker.touch(now)
+	return breaker
+}
+
+// breakerScoped is implemented by adapters whose calls go to one endpoint with one credential
+type breakerScoped interface {
+	breakerKey() string
+}
+
+// breakerKeyOf returns the circuit a provider's calls count towards. Adapters share a circuit
+// only when they call the same base URL with the same credential, so one failing key or
+// endpoint does not stop calls that go elsewhere.
+func breakerKeyOf(p Provider) string {
+	if scoped, ok := p.(breakerScoped); ok {
+		return scoped.breakerKey()
+	}
+	return p.Name()
+}
+
+// breakerKey identifies the provider's base URL and credential. The credential is hashed so
+// the breaker registry never holds a plaintext key.
+func breakerKey(cfg *models.ProviderConfig, credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return cfg.Name + "|" + cfg.BaseURL + "|" + hex.EncodeToString(sum[:8])
+}
+
+// touch marks the breaker as in use
+func (b *CircuitBreaker) touch(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastUsed = now
+}
+
+// idle reports whether the breaker went unused for breakerIdleTTL and is not holding a
+// circuit open, so dropping it loses nothing
+func (b *CircuitBreaker) idle(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return now.Sub(b.lastUsed) >= breakerIdleTTL && !b.probing && !now.Before(b.openUntil)
+}
+
+// Allow reports whether a call may proceed
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.lastUsed = now
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Success closes the circuit
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure counts a failed call and opens the circuit once the threshold is reached
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// Release ends a probe whose outcome says nothing about the provider, such as a cancelled call
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
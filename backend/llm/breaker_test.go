@@ -0,0 +1,29 @@
+package llm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerForEvictsIdleBreakers(t *testing.T) {
+	idle := BreakerFor("test|idle")
+	open := BreakerFor("test|open")
+	for i := 0; i < breakerFailureThreshold; i++ {
+		open.Failure()
+	}
+
+	// Age both breakers past the idle TTL and force the next lookup to sweep
+	past := time.Now().Add(-2 * breakerIdleTTL)
+	idle.touch(past)
+	open.touch(past)
+	breakers.Lock()
+	breakers.swept = past
+	breakers.Unlock()
+
+	if BreakerFor("test|other"); BreakerFor("test|idle") == idle {
+		t.Error("idle breaker was kept, want it replaced")
+	}
+	if BreakerFor("test|open") != open {
+		t.Error("open breaker was dropped, want it kept until its cooldown ends")
+	}
+}
@@ -0,0 +1,405 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cassette modes
+const (
+	CassetteOff    = "off"
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+	// CassetteReplayLoose also serves recordings whose request body differs and,
+	// once a cassette is used up, serves it again from the start
+	CassetteReplayLoose = "replay-loose"
+)
+
+// ErrNoRecording is returned in replay mode when a call has no matching recording
+var ErrNoRecording = errors.New("no recorded provider response")
+
+const redacted = "[REDACTED]"
+
+// Headers and query parameters that carry credentials and are never written to a cassette
+var (
+	secretHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key", "Api-Key", "Cookie", "Set-Cookie"}
+	secretParams  = []string{"key", "api_key"}
+)
+
+// Interaction is a single recorded provider call
+type Interaction struct {
+	RecordedAt   time.Time         `json:"recorded_at"`
+	AgentID      int               `json:"agent_id"`
+	AssignmentID *int              `json:"assignment_id,omitempty"`
+	Request      RecordedRequest   `json:"request"`
+	Response     *RecordedResponse `json:"response,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	LatencyMs    int64             `json:"latency_ms"`
+}
+
+// RecordedRequest is a sanitized provider request
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+// RecordedResponse is a provider response; streamed responses hold the raw event stream
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+type cassetteKey struct {
+	agentID      int
+	assignmentID int
+}
+
+type cassetteContextKey struct{}
+
+// WithCassetteKey files the provider calls made with ctx under an agent and,
+// when set, an assignment. Calls without a key are never recorded or replayed.
+func WithCassetteKey(ctx context.Context, agentID int, assignmentID *int) context.Context {
+	key := cassetteKey{agentID: agentID}
+	if assignmentID != nil {
+		key.assignmentID = *assignmentID
+	}
+	return context.WithValue(ctx, cassetteContextKey{}, key)
+}
+
+// cassetteStore records provider traffic to, or replays it from, JSON Lines files under
+// dir: agent-<id>/assignment-<id>.jsonl, or agent-<id>/unassigned.jsonl
+type cassetteStore struct {
+	mode string
+	dir  string
+
+	mu sync.Mutex
+	// Replay state: the recordings of a key and which of them were already served
+	tapes map[cassetteKey]*tape
+}
+
+type tape struct {
+	interactions []Interaction
+	used         []bool
+}
+
+var (
+	cassettesMu sync.RWMutex
+	cassettes   *cassetteStore
+)
+
+// ConfigureCassettes turns recording or replay of provider traffic on or off.
+// It applies to clients created by New afterwards.
+func ConfigureCassettes(mode, dir string) error {
+	var store *cassetteStore
+
+	switch mode {
+	case "", CassetteOff:
+	case CassetteRecord, CassetteReplay, CassetteReplayLoose:
+		if dir == "" {
+			return errors.New("cassette directory is required")
+		}
+		store = &cassetteStore{mode: mode, dir: dir, tapes: make(map[cassetteKey]*tape)}
+	default:
+		return fmt.Errorf("unknown cassette mode %q, expected off, record, replay or replay-loose", mode)
+	}
+
+	cassettesMu.Lock()
+	cassettes = store
+	cassettesMu.Unlock()
+	return nil
+}
+
+// cassetteTransport wraps base with the configured cassette store, if any
+func cassetteTransport(base http.RoundTripper) http.RoundTripper {
+	cassettesMu.RLock()
+	defer cassettesMu.RUnlock()
+
+	if cassettes == nil {
+		return base
+	}
+	return &cassetteRoundTripper{store: cassettes, base: base}
+}
+
+type cassetteRoundTripper struct {
+	store *cassetteStore
+	base  http.RoundTripper
+}
+
+func (t *cassetteRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, ok := req.Context().Value(cassetteContextKey{}).(cassetteKey)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	recorded, err := sanitizeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.store.mode == CassetteReplay || t.store.mode == CassetteReplayLoose {
+		return t.store.replay(key, recorded, req)
+	}
+
+	started := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	interaction := Interaction{
+		RecordedAt: started.UTC(),
+		AgentID:    key.agentID,
+		Request:    recorded,
+	}
+	if key.assignmentID != 0 {
+		interaction.AssignmentID = &key.assignmentID
+	}
+
+	if err != nil {
+		interaction.Error = err.Error()
+		interaction.LatencyMs = time.Since(started).Milliseconds()
+		t.store.append(key, interaction)
+		return nil, err
+	}
+
+	// The response is written once the caller has read it, so streams are not held back
+	resp.Body = &recordingBody{ReadCloser: resp.Body, onClose: func(body []byte) {
+		interaction.Response = &RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: sanitizeHeaders(resp.Header),
+			Body:    string(body),
+		}
+		interaction.LatencyMs = time.Since(started).Milliseconds()
+		t.store.append(key, interaction)
+	}}
+	return resp, nil
+}
+
+// recordingBody keeps a copy of everything read from a response body
+type recordingBody struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	once    sync.Once
+	onClose func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.onClose(b.buf.Bytes()) })
+	return err
+}
+
+func (s *cassetteStore) path(key cassetteKey) string {
+	name := "unassigned.jsonl"
+	if key.assignmentID != 0 {
+		name = fmt.Sprintf("assignment-%d.jsonl", key.assignmentID)
+	}
+	return filepath.Join(s.dir, fmt.Sprintf("agent-%d", key.agentID), name)
+}
+
+// append writes an interaction to the key's cassette. Failures are only logged,
+// recording must never break a provider call.
+func (s *cassetteStore) append(key cassetteKey, interaction Interaction) {
+	if err := s.write(key, interaction); err != nil {
+		log.Printf("Failed to record provider call of agent %d: %v", key.agentID, err)
+	}
+}
+
+func (s *cassetteStore) write(key cassetteKey, interaction Interaction) error {
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// replay serves the first unused recording with the same endpoint and body, so a changed
+// prompt fails the replay instead of passing on a stale answer. In loose mode a request
+// without such a recording gets the next unused recording for the endpoint, and a used up
+// cassette is served again from the start; both are logged.
+func (s *cassetteStore) replay(key cassetteKey, recorded RecordedRequest, req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.load(key)
+	if err != nil {
+		return nil, err
+	}
+
+	loose := s.mode == CassetteReplayLoose
+	match := t.next(recorded, loose)
+	if match < 0 && loose && t.rewind(recorded) {
+		log.Printf("Replaying %s for agent %d from the start of %s", recorded.URL, key.agentID, s.path(key))
+		match = t.next(recorded, loose)
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w for %s in %s", ErrNoRecording, recorded.URL, s.path(key))
+	}
+	if t.interactions[match].Request.Body != recorded.Body {
+		log.Printf("Replaying %s for agent %d with a recording of a different request body", recorded.URL, key.agentID)
+	}
+
+	t.used[match] = true
+	interaction := t.interactions[match]
+	if interaction.Response == nil {
+		return nil, errors.New(interaction.Error)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+		StatusCode:    interaction.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Response.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *tape) sameEndpoint(i int, recorded RecordedRequest) bool {
+	return t.interactions[i].Request.Method == recorded.Method && t.interactions[i].Request.URL == recorded.URL
+}
+
+// next returns the first unused recording of the request, or -1. With loose set the first
+// unused recording for the endpoint is returned when none has the same body.
+func (t *tape) next(recorded RecordedRequest, loose bool) int {
+	fallback := -1
+	for i := range t.interactions {
+		if t.used[i] || !t.sameEndpoint(i, recorded) {
+			continue
+		}
+		if t.interactions[i].Request.Body == recorded.Body {
+			return i
+		}
+		if loose && fallback < 0 {
+			fallback = i
+		}
+	}
+	return fallback
+}
+
+// rewind marks the endpoint's recordings as unused again and reports whether there were any
+func (t *tape) rewind(recorded RecordedRequest) bool {
+	found := false
+	for i := range t.used {
+		if t.sameEndpoint(i, recorded) {
+			t.used[i] = false
+			found = true
+		}
+	}
+	return found
+}
+
+// load reads a key's cassette once and keeps it for the following calls
+func (s *cassetteStore) load(key cassetteKey) (*tape, error) {
+	if t, ok := s.tapes[key]; ok {
+		return t, nil
+	}
+
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s does not exist", ErrNoRecording, s.path(key))
+		}
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer file.Close()
+
+	t := &tape{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", s.path(key), err)
+		}
+		t.interactions = append(t.interactions, interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	t.used = make([]bool, len(t.interactions))
+
+	s.tapes[key] = t
+	return t, nil
+}
+
+// sanitizeRequest captures a request without its credentials and restores its body for sending
+func sanitizeRequest(req *http.Request) (RecordedRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return RecordedRequest{}, fmt.Errorf("failed to read request: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	u := *req.URL
+	query := u.Query()
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.User = nil
+
+	return RecordedRequest{
+		Method:  req.Method,
+		URL:     unescapeRedacted(u.String()),
+		Headers: sanitizeHeaders(req.Header),
+		Body:    string(body),
+	}, nil
+}
+
+func sanitizeHeaders(headers http.Header) http.Header {
+	clean := headers.Clone()
+	for _, name := range secretHeaders {
+		if clean.Get(name) != "" {
+			clean.Set(name, redacted)
+		}
+	}
+	return clean
+}
+
+// unescapeRedacted keeps the redaction marker readable in recorded URLs
+func unescapeRedacted(rawURL string) string {
+	return strings.ReplaceAll(rawURL, url.QueryEscape(redacted), redacted)
+}
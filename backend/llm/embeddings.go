@@ -0,0 +1,277 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrEmbeddingsNotSupported is returned for providers without an embeddings API
+var ErrEmbeddingsNotSupported = errors.New("provider does not support embeddings")
+
+// Embedder is implemented by adapters whose provider offers an embeddings API
+type Embedder interface {
+	// Embed returns one vector per input, in input order
+	Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+// EmbeddingRequest is a provider-agnostic embeddings request.
+// Dimensions asks models that support it for shorter vectors; zero keeps the model's size.
+type EmbeddingRequest struct {
+	Model      string
+	Input      []string
+	Dimensions int
+}
+
+// EmbeddingResponse holds the vectors of an embeddings request, in input order
+type EmbeddingResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float64 `json:"embeddings"`
+	Usage      Usage       `json:"usage"`
+}
+
+// Embed sends an embeddings request through p, failing with ErrEmbeddingsNotSupported
+// when its adapter has no embeddings API
+func Embed(ctx context.Context, p Provider, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	embedder, ok := p.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p.Name(), ErrEmbeddingsNotSupported)
+	}
+	return embedder.Embed(ctx, req)
+}
+
+// Embed retries like Chat. Providers whose adapter has no embeddings API fail without a call.
+func (p *resilientProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	embedder, ok := p.Provider.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p.Name(), ErrEmbeddingsNotSupported)
+	}
+
+	var resp *EmbeddingResponse
+	err := p.do(ctx, func() error {
+		var err error
+		resp, err = embedder.Embed(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// embedBatches splits a request into batches of at most size inputs, as providers cap the
+// inputs per call, and joins the results. Usage is summed over the batches.
+func embedBatches(req *EmbeddingRequest, size int, embed func(batch *EmbeddingRequest) (*EmbeddingResponse, error)) (*EmbeddingResponse, error) {
+	resp := &EmbeddingResponse{Model: req.Model, Embeddings: make([][]float64, 0, len(req.Input))}
+
+	for start := 0; start < len(req.Input); start += size {
+		end := min(start+size, len(req.Input))
+		batch, err := embed(&EmbeddingRequest{Model: req.Model, Input: req.Input[start:end], Dimensions: req.Dimensions})
+		if err != nil {
+			return nil, err
+		}
+		if len(batch.Embeddings) != end-start {
+			return nil, fmt.Errorf("provider returned %d embeddings for %d inputs", len(batch.Embeddings), end-start)
+		}
+
+		resp.Embeddings = append(resp.Embeddings, batch.Embeddings...)
+		resp.Usage.InputTokens += batch.Usage.InputTokens
+		resp.Usage.TotalTokens += batch.Usage.TotalTokens
+		if batch.Model != "" {
+			resp.Model = batch.Model
+		}
+	}
+
+	return resp, nil
+}
+
+// OpenAI-compatible embeddings API
+
+// openAIEmbeddingBatch is the number of inputs the embeddings API accepts per call
+const openAIEmbeddingBatch = 2048
+
+type openAIEmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+type openAIEmbeddingResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Usage openAIUsage `json:"usage"`
+}
+
+func (p *openAIProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if p.cfg.EmbeddingPath == "" {
+		return nil, fmt.Errorf("%s: %w", p.Name(), ErrEmbeddingsNotSupported)
+	}
+
+	return embedBatches(req, openAIEmbeddingBatch, func(batch *EmbeddingRequest) (*EmbeddingResponse, error) {
+		body := openAIEmbeddingRequest{Model: batch.Model, Input: batch.Input, Dimensions: batch.Dimensions, EncodingFormat: "float"}
+
+		var result openAIEmbeddingResponse
+		if err := postJSON(ctx, p.client, p.Name(), p.cfg.BaseURL+p.cfg.EmbeddingPath, p.headers(), body, &result); err != nil {
+			return nil, err
+		}
+
+		// Entries carry their input's index and are not guaranteed to be in order
+		sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+
+		resp := &EmbeddingResponse{Model: result.Model, Usage: result.Usage.toUsage()}
+		for _, d := range result.Data {
+			resp.Embeddings = append(resp.Embeddings, d.Embedding)
+		}
+		return resp, nil
+	})
+}
+
+// Gemini embeddings API
+
+// geminiEmbeddingBatch is the number of requests batchEmbedContents accepts per call
+const geminiEmbeddingBatch = 100
+
+type geminiEmbeddingRequest struct {
+	Requests []geminiEmbedContentRequest `json:"requests"`
+}
+
+type geminiEmbedContentRequest struct {
+	Model                string        `json:"model"`
+	Content              geminiContent `json:"content"`
+	OutputDimensionality int           `json:"outputDimensionality,omitempty"`
+}
+
+type geminiEmbeddingResponse struct {
+	Embeddings []struct {
+		Values []float64 `json:"values"`
+	} `json:"embeddings"`
+}
+
+// Embed uses batchEmbedContents. Gemini does not report token usage for
+// embeddings, so it is estimated from the input length.
+func (p *geminiProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if p.cfg.EmbeddingPath == "" {
+		return nil, fmt.Errorf("%s: %w", p.Name(), ErrEmbeddingsNotSupported)
+	}
+
+	return embedBatches(req, geminiEmbeddingBatch, func(batch *EmbeddingRequest) (*EmbeddingResponse, error) {
+		body := geminiEmbeddingRequest{}
+		for _, input := range batch.Input {
+			body.Requests = append(body.Requests, geminiEmbedContentRequest{
+				Model:                "models/" + url.PathEscape(batch.Model),
+				Content:              geminiContent{Parts: []geminiPart{{Text: input}}},
+				OutputDimensionality: batch.Dimensions,
+			})
+		}
+
+		var result geminiEmbeddingResponse
+		if err := postJSON(ctx, p.client, p.Name(), p.endpoint(p.cfg.EmbeddingPath, batch.Model), nil, body, &result); err != nil {
+			return nil, err
+		}
+
+		resp := &EmbeddingResponse{Model: batch.Model}
+		for i, e := range result.Embeddings {
+			resp.Embeddings = append(resp.Embeddings, e.Values)
+			if i < len(batch.Input) {
+				resp.Usage.InputTokens += estimateTokens(batch.Input[i])
+			}
+		}
+		resp.Usage.TotalTokens = resp.Usage.InputTokens
+		return resp, nil
+	})
+}
+
+// Mock embeddings
+
+// MockEmbeddingDimensions is the vector size of the mock provider when neither the
+// request nor the model catalog sets one
+const MockEmbeddingDimensions = 256
+
+// Embed returns deterministic vectors built by feature hashing: every word of the input
+// adds a signed weight to a bucket chosen by its hash, and the vector is normalized to unit
+// length. The same text always gets the same vector and texts sharing words end up close
+// to each other, so semantic features can be tested offline. Fixture rules with an error
+// fail embedding calls whose input they match.
+func (p *mockProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	mockFixturesMu.RLock()
+	fixtures := mockFixtures
+	mockFixturesMu.RUnlock()
+
+	for _, rule := range fixtures.Rules {
+		if rule.Error == nil || rule.ToolResult || (rule.Model != "" && rule.Model != req.Model) {
+			continue
+		}
+		for _, input := range req.Input {
+			if rule.re.MatchString(input) && rule.take() {
+				if err := p.wait(ctx, rule); err != nil {
+					return nil, err
+				}
+				return nil, p.fail(rule)
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	dimensions := req.Dimensions
+	if dimensions <= 0 {
+		dimensions = MockEmbeddingDimensions
+		for _, m := range p.cfg.Models {
+			if m.ID == req.Model && m.Dimensions > 0 {
+				dimensions = m.Dimensions
+			}
+		}
+	}
+
+	resp := &EmbeddingResponse{Model: req.Model, Embeddings: make([][]float64, 0, len(req.Input))}
+	for _, input := range req.Input {
+		resp.Embeddings = append(resp.Embeddings, hashEmbedding(input, dimensions))
+		resp.Usage.InputTokens += estimateTokens(input)
+	}
+	resp.Usage.TotalTokens = resp.Usage.InputTokens
+	return resp, nil
+}
+
+// hashEmbedding maps the words of text into a unit vector of the given size
+func hashEmbedding(text string, dimensions int) []float64 {
+	vector := make([]float64, dimensions)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+
+		sign := 1.0
+		if sum&(1<<63) != 0 {
+			sign = -1
+		}
+		vector[sum%uint64(dimensions)] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		// Inputs without words still get a stable, non-zero vector
+		vector[0] = 1
+		return vector
+	}
+
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
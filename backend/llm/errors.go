@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error is a normalized provider error
+type Error struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	// Retryable is set for rate limits, timeouts and server-side failures
+	Retryable bool
+	// RetryAfter is the delay requested by the provider, if any
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s: %s", e.Provider, e.Message)
+	}
+	return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// AsError extracts a provider error from err
+func AsError(err error) (*Error, bool) {
+	var providerErr *Error
+	if errors.As(err, &providerErr) {
+		return providerErr, true
+	}
+	return nil, false
+}
+
+// isRetryableStatus reports whether a status code is worth retrying
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	case 529: // Anthropic overloaded
+		return true
+	}
+	return status >= 500
+}
+
+// errorFromResponse builds an Error from a non-2xx response.
+// OpenAI, Anthropic and Gemini all report the message under error.message.
+func errorFromResponse(provider string, resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	message := http.StatusText(resp.StatusCode)
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error.Message != "" {
+		message = payload.Error.Message
+	}
+
+	return &Error{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		Retryable:  isRetryableStatus(resp.StatusCode),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// transportError wraps a failure to reach the provider
+func transportError(provider string, err error) *Error {
+	return &Error{
+		Provider:  provider,
+		Message:   err.Error(),
+		Retryable: true,
+	}
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date values
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,278 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+// geminiProvider speaks the Gemini generateContent API
+type geminiProvider struct {
+	cfg    *models.ProviderConfig
+	apiKey string
+	client *http.Client
+	// streamClient has no overall deadline, streams are bounded by openStream's idle timeout
+	streamClient *http.Client
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiInlineData       `json:"inline_data,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// geminiInlineData is an image sent inline with a message
+type geminiInlineData struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent        `json:"contents"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool           `json:"tools,omitempty"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata geminiUsage `json:"usageMetadata"`
+	ModelVersion  string      `json:"modelVersion"`
+}
+
+func (p *geminiProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *geminiProvider) breakerKey() string {
+	return breakerKey(p.cfg, p.apiKey)
+}
+
+func (p *geminiProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var result geminiResponse
+	if err := postJSON(ctx, p.client, p.Name(), p.endpoint(p.cfg.ChatCompletionPath, req.Model), nil, p.buildRequest(req), &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Candidates) == 0 {
+		return nil, &Error{Provider: p.Name(), Message: "response contained no candidates"}
+	}
+
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, part := range result.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+		if part.FunctionCall != nil {
+			toolCalls = append(toolCalls, geminiToolCall(part.FunctionCall, len(toolCalls)))
+		}
+	}
+
+	resp := &ChatResponse{
+		Model:        result.ModelVersion,
+		Content:      text.String(),
+		ToolCalls:    toolCalls,
+		FinishReason: result.Candidates[0].FinishReason,
+		Usage:        geminiToUsage(result.UsageMetadata),
+	}
+	if resp.Model == "" {
+		resp.Model = req.Model
+	}
+
+	return resp, nil
+}
+
+func (p *geminiProvider) ChatStream(ctx context.Context, req *ChatRequest, onChunk func(StreamChunk) error) (*ChatResponse, error) {
+	path := strings.Replace(p.cfg.ChatCompletionPath, ":generateContent", ":streamGenerateContent", 1)
+	endpoint := p.endpoint(path, req.Model) + "&alt=sse"
+
+	resp := &ChatResponse{Model: req.Model}
+	var content strings.Builder
+
+	err := openStream(ctx, p.streamClient, p.Name(), endpoint, nil, p.buildRequest(req), func(_, data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return &Error{Provider: p.Name(), Message: fmt.Sprintf("failed to decode stream chunk: %v", err)}
+		}
+
+		if chunk.ModelVersion != "" {
+			resp.Model = chunk.ModelVersion
+		}
+		// usageMetadata is cumulative, the last chunk holds the totals
+		if chunk.UsageMetadata.TotalTokenCount > 0 || chunk.UsageMetadata.PromptTokenCount > 0 {
+			resp.Usage = geminiToUsage(chunk.UsageMetadata)
+		}
+
+		for _, candidate := range chunk.Candidates {
+			if candidate.FinishReason != "" {
+				resp.FinishReason = candidate.FinishReason
+			}
+			for _, part := range candidate.Content.Parts {
+				// Function calls arrive whole, never split across chunks
+				if part.FunctionCall != nil {
+					resp.ToolCalls = append(resp.ToolCalls, geminiToolCall(part.FunctionCall, len(resp.ToolCalls)))
+				}
+				if part.Text == "" {
+					continue
+				}
+				content.WriteString(part.Text)
+				if err := onChunk(StreamChunk{Delta: part.Text}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	resp.Content = content.String()
+	return resp, err
+}
+
+func (p *geminiProvider) buildRequest(req *ChatRequest) geminiRequest {
+	system, conversation := splitSystem(req.Messages)
+
+	names := toolNames(conversation)
+	contents := make([]geminiContent, 0, len(conversation))
+	for _, m := range conversation {
+		role := RoleUser
+		var parts []geminiPart
+
+		switch m.Role {
+		case RoleTool:
+			name := m.Name
+			if name == "" {
+				name = names[m.ToolCallID]
+			}
+			parts = append(parts, geminiPart{FunctionResponse: &geminiFunctionResponse{Name: name, Response: geminiToolResult(m.Content)}})
+		case RoleAssistant:
+			role = "model"
+			if m.Content != "" || len(m.ToolCalls) == 0 {
+				parts = append(parts, geminiPart{Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: call.Name, Args: toolInput(call.Arguments)}})
+			}
+		default:
+			parts = append(parts, geminiPart{Text: m.Content})
+			for _, image := range m.Images {
+				parts = append(parts, geminiPart{InlineData: &geminiInlineData{MimeType: image.MediaType, Data: image.base64()}})
+			}
+		}
+
+		// Consecutive messages of one role, such as several tool results, form a single turn
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+			continue
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: parts})
+	}
+
+	body := geminiRequest{
+		Contents: contents,
+		GenerationConfig: geminiGenerationConfig{
+			MaxOutputTokens:  req.MaxTokens,
+			Temperature:      optional(req.Temperature),
+			TopP:             optional(req.TopP),
+			FrequencyPenalty: optional(req.FrequencyPenalty),
+			PresencePenalty:  optional(req.PresencePenalty),
+		},
+	}
+	if system != "" {
+		body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+	if len(req.Tools) > 0 {
+		declarations := make([]geminiFunctionDeclaration, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			declarations = append(declarations, geminiFunctionDeclaration{Name: tool.Name, Description: tool.Description, Parameters: toolSchema(tool)})
+		}
+		body.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+	}
+
+	return body
+}
+
+// endpoint fills the {model} placeholder and appends the API key as the key query parameter
+func (p *geminiProvider) endpoint(path, model string) string {
+	path = strings.ReplaceAll(path, "{model}", url.PathEscape(model))
+	return p.cfg.BaseURL + path + "?key=" + url.QueryEscape(p.apiKey)
+}
+
+// geminiToolCall normalizes a function call. Gemini only sometimes assigns call IDs,
+// so missing ones are numbered by position.
+func geminiToolCall(call *geminiFunctionCall, position int) ToolCall {
+	id := call.ID
+	if id == "" {
+		id = fmt.Sprintf("call_%d", position+1)
+	}
+	return ToolCall{ID: id, Name: call.Name, Arguments: toolInput(call.Args)}
+}
+
+// geminiToolResult wraps a tool result in the JSON object functionResponse requires
+func geminiToolResult(content string) json.RawMessage {
+	if trimmed := strings.TrimSpace(content); strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	wrapped, _ := json.Marshal(map[string]string{"content": content})
+	return wrapped
+}
+
+func geminiToUsage(u geminiUsage) Usage {
+	usage := Usage{
+		InputTokens:  u.PromptTokenCount,
+		OutputTokens: u.CandidatesTokenCount,
+		TotalTokens:  u.TotalTokenCount,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	return usage
+}
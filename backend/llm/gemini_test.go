@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestGeminiChat(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *ChatRequest
+		resp          string
+		wantRequest   string
+		wantContent   string
+		wantToolCalls []ToolCall
+		wantUsage     Usage
+	}{
+		{
+			name: "system instruction and generation config",
+			req: &ChatRequest{
+				Model:     "gemini-test",
+				Messages:  []Message{{Role: RoleSystem, Content: "be brief"}, {Role: RoleUser, Content: "hi"}},
+				MaxTokens: 64,
+				TopP:      0.9,
+			},
+			resp: `{"candidates":[{"content":{"role":"model","parts":[{"text":"hel"},{"text":"lo"}]},"finishReason":"STOP"}],
+				"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":2,"totalTokenCount":9},"modelVersion":"gemini-test-001"}`,
+			wantRequest: `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],
+				"systemInstruction":{"parts":[{"text":"be brief"}]},
+				"generationConfig":{"maxOutputTokens":64,"topP":0.9}}`,
+			wantContent: "hello",
+			wantUsage:   Usage{InputTokens: 7, OutputTokens: 2, TotalTokens: 9},
+		},
+		{
+			name: "function call round trip",
+			req: &ChatRequest{
+				Model: "gemini-test",
+				Messages: []Message{
+					{Role: RoleUser, Content: "weather?"},
+					{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Name: "weather", Arguments: json.RawMessage(`{"city":"Izmir"}`)}}},
+					{Role: RoleTool, ToolCallID: "call_1", Content: "sunny"},
+				},
+				Tools: []Tool{{Name: "weather", Description: "Current weather"}},
+			},
+			// Gemini leaves call IDs out, they are numbered by position
+			resp: `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"weather","args":{"city":"Ankara"}}}]},"finishReason":"STOP"}],
+				"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":4}}`,
+			wantRequest: `{"contents":[
+					{"role":"user","parts":[{"text":"weather?"}]},
+					{"role":"model","parts":[{"functionCall":{"name":"weather","args":{"city":"Izmir"}}}]},
+					{"role":"user","parts":[{"functionResponse":{"name":"weather","response":{"content":"sunny"}}}]}],
+				"tools":[{"functionDeclarations":[{"name":"weather","description":"Current weather","parameters":{"type":"object","properties":{}}}]}],
+				"generationConfig":{}}`,
+			wantToolCalls: []ToolCall{{ID: "call_1", Name: "weather", Arguments: json.RawMessage(`{"city":"Ankara"}`)}},
+			wantUsage:     Usage{InputTokens: 20, OutputTokens: 4, TotalTokens: 24},
+		},
+		{
+			name: "image",
+			req: &ChatRequest{
+				Model:    "gemini-test",
+				Messages: []Message{{Role: RoleUser, Content: "what is this?", Images: []Image{{MediaType: "image/png", Data: []byte("png")}}}},
+			},
+			resp: `{"candidates":[{"content":{"parts":[{"text":"a logo"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}`,
+			wantRequest: `{"contents":[{"role":"user","parts":[{"text":"what is this?"},{"inline_data":{"mime_type":"image/png","data":"cG5n"}}]}],
+				"generationConfig":{}}`,
+			wantContent: "a logo",
+			wantUsage:   Usage{InputTokens: 3, OutputTokens: 2, TotalTokens: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, captured := fakeProvider(t, FormatGemini, fakeResponse{body: tt.resp})
+
+			resp, err := newTestProvider(t, cfg).Chat(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Chat: %v", err)
+			}
+
+			if captured.path != "/models/gemini-test:generateContent" {
+				t.Errorf("path = %q, want the model's generateContent endpoint", captured.path)
+			}
+			if captured.query != "key=test-key" {
+				t.Errorf("query = %q, want key=test-key", captured.query)
+			}
+			if got := captured.header.Get("Authorization"); got != "" {
+				t.Errorf("Authorization = %q, want none", got)
+			}
+			if !jsonEqual(t, captured.body, tt.wantRequest) {
+				t.Errorf("request body = %v, want %s", captured.body, tt.wantRequest)
+			}
+
+			if resp.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", resp.Content, tt.wantContent)
+			}
+			if !jsonEqual(t, resp.ToolCalls, mustJSON(t, tt.wantToolCalls)) {
+				t.Errorf("tool calls = %+v, want %+v", resp.ToolCalls, tt.wantToolCalls)
+			}
+			if resp.Usage != tt.wantUsage {
+				t.Errorf("usage = %+v, want %+v", resp.Usage, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestGeminiChatStream(t *testing.T) {
+	stream := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}],"usageMetadata":{"promptTokenCount":8},"modelVersion":"gemini-test-001"}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]}}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":2,"totalTokenCount":10}}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"id":"fc_1","name":"weather","args":{"city":"Izmir"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":6,"totalTokenCount":14}}
+
+`
+	cfg, captured := fakeProvider(t, FormatGemini, fakeResponse{headers: map[string]string{"Content-Type": "text/event-stream"}, body: stream})
+
+	var deltas []string
+	resp, err := newTestProvider(t, cfg).ChatStream(context.Background(), &ChatRequest{Model: "gemini-test", Messages: []Message{{Role: RoleUser, Content: "hi"}}}, func(chunk StreamChunk) error {
+		deltas = append(deltas, chunk.Delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	if captured.path != "/models/gemini-test:streamGenerateContent" || captured.query != "key=test-key&alt=sse" {
+		t.Errorf("path = %q, query = %q, want the model's SSE streaming endpoint", captured.path, captured.query)
+	}
+	if len(deltas) != 2 || resp.Content != "Hello" {
+		t.Errorf("deltas = %q, content = %q, want two deltas forming Hello", deltas, resp.Content)
+	}
+	if resp.Model != "gemini-test-001" || resp.FinishReason != "STOP" {
+		t.Errorf("model = %q, finish = %q", resp.Model, resp.FinishReason)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "fc_1" || string(resp.ToolCalls[0].Arguments) != `{"city":"Izmir"}` {
+		t.Errorf("tool calls = %+v, want the weather call", resp.ToolCalls)
+	}
+	// usageMetadata is cumulative, the last chunk holds the totals
+	if want := (Usage{InputTokens: 8, OutputTokens: 6, TotalTokens: 14}); resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+}
+
+func TestGeminiEmbed(t *testing.T) {
+	cfg, captured := fakeProvider(t, FormatGemini, fakeResponse{body: `{"embeddings":[{"values":[0.1,0.2]},{"values":[0.3,0.4]}]}`})
+
+	resp, err := Embed(context.Background(), newTestProvider(t, cfg), &EmbeddingRequest{Model: "embed-test", Input: []string{"one two", "three"}, Dimensions: 2})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	if captured.path != "/models/embed-test:batchEmbedContents" || captured.query != "key=test-key" {
+		t.Errorf("path = %q, query = %q", captured.path, captured.query)
+	}
+	want := `{"requests":[
+		{"model":"models/embed-test","content":{"parts":[{"text":"one two"}]},"outputDimensionality":2},
+		{"model":"models/embed-test","content":{"parts":[{"text":"three"}]},"outputDimensionality":2}]}`
+	if !jsonEqual(t, captured.body, want) {
+		t.Errorf("request body = %v, want %s", captured.body, want)
+	}
+	if !jsonEqual(t, resp.Embeddings, `[[0.1,0.2],[0.3,0.4]]`) {
+		t.Errorf("embeddings = %v", resp.Embeddings)
+	}
+	// Gemini reports no usage for embeddings, so it is estimated
+	if resp.Usage.InputTokens == 0 || resp.Usage.TotalTokens != resp.Usage.InputTokens {
+		t.Errorf("usage = %+v, want an input token estimate", resp.Usage)
+	}
+}
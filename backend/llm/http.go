@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// postJSON sends body as JSON and decodes a successful response into out
+func postJSON(ctx context.Context, client *http.Client, provider, endpoint string, headers map[string]string, body, out any) error {
+	resp, err := send(ctx, client, provider, endpoint, headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &Error{Provider: provider, StatusCode: resp.StatusCode, Message: fmt.Sprintf("failed to decode response: %v", err)}
+	}
+
+	return nil
+}
+
+// send posts body as JSON and returns the response if it has a 2xx status.
+// The caller must close the response body.
+func send(ctx context.Context, client *http.Client, provider, endpoint string, headers map[string]string, body any) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Cancellation by the caller is not a provider failure
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Drop the URL from the message, it can carry the API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		// A missing recording will not show up on a retry
+		if errors.Is(err, ErrNoRecording) {
+			return nil, &Error{Provider: provider, Message: err.Error()}
+		}
+		return nil, transportError(provider, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, errorFromResponse(provider, resp)
+	}
+
+	return resp, nil
+}
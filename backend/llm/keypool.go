@@ -0,0 +1,257 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+// DefaultKeyCooldown is how long a key rests after a rate limit that came without Retry-After
+const DefaultKeyCooldown = time.Minute
+
+// keyPoolIdleTTL is how long an unused pool, and the plaintext keys it holds, stays in memory
+const keyPoolIdleTTL = 10 * time.Minute
+
+// PoolKey is one API key of a key pool. ID is the caller's identifier for it and Masked is
+// the form that is safe to show.
+type PoolKey struct {
+	ID     int
+	Key    string
+	Masked string
+	// CooldownUntil is a cooldown recorded elsewhere, such as by another server
+	CooldownUntil *time.Time
+}
+
+// KeyPool spreads calls round-robin over a set of API keys for one provider and
+// rests keys that hit a rate limit
+type KeyPool struct {
+	name      string
+	mu        sync.Mutex
+	keys      []PoolKey
+	next      int
+	cooldowns map[string]time.Time
+	// lastUsed is guarded by keyPools
+	lastUsed time.Time
+}
+
+var keyPools = struct {
+	sync.Mutex
+	byName map[string]*KeyPool
+	swept  time.Time
+}{byName: make(map[string]*KeyPool)}
+
+// KeyPoolFor returns the shared pool of name holding keys. The key set is replaced on
+// every call, so removed keys drop out; cooldowns of keys that stay are kept. Pools unused
+// for a while are swept out, so those of deleted agents do not keep their keys in memory.
+func KeyPoolFor(name string, keys []PoolKey) *KeyPool {
+	now := time.Now()
+
+	keyPools.Lock()
+	if now.Sub(keyPools.swept) >= keyPoolIdleTTL {
+		for n, p := range keyPools.byName {
+			if now.Sub(p.lastUsed) >= keyPoolIdleTTL {
+				delete(keyPools.byName, n)
+			}
+		}
+		keyPools.swept = now
+	}
+	pool, ok := keyPools.byName[name]
+	if !ok {
+		pool = &KeyPool{name: name, cooldowns: make(map[string]time.Time)}
+		keyPools.byName[name] = pool
+	}
+	pool.lastUsed = now
+	keyPools.Unlock()
+
+	pool.setKeys(keys)
+	return pool
+}
+
+// DropKeyPool forgets the pool of name along with its keys and in-memory cooldowns
+func DropKeyPool(name string) {
+	keyPools.Lock()
+	defer keyPools.Unlock()
+
+	delete(keyPools.byName, name)
+}
+
+func (p *KeyPool) setKeys(keys []PoolKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cooldowns := make(map[string]time.Time, len(keys))
+	for _, key := range keys {
+		until := p.cooldowns[key.Key]
+		if key.CooldownUntil != nil && key.CooldownUntil.After(until) {
+			until = *key.CooldownUntil
+		}
+		if time.Now().Before(until) {
+			cooldowns[key.Key] = until
+		}
+	}
+
+	p.keys = keys
+	p.cooldowns = cooldowns
+}
+
+// acquire returns the next key that is not cooling down and was not tried yet. When
+// every untried key is cooling down it returns how long until the first one is free.
+func (p *KeyPool) acquire(tried map[string]bool) (PoolKey, time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for i := range p.keys {
+		key := p.keys[(p.next+i)%len(p.keys)]
+		if tried[key.Key] {
+			continue
+		}
+		if until, ok := p.cooldowns[key.Key]; ok && now.Before(until) {
+			if d := until.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		p.next = (p.next + i + 1) % len(p.keys)
+		return key, 0, true
+	}
+	return PoolKey{}, wait, false
+}
+
+// coolDown rests a key after a rate limit and returns when it is usable again
+func (p *KeyPool) coolDown(key PoolKey, retryAfter time.Duration) time.Time {
+	if retryAfter <= 0 {
+		retryAfter = DefaultKeyCooldown
+	}
+	until := time.Now().Add(retryAfter)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until.After(p.cooldowns[key.Key]) {
+		p.cooldowns[key.Key] = until
+	}
+	return p.cooldowns[key.Key]
+}
+
+// KeyObserver is told which key of a pool each call went out with and how it ended.
+// cooldownUntil is set when the call hit a rate limit and the key was rested.
+type KeyObserver func(key PoolKey, err error, cooldownUntil *time.Time)
+
+// pooledProvider sends every call with the next key of its pool. A rate-limited call is
+// repeated right away with another key; once every key is cooling down the rate limit
+// is returned, so retries and fallbacks apply as for a single key.
+type pooledProvider struct {
+	cfg    *models.ProviderConfig
+	client *http.Client
+	// streamClient has no overall deadline, streams are bounded by openStream's idle timeout
+	streamClient *http.Client
+	pool         *KeyPool
+	observe      KeyObserver
+}
+
+// NewPooled returns an adapter for the provider's wire format that rotates over the keys of pool.
+// observe may be nil.
+func NewPooled(cfg *models.ProviderConfig, pool *KeyPool, httpClient *http.Client, observe KeyObserver) (Provider, error) {
+	httpClient, streamClient := clients(httpClient)
+	// Reject unsupported formats up front rather than on the first call
+	if _, err := newAdapter(cfg, "", httpClient, streamClient); err != nil {
+		return nil, err
+	}
+
+	return &pooledProvider{cfg: cfg, client: httpClient, streamClient: streamClient, pool: pool, observe: observe}, nil
+}
+
+func (p *pooledProvider) Name() string {
+	return p.cfg.Name
+}
+
+// breakerKey scopes the circuit to the pool, since each call may use any of its keys
+func (p *pooledProvider) breakerKey() string {
+	return breakerKey(p.cfg, "pool:"+p.pool.name)
+}
+
+func (p *pooledProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var resp *ChatResponse
+	err := p.rotate(ctx, nil, func(provider Provider) error {
+		var err error
+		resp, err = provider.Chat(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// ChatStream only moves on to another key while no chunk was relayed
+func (p *pooledProvider) ChatStream(ctx context.Context, req *ChatRequest, onChunk func(StreamChunk) error) (*ChatResponse, error) {
+	delivered := false
+	var resp *ChatResponse
+	err := p.rotate(ctx, func() bool { return !delivered }, func(provider Provider) error {
+		var err error
+		resp, err = provider.ChatStream(ctx, req, func(chunk StreamChunk) error {
+			delivered = true
+			return onChunk(chunk)
+		})
+		return err
+	})
+	return resp, err
+}
+
+func (p *pooledProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	var resp *EmbeddingResponse
+	err := p.rotate(ctx, nil, func(provider Provider) error {
+		var err error
+		resp, err = Embed(ctx, provider, req)
+		return err
+	})
+	return resp, err
+}
+
+// rotate makes call with a free key, moving on to the next one after a rate limit for as
+// long as untried keys are free and canRotate, if set, allows it
+func (p *pooledProvider) rotate(ctx context.Context, canRotate func() bool, call func(Provider) error) error {
+	tried := make(map[string]bool)
+	var lastErr error
+	for {
+		key, wait, ok := p.pool.acquire(tried)
+		if !ok {
+			if lastErr != nil {
+				return lastErr
+			}
+			return &Error{
+				Provider:   p.Name(),
+				StatusCode: http.StatusTooManyRequests,
+				Message:    "every API key is cooling down after a rate limit",
+				Retryable:  true,
+				RetryAfter: wait,
+			}
+		}
+		tried[key.Key] = true
+
+		provider, err := newAdapter(p.cfg, key.Key, p.client, p.streamClient)
+		if err != nil {
+			return err
+		}
+		err = call(provider)
+
+		providerErr, ok := AsError(err)
+		if !ok || providerErr.StatusCode != http.StatusTooManyRequests {
+			if p.observe != nil {
+				p.observe(key, err, nil)
+			}
+			return err
+		}
+
+		until := p.pool.coolDown(key, providerErr.RetryAfter)
+		if p.observe != nil {
+			p.observe(key, err, &until)
+		}
+		lastErr = err
+		if ctx.Err() != nil || (canRotate != nil && !canRotate()) {
+			return err
+		}
+	}
+}
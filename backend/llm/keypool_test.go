@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+func TestPooledProviderRotatesOnRateLimit(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") == "Bearer key-a" {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		// The stream outlives the plain client's Timeout, which streams must not inherit
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, `data: {"choices":[{"delta":{"content":"Hel"}}]}`+"\n\n")
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		io.WriteString(w, `data: {"choices":[{"delta":{"content":"lo"}}]}`+"\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	cfg := &models.ProviderConfig{Name: "openai", BaseURL: server.URL, ChatCompletionPath: "/chat/completions", APIFormat: FormatOpenAI}
+	pool := KeyPoolFor("test:rotate", []PoolKey{{ID: 1, Key: "key-a"}, {ID: 2, Key: "key-b"}})
+	defer DropKeyPool("test:rotate")
+
+	var served []int
+	var cooled *time.Time
+	provider, err := NewPooled(cfg, pool, &http.Client{Timeout: 100 * time.Millisecond}, func(key PoolKey, _ error, cooldownUntil *time.Time) {
+		served = append(served, key.ID)
+		if cooldownUntil != nil {
+			cooled = cooldownUntil
+		}
+	})
+	if err != nil {
+		t.Fatalf("NewPooled: %v", err)
+	}
+
+	resp, err := provider.ChatStream(context.Background(), &ChatRequest{Model: "m"}, func(StreamChunk) error { return nil })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if resp.Content != "Hello" {
+		t.Errorf("content = %q, want Hello", resp.Content)
+	}
+	if len(served) != 2 || served[0] != 1 || served[1] != 2 {
+		t.Errorf("served by %v, want key 1 then key 2", served)
+	}
+	if cooled == nil || time.Until(*cooled) < 20*time.Second {
+		t.Errorf("cooldown = %v, want the rate-limited key rested for Retry-After", cooled)
+	}
+
+	// The rested key is skipped on the next call
+	seen = nil
+	if _, err := provider.ChatStream(context.Background(), &ChatRequest{Model: "m"}, func(StreamChunk) error { return nil }); err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if len(seen) != 1 || seen[0] != "Bearer key-b" {
+		t.Errorf("requests went out with %v, want only key-b", seen)
+	}
+}
+
+func TestKeyPoolForEvictsIdlePools(t *testing.T) {
+	idle := KeyPoolFor("test:idle", []PoolKey{{Key: "k"}})
+
+	past := time.Now().Add(-2 * keyPoolIdleTTL)
+	keyPools.Lock()
+	idle.lastUsed = past
+	keyPools.swept = past
+	keyPools.Unlock()
+
+	KeyPoolFor("test:other", nil)
+	defer DropKeyPool("test:other")
+	if again := KeyPoolFor("test:idle", nil); again == idle {
+		t.Error("idle pool was kept, want it replaced")
+	}
+	DropKeyPool("test:idle")
+
+	keyPools.Lock()
+	_, ok := keyPools.byName["test:idle"]
+	keyPools.Unlock()
+	if ok {
+		t.Error("DropKeyPool left the pool in place")
+	}
+}
@@ -0,0 +1,356 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/goccy/go-yaml"
+)
+
+//go:embed mock_fixtures.yaml
+var defaultMockFixtures []byte
+
+// MockFixtures scripts the replies of the mock provider
+type MockFixtures struct {
+	Rules   []*MockRule `json:"rules"`
+	Default *MockRule   `json:"default"`
+}
+
+// MockRule answers requests whose last user message matches Match
+type MockRule struct {
+	Name         string         `json:"name"`
+	Match        string         `json:"match"`
+	Model        string         `json:"model"`
+	Response     string         `json:"response"`
+	ToolCalls    []MockToolCall `json:"tool_calls"`
+	LatencyMs    int            `json:"latency_ms"`
+	Error        *MockError     `json:"error"`
+	Usage        *MockUsage     `json:"usage"`
+	FinishReason string         `json:"finish_reason"`
+	// Times limits the rule to its first N matches; zero means unlimited
+	Times int `json:"times"`
+	// ToolResult rules answer a conversation ending in a tool result and match its content
+	ToolResult bool `json:"tool_result"`
+
+	re   *regexp.Regexp
+	mu   sync.Mutex
+	hits int
+}
+
+// MockToolCall is a canned tool call. Arguments is a JSON template expanded with the match's captures.
+type MockToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// MockError makes a rule fail like a provider would
+type MockError struct {
+	Status            int    `json:"status"`
+	Message           string `json:"message"`
+	RetryAfterSeconds int    `json:"retry_after_seconds"`
+}
+
+// MockUsage overrides the estimated token counts of a rule
+type MockUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+var (
+	mockFixturesMu sync.RWMutex
+	mockFixtures   *MockFixtures
+)
+
+func init() {
+	fixtures, err := ParseMockFixtures(defaultMockFixtures, ".yaml")
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in mock fixtures: %v", err))
+	}
+	mockFixtures = fixtures
+}
+
+// LoadMockFixtures replaces the mock provider's fixtures with the contents of path.
+// An empty path keeps the built-in fixtures.
+func LoadMockFixtures(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read mock fixtures: %w", err)
+	}
+
+	fixtures, err := ParseMockFixtures(data, filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("invalid mock fixtures %s: %w", path, err)
+	}
+
+	mockFixturesMu.Lock()
+	mockFixtures = fixtures
+	mockFixturesMu.Unlock()
+	return nil
+}
+
+// ParseMockFixtures decodes and validates a fixture file. ext selects JSON
+// (".json") or YAML (anything else). Unknown fields are rejected.
+func ParseMockFixtures(data []byte, ext string) (*MockFixtures, error) {
+	if !strings.EqualFold(ext, ".json") {
+		converted, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse yaml: %w", err)
+		}
+		data = converted
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var fixtures MockFixtures
+	if err := decoder.Decode(&fixtures); err != nil {
+		return nil, fmt.Errorf("failed to decode fixtures: %w", err)
+	}
+
+	if fixtures.Default == nil {
+		fixtures.Default = &MockRule{Name: "default"}
+	}
+	if fixtures.Default.Match != "" || fixtures.Default.Times != 0 {
+		return nil, errors.New("default: match and times are not allowed")
+	}
+
+	for i, rule := range fixtures.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.Match == "" {
+			return nil, fmt.Errorf("%s: match is required", rule.Name)
+		}
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid match: %w", rule.Name, err)
+		}
+		rule.re = re
+	}
+
+	for _, rule := range append(fixtures.Rules, fixtures.Default) {
+		if rule.LatencyMs < 0 || rule.Times < 0 {
+			return nil, fmt.Errorf("%s: latency_ms and times must not be negative", rule.Name)
+		}
+		if rule.Error != nil && (rule.Error.Status < 400 || rule.Error.Status > 599) {
+			return nil, fmt.Errorf("%s: error status must be a 4xx or 5xx code", rule.Name)
+		}
+		for _, call := range rule.ToolCalls {
+			if call.Name == "" {
+				return nil, fmt.Errorf("%s: tool call name is required", rule.Name)
+			}
+		}
+	}
+
+	return &fixtures, nil
+}
+
+// mockProvider answers from the loaded fixtures without any network access
+type mockProvider struct {
+	cfg *models.ProviderConfig
+}
+
+func (p *mockProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *mockProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	rule, match, prompt := p.selectRule(req)
+	if err := p.wait(ctx, rule); err != nil {
+		return nil, err
+	}
+	return p.respond(rule, match, prompt, req)
+}
+
+func (p *mockProvider) ChatStream(ctx context.Context, req *ChatRequest, onChunk func(StreamChunk) error) (*ChatResponse, error) {
+	rule, match, prompt := p.selectRule(req)
+	if err := p.wait(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.respond(rule, match, prompt, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, word := range strings.SplitAfter(resp.Content, " ") {
+		if word == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return resp, err
+		}
+		if err := onChunk(StreamChunk{Delta: word}); err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, nil
+}
+
+// selectRule returns the first rule matching the last user message, with the match's submatch indexes.
+// When the conversation ends in a tool result only tool_result rules apply, matched against the result.
+func (p *mockProvider) selectRule(req *ChatRequest) (*MockRule, []int, string) {
+	mockFixturesMu.RLock()
+	fixtures := mockFixtures
+	mockFixturesMu.RUnlock()
+
+	toolResult := len(req.Messages) > 0 && req.Messages[len(req.Messages)-1].Role == RoleTool
+	prompt := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == RoleUser || toolResult {
+			prompt = req.Messages[i].Content
+			break
+		}
+	}
+
+	for _, rule := range fixtures.Rules {
+		if rule.ToolResult != toolResult || (rule.Model != "" && rule.Model != req.Model) {
+			continue
+		}
+		match := rule.re.FindStringSubmatchIndex(prompt)
+		if match == nil || !rule.take() {
+			continue
+		}
+		return rule, match, prompt
+	}
+
+	if toolResult {
+		return toolResultRule(req.Messages), nil, prompt
+	}
+	return fixtures.Default, nil, prompt
+}
+
+// toolResultRule acknowledges the tool results at the end of a conversation, so that
+// tool loops end after one round unless a fixture says otherwise
+func toolResultRule(messages []Message) *MockRule {
+	names := toolNames(messages)
+	var results []string
+	for i := len(messages) - 1; i >= 0 && messages[i].Role == RoleTool; i-- {
+		name := messages[i].Name
+		if name == "" {
+			name = names[messages[i].ToolCallID]
+		}
+		results = append([]string{fmt.Sprintf("%s returned %s", name, messages[i].Content)}, results...)
+	}
+	return &MockRule{Name: "tool-result", Response: "Tool results received: " + strings.Join(results, "; ")}
+}
+
+// take counts a match against the rule's Times limit
+func (r *MockRule) take() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Times > 0 && r.hits >= r.Times {
+		return false
+	}
+	r.hits++
+	return true
+}
+
+func (p *mockProvider) wait(ctx context.Context, rule *MockRule) error {
+	if rule.LatencyMs == 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(time.Duration(rule.LatencyMs) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *mockProvider) respond(rule *MockRule, match []int, prompt string, req *ChatRequest) (*ChatResponse, error) {
+	if rule.Error != nil {
+		return nil, p.fail(rule)
+	}
+
+	resp := &ChatResponse{
+		Model:        req.Model,
+		Content:      expand(rule, match, prompt, rule.Response),
+		FinishReason: rule.FinishReason,
+	}
+
+	for i, call := range rule.ToolCalls {
+		arguments := "{}"
+		if call.Arguments != "" {
+			arguments = expand(rule, match, prompt, call.Arguments)
+		}
+		if !json.Valid([]byte(arguments)) {
+			return nil, &Error{Provider: p.Name(), StatusCode: http.StatusInternalServerError, Message: fmt.Sprintf("fixture %s produced invalid arguments for %s", rule.Name, call.Name)}
+		}
+
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i+1)
+		}
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{ID: id, Name: call.Name, Arguments: json.RawMessage(arguments)})
+	}
+
+	if resp.FinishReason == "" {
+		resp.FinishReason = "stop"
+		if len(resp.ToolCalls) > 0 {
+			resp.FinishReason = "tool_calls"
+		}
+	}
+
+	if rule.Usage != nil {
+		resp.Usage = Usage{InputTokens: rule.Usage.InputTokens, OutputTokens: rule.Usage.OutputTokens}
+	} else {
+		for _, m := range req.Messages {
+			resp.Usage.InputTokens += estimateTokens(m.Content)
+		}
+		resp.Usage.OutputTokens = estimateTokens(resp.Content)
+		for _, call := range resp.ToolCalls {
+			resp.Usage.OutputTokens += estimateTokens(call.Name) + estimateTokens(string(call.Arguments))
+		}
+	}
+	resp.Usage.TotalTokens = resp.Usage.InputTokens + resp.Usage.OutputTokens
+
+	return resp, nil
+}
+
+// fail builds the provider error a failing rule simulates
+func (p *mockProvider) fail(rule *MockRule) *Error {
+	return &Error{
+		Provider:   p.Name(),
+		StatusCode: rule.Error.Status,
+		Message:    rule.Error.Message,
+		Retryable:  isRetryableStatus(rule.Error.Status),
+		RetryAfter: time.Duration(rule.Error.RetryAfterSeconds) * time.Second,
+	}
+}
+
+// expand fills $1 / ${name} in a template with the captures of the rule's match
+func expand(rule *MockRule, match []int, prompt, template string) string {
+	if match == nil || rule.re == nil {
+		return template
+	}
+	return string(rule.re.ExpandString(nil, template, prompt, match))
+}
+
+// estimateTokens approximates a token count as one token per four characters
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
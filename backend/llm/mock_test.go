@@ -0,0 +1,293 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+// useMockFixtures loads fixtures from YAML for the duration of the test
+func useMockFixtures(t *testing.T, yaml string) {
+	t.Helper()
+
+	fixtures, err := ParseMockFixtures([]byte(yaml), ".yaml")
+	if err != nil {
+		t.Fatalf("ParseMockFixtures: %v", err)
+	}
+
+	mockFixturesMu.Lock()
+	previous := mockFixtures
+	mockFixtures = fixtures
+	mockFixturesMu.Unlock()
+
+	t.Cleanup(func() {
+		mockFixturesMu.Lock()
+		mockFixtures = previous
+		mockFixturesMu.Unlock()
+	})
+}
+
+func newMockProvider(t *testing.T) Provider {
+	t.Helper()
+
+	provider, err := New(&models.ProviderConfig{Name: "mock", APIFormat: FormatMock}, "", nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return provider
+}
+
+func userMessage(content string) *ChatRequest {
+	return &ChatRequest{Model: "mock-model", Messages: []Message{{Role: RoleUser, Content: content}}}
+}
+
+func TestMockRuleMatching(t *testing.T) {
+	useMockFixtures(t, `
+rules:
+  - name: once
+    match: '(?i)^hello'
+    times: 1
+    response: "first hello"
+  - name: model-only
+    match: 'hello'
+    model: other-model
+    response: "other model"
+  - name: capture
+    match: '(?i)hello (?P<who>\w+)'
+    response: "hi ${who}"
+default:
+  response: "fallback"
+`)
+	provider := newMockProvider(t)
+
+	tests := []struct {
+		name   string
+		prompt string
+		model  string
+		want   string
+	}{
+		{name: "first rule", prompt: "Hello there", want: "first hello"},
+		{name: "times used up falls through", prompt: "Hello there", want: "hi there"},
+		{name: "model filter", prompt: "say hello", model: "other-model", want: "other model"},
+		{name: "model filter skips other models", prompt: "say hello", want: "fallback"},
+		{name: "no match", prompt: "goodbye", want: "fallback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := userMessage(tt.prompt)
+			if tt.model != "" {
+				req.Model = tt.model
+			}
+			resp, err := provider.Chat(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Chat: %v", err)
+			}
+			if resp.Content != tt.want {
+				t.Errorf("content = %q, want %q", resp.Content, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockMatchesLastUserMessage(t *testing.T) {
+	useMockFixtures(t, `
+rules:
+  - match: 'second'
+    response: "matched the last message"
+`)
+	provider := newMockProvider(t)
+
+	resp, err := provider.Chat(context.Background(), &ChatRequest{Messages: []Message{
+		{Role: RoleUser, Content: "first"},
+		{Role: RoleAssistant, Content: "ok"},
+		{Role: RoleUser, Content: "second"},
+	}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != "matched the last message" {
+		t.Errorf("content = %q", resp.Content)
+	}
+}
+
+func TestMockLatency(t *testing.T) {
+	useMockFixtures(t, `
+rules:
+  - match: 'slow'
+    latency_ms: 5000
+    response: "late"
+`)
+	provider := newMockProvider(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := provider.Chat(ctx, userMessage("slow"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Chat: got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("cancelled call took %v, want it to stop waiting", elapsed)
+	}
+
+	_, err = provider.ChatStream(ctx, userMessage("slow"), func(StreamChunk) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ChatStream: got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestMockErrors(t *testing.T) {
+	useMockFixtures(t, `
+rules:
+  - match: 'rate limit'
+    error: {status: 429, message: "slow down", retry_after_seconds: 7}
+  - match: 'outage'
+    error: {status: 503, message: "unavailable"}
+  - match: 'bad request'
+    error: {status: 400, message: "invalid"}
+`)
+	provider := newMockProvider(t)
+
+	tests := []struct {
+		prompt        string
+		wantStatus    int
+		wantRetryable bool
+		wantAfter     time.Duration
+	}{
+		{prompt: "rate limit", wantStatus: http.StatusTooManyRequests, wantRetryable: true, wantAfter: 7 * time.Second},
+		{prompt: "outage", wantStatus: http.StatusServiceUnavailable, wantRetryable: true},
+		{prompt: "bad request", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prompt, func(t *testing.T) {
+			_, err := provider.Chat(context.Background(), userMessage(tt.prompt))
+			var providerErr *Error
+			if !errors.As(err, &providerErr) {
+				t.Fatalf("Chat: got %v, want an *Error", err)
+			}
+			if providerErr.StatusCode != tt.wantStatus || providerErr.Retryable != tt.wantRetryable || providerErr.RetryAfter != tt.wantAfter {
+				t.Errorf("error = status %d, retryable %v, retry after %v; want %d, %v, %v",
+					providerErr.StatusCode, providerErr.Retryable, providerErr.RetryAfter, tt.wantStatus, tt.wantRetryable, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestMockToolCalls(t *testing.T) {
+	useMockFixtures(t, `
+rules:
+  - match: 'report progress on task #?(\d+):\s*(.*)'
+    tool_calls:
+      - name: add_progress
+        arguments: '{"task_id": $1, "message": "${2}"}'
+      - id: fixed
+        name: get_context
+  - match: 'broken (.*)'
+    tool_calls:
+      - name: start_task
+        arguments: '{"task_id": $1}'
+  - match: 'done'
+    tool_result: true
+    response: "all done"
+`)
+	provider := newMockProvider(t)
+
+	resp, err := provider.Chat(context.Background(), userMessage("Please report progress on task #12: tests pass"))
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 2 {
+		t.Fatalf("response = %+v, want two tool calls", resp)
+	}
+	first, second := resp.ToolCalls[0], resp.ToolCalls[1]
+	if first.ID != "call_1" || first.Name != "add_progress" || !jsonEqual(t, first.Arguments, `{"task_id":12,"message":"tests pass"}`) {
+		t.Errorf("first call = %s %s %s", first.ID, first.Name, first.Arguments)
+	}
+	if second.ID != "fixed" || second.Name != "get_context" || string(second.Arguments) != "{}" {
+		t.Errorf("second call = %s %s %s", second.ID, second.Name, second.Arguments)
+	}
+
+	// Captures that do not form valid JSON fail the call instead of sending bad arguments
+	_, err = provider.Chat(context.Background(), userMessage("broken not-a-number"))
+	var providerErr *Error
+	if !errors.As(err, &providerErr) || !strings.Contains(providerErr.Message, "invalid arguments") {
+		t.Errorf("Chat with invalid arguments: got %v", err)
+	}
+
+	// Tool results are answered by tool_result rules, or acknowledged when none matches
+	conversation := []Message{
+		{Role: RoleUser, Content: "report progress on task 12: tests pass"},
+		{Role: RoleAssistant, ToolCalls: resp.ToolCalls},
+		{Role: RoleTool, ToolCallID: "call_1", Content: `{"id": 3}`},
+	}
+	resp, err = provider.Chat(context.Background(), &ChatRequest{Messages: conversation})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != `Tool results received: add_progress returned {"id": 3}` || len(resp.ToolCalls) != 0 {
+		t.Errorf("acknowledgement = %q, %d tool calls", resp.Content, len(resp.ToolCalls))
+	}
+
+	conversation[2].Content = "done"
+	resp, err = provider.Chat(context.Background(), &ChatRequest{Messages: conversation})
+	if err != nil || resp.Content != "all done" {
+		t.Errorf("tool_result rule = %q, %v", resp.Content, err)
+	}
+}
+
+func TestMockStreamAndUsage(t *testing.T) {
+	useMockFixtures(t, `
+rules:
+  - match: 'counted'
+    response: "one two three"
+    usage: {input_tokens: 11, output_tokens: 3}
+`)
+	provider := newMockProvider(t)
+
+	var chunks []string
+	resp, err := provider.ChatStream(context.Background(), userMessage("counted"), func(chunk StreamChunk) error {
+		chunks = append(chunks, chunk.Delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if strings.Join(chunks, "") != "one two three" || len(chunks) != 3 {
+		t.Errorf("chunks = %q", chunks)
+	}
+	if resp.Usage != (Usage{InputTokens: 11, OutputTokens: 3, TotalTokens: 14}) {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestParseMockFixturesRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "missing match", yaml: "rules: [{response: x}]", wantErr: "match is required"},
+		{name: "invalid regexp", yaml: "rules: [{match: '('}]", wantErr: "invalid match"},
+		{name: "negative times", yaml: "rules: [{match: x, times: -1}]", wantErr: "must not be negative"},
+		{name: "error status", yaml: "rules: [{match: x, error: {status: 200}}]", wantErr: "4xx or 5xx"},
+		{name: "tool call without name", yaml: "rules: [{match: x, tool_calls: [{arguments: '{}'}]}]", wantErr: "tool call name"},
+		{name: "default with match", yaml: "default: {match: x}", wantErr: "not allowed"},
+		{name: "unknown field", yaml: "rules: [{match: x, reply: y}]", wantErr: "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMockFixtures([]byte(tt.yaml), ".yaml"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+// openAIProvider speaks the OpenAI chat completions format used by
+// OpenRouter, Z.AI, Kimi and other compatible providers
+type openAIProvider struct {
+	cfg    *models.ProviderConfig
+	apiKey string
+	client *http.Client
+	// streamClient has no overall deadline, streams are bounded by openStream's idle timeout
+	streamClient *http.Client
+}
+
+type openAIRequest struct {
+	Model            string               `json:"model"`
+	Messages         []openAIMessage      `json:"messages"`
+	Tools            []openAITool         `json:"tools,omitempty"`
+	MaxTokens        int                  `json:"max_tokens,omitempty"`
+	Temperature      *float64             `json:"temperature,omitempty"`
+	TopP             *float64             `json:"top_p,omitempty"`
+	FrequencyPenalty *float64             `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64             `json:"presence_penalty,omitempty"`
+	Stream           bool                 `json:"stream,omitempty"`
+	StreamOptions    *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+// openAIMessage is a chat message. Content is a string, null on assistant messages that
+// only call tools, or a list of openAIContentPart on messages that carry images.
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    any              `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIContentPart is a text or image_url part of a message's content
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+// openAIImageURL carries an image as a data URL
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// openAIToolCall is a tool call; in streams it arrives in pieces identified by Index
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func (u openAIUsage) toUsage() Usage {
+	usage := Usage{
+		InputTokens:  u.PromptTokens,
+		OutputTokens: u.CompletionTokens,
+		TotalTokens:  u.TotalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	return usage
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage openAIUsage `json:"usage"`
+}
+
+// openAIStreamChunk is a chat.completion.chunk event. Some compatible
+// providers report usage on the final choice instead of the chunk.
+type openAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string      `json:"finish_reason"`
+		Usage        *openAIUsage `json:"usage"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+func (p *openAIProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *openAIProvider) breakerKey() string {
+	return breakerKey(p.cfg, p.apiKey)
+}
+
+func (p *openAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var result openAIResponse
+	if err := postJSON(ctx, p.client, p.Name(), p.cfg.BaseURL+p.cfg.ChatCompletionPath, p.headers(), p.buildRequest(req), &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Choices) == 0 {
+		return nil, &Error{Provider: p.Name(), Message: "response contained no choices"}
+	}
+
+	resp := &ChatResponse{
+		Model:        result.Model,
+		Content:      result.Choices[0].Message.Content,
+		FinishReason: result.Choices[0].FinishReason,
+		Usage:        result.Usage.toUsage(),
+	}
+	for _, call := range result.Choices[0].Message.ToolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: toolArguments(call.Function.Arguments)})
+	}
+	if resp.Model == "" {
+		resp.Model = req.Model
+	}
+
+	return resp, nil
+}
+
+func (p *openAIProvider) ChatStream(ctx context.Context, req *ChatRequest, onChunk func(StreamChunk) error) (*ChatResponse, error) {
+	body := p.buildRequest(req)
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+
+	resp := &ChatResponse{Model: req.Model}
+	var content strings.Builder
+	// Tool calls are streamed as fragments keyed by their index
+	var calls []*openAIToolCall
+
+	err := openStream(ctx, p.streamClient, p.Name(), p.cfg.BaseURL+p.cfg.ChatCompletionPath, p.headers(), body, func(_, data string) error {
+		if data == "[DONE]" {
+			return io.EOF
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return &Error{Provider: p.Name(), Message: fmt.Sprintf("failed to decode stream chunk: %v", err)}
+		}
+
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			resp.Usage = chunk.Usage.toUsage()
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				resp.FinishReason = *choice.FinishReason
+			}
+			if choice.Usage != nil {
+				resp.Usage = choice.Usage.toUsage()
+			}
+			for _, fragment := range choice.Delta.ToolCalls {
+				index := len(calls)
+				if fragment.Index != nil {
+					index = *fragment.Index
+				}
+				for len(calls) <= index {
+					calls = append(calls, &openAIToolCall{})
+				}
+				call := calls[index]
+				if fragment.ID != "" {
+					call.ID = fragment.ID
+				}
+				call.Function.Name += fragment.Function.Name
+				call.Function.Arguments += fragment.Function.Arguments
+			}
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				if err := onChunk(StreamChunk{Delta: choice.Delta.Content}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	resp.Content = content.String()
+	for _, call := range calls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: toolArguments(call.Function.Arguments)})
+	}
+	return resp, err
+}
+
+func (p *openAIProvider) buildRequest(req *ChatRequest) openAIRequest {
+	messages := make([]openAIMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		message := openAIMessage{Role: m.Role, ToolCallID: m.ToolCallID}
+		switch {
+		case len(m.Images) > 0:
+			parts := []openAIContentPart{{Type: "text", Text: m.Content}}
+			for _, image := range m.Images {
+				parts = append(parts, openAIContentPart{
+					Type:     "image_url",
+					ImageURL: &openAIImageURL{URL: "data:" + image.MediaType + ";base64," + image.base64()},
+				})
+			}
+			message.Content = parts
+		case m.Content != "" || len(m.ToolCalls) == 0:
+			message.Content = m.Content
+		}
+		for _, call := range m.ToolCalls {
+			var toolCall openAIToolCall
+			toolCall.ID = call.ID
+			toolCall.Type = "function"
+			toolCall.Function.Name = call.Name
+			toolCall.Function.Arguments = string(call.Arguments)
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+		}
+		messages = append(messages, message)
+	}
+
+	var tools []openAITool
+	for _, tool := range req.Tools {
+		tools = append(tools, openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: tool.Name, Description: tool.Description, Parameters: toolSchema(tool)},
+		})
+	}
+
+	return openAIRequest{
+		Model:            req.Model,
+		Messages:         messages,
+		Tools:            tools,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      optional(req.Temperature),
+		TopP:             optional(req.TopP),
+		FrequencyPenalty: optional(req.FrequencyPenalty),
+		PresencePenalty:  optional(req.PresencePenalty),
+	}
+}
+
+func (p *openAIProvider) headers() map[string]string {
+	return map[string]string{
+		"Authorization": "Bearer " + p.apiKey,
+	}
+}
+
+// optional omits zero sampling parameters so provider defaults apply
+func optional(v float64) *float64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
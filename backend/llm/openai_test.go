@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestOpenAIChat(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *ChatRequest
+		resp          string
+		wantRequest   string
+		wantContent   string
+		wantToolCalls []ToolCall
+		wantUsage     Usage
+	}{
+		{
+			name: "text",
+			req: &ChatRequest{
+				Model:       "gpt-test",
+				Messages:    []Message{{Role: RoleSystem, Content: "be brief"}, {Role: RoleUser, Content: "hi"}},
+				MaxTokens:   64,
+				Temperature: 0.5,
+			},
+			resp: `{"model":"gpt-test-0613","choices":[{"message":{"content":"hello"},"finish_reason":"stop"}],
+				"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`,
+			wantRequest: `{"model":"gpt-test","max_tokens":64,"temperature":0.5,
+				"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"}]}`,
+			wantContent: "hello",
+			wantUsage:   Usage{InputTokens: 12, OutputTokens: 3, TotalTokens: 15},
+		},
+		{
+			name: "tool call round trip",
+			req: &ChatRequest{
+				Model: "gpt-test",
+				Messages: []Message{
+					{Role: RoleUser, Content: "weather?"},
+					{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Name: "weather", Arguments: json.RawMessage(`{"city":"Izmir"}`)}}},
+					{Role: RoleTool, ToolCallID: "call_1", Content: "sunny"},
+				},
+				Tools: []Tool{{Name: "weather", Description: "Current weather"}},
+			},
+			resp: `{"choices":[{"message":{"content":"","tool_calls":[{"id":"call_2","type":"function",
+				"function":{"name":"weather","arguments":"{\"city\":\"Ankara\"}"}}]},"finish_reason":"tool_calls"}],
+				"usage":{"prompt_tokens":20,"completion_tokens":5}}`,
+			wantRequest: `{"model":"gpt-test",
+				"messages":[
+					{"role":"user","content":"weather?"},
+					{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Izmir\"}"}}]},
+					{"role":"tool","content":"sunny","tool_call_id":"call_1"}],
+				"tools":[{"type":"function","function":{"name":"weather","description":"Current weather","parameters":{"type":"object","properties":{}}}}]}`,
+			wantToolCalls: []ToolCall{{ID: "call_2", Name: "weather", Arguments: json.RawMessage(`{"city":"Ankara"}`)}},
+			// A missing total is derived from the input and output counts
+			wantUsage: Usage{InputTokens: 20, OutputTokens: 5, TotalTokens: 25},
+		},
+		{
+			name: "image",
+			req: &ChatRequest{
+				Model:    "gpt-test",
+				Messages: []Message{{Role: RoleUser, Content: "what is this?", Images: []Image{{MediaType: "image/png", Data: []byte("png")}}}},
+			},
+			resp: `{"choices":[{"message":{"content":"a logo"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`,
+			wantRequest: `{"model":"gpt-test","messages":[{"role":"user","content":[
+				{"type":"text","text":"what is this?"},
+				{"type":"image_url","image_url":{"url":"data:image/png;base64,cG5n"}}]}]}`,
+			wantContent: "a logo",
+			wantUsage:   Usage{InputTokens: 1, OutputTokens: 1, TotalTokens: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, captured := fakeProvider(t, FormatOpenAI, fakeResponse{body: tt.resp})
+
+			resp, err := newTestProvider(t, cfg).Chat(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Chat: %v", err)
+			}
+
+			if captured.path != "/chat/completions" {
+				t.Errorf("path = %q, want /chat/completions", captured.path)
+			}
+			if got := captured.header.Get("Authorization"); got != "Bearer test-key" {
+				t.Errorf("Authorization = %q, want Bearer test-key", got)
+			}
+			if !jsonEqual(t, captured.body, tt.wantRequest) {
+				t.Errorf("request body = %v, want %s", captured.body, tt.wantRequest)
+			}
+
+			if resp.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", resp.Content, tt.wantContent)
+			}
+			if !jsonEqual(t, resp.ToolCalls, mustJSON(t, tt.wantToolCalls)) {
+				t.Errorf("tool calls = %+v, want %+v", resp.ToolCalls, tt.wantToolCalls)
+			}
+			if resp.Usage != tt.wantUsage {
+				t.Errorf("usage = %+v, want %+v", resp.Usage, tt.wantUsage)
+			}
+			if resp.Model == "" {
+				t.Error("model is empty, want the response or request model")
+			}
+		})
+	}
+}
+
+func TestOpenAIChatStream(t *testing.T) {
+	stream := `data: {"model":"gpt-test-0613","choices":[{"delta":{"content":"Hel"}}]}
+
+data: {"choices":[{"delta":{"content":"lo"}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"weather","arguments":"{\"ci"}}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ty\":\"Izmir\"}"}}]},"finish_reason":"tool_calls"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":4,"total_tokens":13}}
+
+data: [DONE]
+
+`
+	cfg, captured := fakeProvider(t, FormatOpenAI, fakeResponse{headers: map[string]string{"Content-Type": "text/event-stream"}, body: stream})
+
+	var deltas []string
+	resp, err := newTestProvider(t, cfg).ChatStream(context.Background(), &ChatRequest{Model: "gpt-test", Messages: []Message{{Role: RoleUser, Content: "hi"}}}, func(chunk StreamChunk) error {
+		deltas = append(deltas, chunk.Delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	if captured.body["stream"] != true || !jsonEqual(t, captured.body["stream_options"], `{"include_usage":true}`) {
+		t.Errorf("request body = %v, want stream with usage", captured.body)
+	}
+	if len(deltas) != 2 || resp.Content != "Hello" {
+		t.Errorf("deltas = %q, content = %q, want two deltas forming Hello", deltas, resp.Content)
+	}
+	if resp.Model != "gpt-test-0613" || resp.FinishReason != "tool_calls" {
+		t.Errorf("model = %q, finish = %q", resp.Model, resp.FinishReason)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || string(resp.ToolCalls[0].Arguments) != `{"city":"Izmir"}` {
+		t.Errorf("tool calls = %+v, want the reassembled weather call", resp.ToolCalls)
+	}
+	if want := (Usage{InputTokens: 9, OutputTokens: 4, TotalTokens: 13}); resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+}
+
+func TestOpenAIEmbed(t *testing.T) {
+	cfg, captured := fakeProvider(t, FormatOpenAI, fakeResponse{body: `{"model":"embed-test",
+		"data":[{"index":1,"embedding":[0.3,0.4]},{"index":0,"embedding":[0.1,0.2]}],
+		"usage":{"prompt_tokens":6,"total_tokens":6}}`})
+
+	resp, err := Embed(context.Background(), newTestProvider(t, cfg), &EmbeddingRequest{Model: "embed-test", Input: []string{"a", "b"}, Dimensions: 2})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	if captured.path != "/embeddings" {
+		t.Errorf("path = %q, want /embeddings", captured.path)
+	}
+	if want := `{"model":"embed-test","input":["a","b"],"dimensions":2,"encoding_format":"float"}`; !jsonEqual(t, captured.body, want) {
+		t.Errorf("request body = %v, want %s", captured.body, want)
+	}
+	// Vectors come back in input order even when the provider reorders them
+	if !jsonEqual(t, resp.Embeddings, `[[0.1,0.2],[0.3,0.4]]`) {
+		t.Errorf("embeddings = %v", resp.Embeddings)
+	}
+	if want := (Usage{InputTokens: 6, TotalTokens: 6}); resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+}
+
+// mustJSON marshals v for comparison with jsonEqual
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(raw)
+}
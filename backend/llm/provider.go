@@ -0,0 +1,224 @@
+// Package llm talks to model providers. Each wire format has an adapter that
+// normalizes requests, responses, token usage and errors behind Provider.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+// Wire formats supported by the adapters
+const (
+	FormatOpenAI    = "openai"
+	FormatAnthropic = "anthropic"
+	FormatGemini    = "gemini"
+	// FormatMock answers from scripted fixtures without calling out
+	FormatMock = "mock"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Provider is implemented by every provider adapter
+type Provider interface {
+	// Name returns the registry name of the provider
+	Name() string
+	// Chat sends a single, non-streaming chat completion request
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+	// ChatStream streams a chat completion, calling onChunk for every text delta.
+	// It returns the aggregated response, including usage, once the stream ends.
+	ChatStream(ctx context.Context, req *ChatRequest, onChunk func(StreamChunk) error) (*ChatResponse, error)
+}
+
+// Message is a single chat message. Assistant messages may carry the tool calls
+// the model made; tool messages answer one of them by ToolCallID. User messages
+// may carry images for vision-capable models.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Images     []Image    `json:"images,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Name is the called tool's name on tool messages; Gemini matches results by name
+	Name string `json:"name,omitempty"`
+}
+
+// Image is an inline image sent with a message, such as a screenshot or mockup
+type Image struct {
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"data"`
+}
+
+// base64 returns the image data base64-encoded, as every wire format expects it
+func (i Image) base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// Tool is a function the model may call. Parameters is a JSON Schema object.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ChatRequest is a provider-agnostic chat completion request.
+// Zero values for optional sampling parameters are omitted.
+type ChatRequest struct {
+	Model            string
+	Messages         []Message
+	Tools            []Tool
+	MaxTokens        int
+	Temperature      float64
+	TopP             float64
+	FrequencyPenalty float64
+	PresencePenalty  float64
+}
+
+// Usage is the token usage reported by a provider
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// ToolCall is a function call requested by the model. Arguments is a JSON object.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ChatResponse is a provider-agnostic chat completion response
+type ChatResponse struct {
+	Model        string     `json:"model"`
+	Content      string     `json:"content"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string     `json:"finish_reason"`
+	Usage        Usage      `json:"usage"`
+}
+
+// DefaultTimeout bounds a single provider call when no client is supplied
+const DefaultTimeout = 60 * time.Second
+
+// StreamIdleTimeout bounds the wait for a stream's response headers and for each piece of
+// its body. Streams have no overall deadline, callers end them through their context.
+const StreamIdleTimeout = 60 * time.Second
+
+// New returns the adapter for a provider's wire format.
+// A nil httpClient uses a client with DefaultTimeout that records or
+// replays provider traffic when cassettes are configured. Streaming calls
+// use the same client without its overall Timeout.
+func New(cfg *models.ProviderConfig, apiKey string, httpClient *http.Client) (Provider, error) {
+	httpClient, streamClient := clients(httpClient)
+	return newAdapter(cfg, apiKey, httpClient, streamClient)
+}
+
+// clients returns the client for plain calls and the one for streams. A nil httpClient gets
+// the defaults; otherwise streams use a copy of it without its overall Timeout.
+func clients(httpClient *http.Client) (*http.Client, *http.Client) {
+	if httpClient == nil {
+		return defaultHTTPClient(), defaultStreamClient()
+	}
+	withoutTimeout := *httpClient
+	withoutTimeout.Timeout = 0
+	return httpClient, &withoutTimeout
+}
+
+// newAdapter returns the adapter for a provider's wire format using the given clients
+func newAdapter(cfg *models.ProviderConfig, apiKey string, httpClient, streamClient *http.Client) (Provider, error) {
+	switch cfg.APIFormat {
+	case FormatOpenAI, "":
+		return &openAIProvider{cfg: cfg, apiKey: apiKey, client: httpClient, streamClient: streamClient}, nil
+	case FormatAnthropic:
+		return &anthropicProvider{cfg: cfg, apiKey: apiKey, client: httpClient, streamClient: streamClient}, nil
+	case FormatGemini:
+		return &geminiProvider{cfg: cfg, apiKey: apiKey, client: httpClient, streamClient: streamClient}, nil
+	case FormatMock:
+		return &mockProvider{cfg: cfg}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported api format %q for provider %s", cfg.APIFormat, cfg.Name)
+}
+
+// defaultHTTPClient bounds calls by DefaultTimeout and records or replays cassettes
+func defaultHTTPClient() *http.Client {
+	return &http.Client{Timeout: DefaultTimeout, Transport: cassetteTransport(http.DefaultTransport)}
+}
+
+// defaultStreamClient waits at most StreamIdleTimeout for response headers and records or
+// replays cassettes. An http.Client Timeout would also cut off long streams mid-body.
+func defaultStreamClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = StreamIdleTimeout
+	return &http.Client{Transport: cassetteTransport(transport)}
+}
+
+// toolArguments normalizes the arguments a provider returned for a tool call to a JSON
+// value. Empty arguments become an empty object and malformed ones a JSON string.
+func toolArguments(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(arguments)) {
+		return json.RawMessage(arguments)
+	}
+	quoted, _ := json.Marshal(arguments)
+	return quoted
+}
+
+// toolInput returns tool call arguments as the JSON object Anthropic and Gemini expect
+func toolInput(arguments json.RawMessage) json.RawMessage {
+	if trimmed := bytes.TrimSpace(arguments); len(trimmed) > 0 && trimmed[0] == '{' {
+		return trimmed
+	}
+	return json.RawMessage("{}")
+}
+
+// toolSchema returns a tool's parameters, defaulting to an object without properties
+func toolSchema(tool Tool) json.RawMessage {
+	if len(tool.Parameters) == 0 {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return tool.Parameters
+}
+
+// toolNames maps tool call IDs to the called tool's name
+func toolNames(messages []Message) map[string]string {
+	names := make(map[string]string)
+	for _, m := range messages {
+		for _, call := range m.ToolCalls {
+			names[call.ID] = call.Name
+		}
+	}
+	return names
+}
+
+// splitSystem separates system messages, which Anthropic and Gemini take outside the conversation
+func splitSystem(messages []Message) (string, []Message) {
+	var system string
+	conversation := make([]Message, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == RoleSystem {
+			if system != "" {
+				system += "\n\n"
+			}
+			system += m.Content
+			continue
+		}
+		conversation = append(conversation, m)
+	}
+	return system, conversation
+}
@@ -0,0 +1,197 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+// fakeResponse is what a fake provider answers with
+type fakeResponse struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// capturedRequest is the last request a fake provider received
+type capturedRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   map[string]any
+}
+
+// fakeProvider starts a local stand-in for a provider that records the request and
+// answers with resp. It returns the provider config pointing at it.
+func fakeProvider(t *testing.T, format string, resp fakeResponse) (*models.ProviderConfig, *capturedRequest) {
+	t.Helper()
+
+	captured := &capturedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.method = r.Method
+		captured.path = r.URL.Path
+		captured.query = r.URL.RawQuery
+		captured.header = r.Header.Clone()
+		raw, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(raw, &captured.body); err != nil {
+			t.Errorf("request body is not a JSON object: %v: %s", err, raw)
+		}
+
+		for key, value := range resp.headers {
+			w.Header().Set(key, value)
+		}
+		status := resp.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, resp.body)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &models.ProviderConfig{Name: format, BaseURL: server.URL, APIFormat: format}
+	switch format {
+	case FormatOpenAI:
+		cfg.ChatCompletionPath = "/chat/completions"
+		cfg.EmbeddingPath = "/embeddings"
+	case FormatAnthropic:
+		cfg.ChatCompletionPath = "/messages"
+	case FormatGemini:
+		cfg.ChatCompletionPath = "/models/{model}:generateContent"
+		cfg.EmbeddingPath = "/models/{model}:batchEmbedContents"
+	}
+	return cfg, captured
+}
+
+// newTestProvider builds the adapter of cfg with a client that bypasses cassettes
+func newTestProvider(t *testing.T, cfg *models.ProviderConfig) Provider {
+	t.Helper()
+
+	provider, err := New(cfg, "test-key", &http.Client{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return provider
+}
+
+// jsonEqual reports whether got, after a JSON round trip, matches the JSON document want
+func jsonEqual(t *testing.T, got any, want string) bool {
+	t.Helper()
+
+	raw, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var gotValue, wantValue any
+	if err := json.Unmarshal(raw, &gotValue); err != nil {
+		t.Fatalf("unmarshal got: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("unmarshal want: %v", err)
+	}
+	gotJSON, _ := json.Marshal(gotValue)
+	wantJSON, _ := json.Marshal(wantValue)
+	return string(gotJSON) == string(wantJSON)
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := []struct {
+		name          string
+		resp          fakeResponse
+		wantStatus    int
+		wantMessage   string
+		wantRetryable bool
+		wantAfter     time.Duration
+	}{
+		{
+			name:        "bad request",
+			resp:        fakeResponse{status: http.StatusBadRequest, body: `{"error":{"message":"invalid model"}}`},
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "invalid model",
+		},
+		{
+			name:        "unauthorized without a JSON body",
+			resp:        fakeResponse{status: http.StatusUnauthorized, body: "nope"},
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "Unauthorized",
+		},
+		{
+			name: "rate limit with Retry-After",
+			resp: fakeResponse{
+				status:  http.StatusTooManyRequests,
+				headers: map[string]string{"Retry-After": "7"},
+				body:    `{"error":{"message":"slow down"}}`,
+			},
+			wantStatus:    http.StatusTooManyRequests,
+			wantMessage:   "slow down",
+			wantRetryable: true,
+			wantAfter:     7 * time.Second,
+		},
+		{
+			name:          "server error",
+			resp:          fakeResponse{status: http.StatusBadGateway, body: `{"error":{"message":"upstream failed"}}`},
+			wantStatus:    http.StatusBadGateway,
+			wantMessage:   "upstream failed",
+			wantRetryable: true,
+		},
+		{
+			name:          "overloaded",
+			resp:          fakeResponse{status: 529, body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`},
+			wantStatus:    529,
+			wantMessage:   "Overloaded",
+			wantRetryable: true,
+		},
+	}
+
+	for _, format := range []string{FormatOpenAI, FormatAnthropic, FormatGemini} {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				cfg, _ := fakeProvider(t, format, tt.resp)
+				provider := newTestProvider(t, cfg)
+
+				_, err := provider.Chat(context.Background(), &ChatRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+				providerErr, ok := AsError(err)
+				if !ok {
+					t.Fatalf("got %v, want a provider error", err)
+				}
+				if providerErr.Provider != format {
+					t.Errorf("provider = %q, want %q", providerErr.Provider, format)
+				}
+				if providerErr.StatusCode != tt.wantStatus {
+					t.Errorf("status = %d, want %d", providerErr.StatusCode, tt.wantStatus)
+				}
+				if providerErr.Message != tt.wantMessage {
+					t.Errorf("message = %q, want %q", providerErr.Message, tt.wantMessage)
+				}
+				if providerErr.Retryable != tt.wantRetryable {
+					t.Errorf("retryable = %v, want %v", providerErr.Retryable, tt.wantRetryable)
+				}
+				if providerErr.RetryAfter != tt.wantAfter {
+					t.Errorf("retry after = %v, want %v", providerErr.RetryAfter, tt.wantAfter)
+				}
+			})
+		}
+	}
+}
+
+func TestTransportErrorIsRetryable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	cfg := &models.ProviderConfig{Name: "openai", BaseURL: server.URL, ChatCompletionPath: "/chat/completions", APIFormat: FormatOpenAI}
+	server.Close()
+
+	_, err := newTestProvider(t, cfg).Chat(context.Background(), &ChatRequest{Model: "m"})
+	providerErr, ok := AsError(err)
+	if !ok {
+		t.Fatalf("got %v, want a provider error", err)
+	}
+	if providerErr.StatusCode != 0 || !providerErr.Retryable {
+		t.Errorf("got status %d retryable %v, want a retryable transport error", providerErr.StatusCode, providerErr.Retryable)
+	}
+}
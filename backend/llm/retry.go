@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how failed provider calls are retried
+type RetryPolicy struct {
+	// MaxAttempts includes the first call
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than this is not waited for.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy makes up to three attempts with 0.5s-base jittered backoff
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// resilientProvider retries retryable failures with backoff and guards the
+// provider with its circuit breaker
+type resilientProvider struct {
+	Provider
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+}
+
+// WithResilience wraps a provider with retries and the circuit breaker shared by calls to the
+// same endpoint with the same credential
+func WithResilience(p Provider, policy RetryPolicy) Provider {
+	return &resilientProvider{
+		Provider: p,
+		policy:   policy,
+		breaker:  BreakerFor(breakerKeyOf(p)),
+	}
+}
+
+func (p *resilientProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var resp *ChatResponse
+	err := p.do(ctx, func() error {
+		var err error
+		resp, err = p.Provider.Chat(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// ChatStream retries only until the first chunk was delivered, since a
+// partially relayed stream cannot be replayed
+func (p *resilientProvider) ChatStream(ctx context.Context, req *ChatRequest, onChunk func(StreamChunk) error) (*ChatResponse, error) {
+	delivered := false
+	var resp *ChatResponse
+	err := p.do(ctx, func() error {
+		var err error
+		resp, err = p.Provider.ChatStream(ctx, req, func(chunk StreamChunk) error {
+			delivered = true
+			return onChunk(chunk)
+		})
+		if err != nil && delivered {
+			return permanent{err}
+		}
+		return err
+	})
+	return resp, err
+}
+
+// permanent marks an error that must not be retried
+type permanent struct{ error }
+
+func (e permanent) Unwrap() error { return e.error }
+
+func (p *resilientProvider) do(ctx context.Context, call func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !p.breaker.Allow() {
+			return fmt.Errorf("%s: %w", p.Name(), ErrCircuitOpen)
+		}
+
+		err = call()
+		var stop permanent
+		if errors.As(err, &stop) {
+			p.record(stop.error)
+			return stop.error
+		}
+		p.record(err)
+
+		if err == nil || attempt >= p.policy.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay, ok := p.retryDelay(err, attempt)
+		if !ok {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// record feeds the outcome of a call to the circuit breaker. Only failures that
+// indicate an unhealthy provider count: a rejected request still proves the
+// provider is up, and a cancellation proves nothing. Rate limits are left to the
+// key pool's cooldowns.
+func (p *resilientProvider) record(err error) {
+	providerErr, ok := AsError(err)
+	switch {
+	case err == nil, ok && !providerErr.Retryable:
+		p.breaker.Success()
+	case ok && providerErr.StatusCode != http.StatusTooManyRequests:
+		p.breaker.Failure()
+	default:
+		p.breaker.Release()
+	}
+}
+
+// retryDelay returns how long to wait before the next attempt, or false if err is not retryable.
+// Retry-After is honoured; otherwise the delay is full-jitter exponential backoff.
+func (p *resilientProvider) retryDelay(err error, attempt int) (time.Duration, bool) {
+	providerErr, ok := AsError(err)
+	if !ok || !providerErr.Retryable {
+		return 0, false
+	}
+
+	if providerErr.RetryAfter > 0 {
+		if providerErr.RetryAfter > p.policy.MaxDelay {
+			return 0, false
+		}
+		return providerErr.RetryAfter, true
+	}
+
+	backoff := p.policy.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.policy.MaxDelay {
+		backoff = p.policy.MaxDelay
+	}
+	return time.Duration(rand.Int64N(int64(backoff) + 1)), true
+}
+
+// Unavailable reports whether err means the provider could not serve the request
+// right now, so a fallback model is worth trying
+func Unavailable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	providerErr, ok := AsError(err)
+	return ok && providerErr.Retryable
+}
@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StreamChunk is a piece of streamed model output
+type StreamChunk struct {
+	Delta string `json:"delta"`
+}
+
+// maxSSELine bounds a single server-sent event line
+const maxSSELine = 1024 * 1024
+
+// readSSE parses a server-sent event stream and calls fn with each event's name and data.
+// Returning io.EOF from fn stops reading without an error.
+func readSSE(ctx context.Context, r io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELine)
+
+	var event string
+	var data []string
+
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event = ""
+		data = data[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	// Flush a trailing event without a blank line
+	if err := dispatch(); err != nil && err != io.EOF {
+		return err
+	}
+
+	// A stream cut short by cancellation ends without a scanner error
+	return ctx.Err()
+}
+
+// errStreamIdle ends a stream that sent nothing for StreamIdleTimeout
+var errStreamIdle = errors.New("stream stalled: no data received within the idle timeout")
+
+// openStream posts a streaming request and relays its events to fn. The stream is abandoned
+// when its body sends nothing for StreamIdleTimeout.
+func openStream(ctx context.Context, client *http.Client, provider, endpoint string, headers map[string]string, body any, fn func(event, data string) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	resp, err := send(ctx, client, provider, endpoint, headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	idle := time.AfterFunc(StreamIdleTimeout, func() { cancel(errStreamIdle) })
+	defer idle.Stop()
+
+	err = readSSE(ctx, &idleReader{r: resp.Body, timer: idle}, fn)
+	if errors.Is(context.Cause(ctx), errStreamIdle) {
+		return transportError(provider, errStreamIdle)
+	}
+	return err
+}
+
+// idleReader pushes back the idle timer whenever data arrives
+type idleReader struct {
+	r     io.Reader
+	timer *time.Timer
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(StreamIdleTimeout)
+	}
+	return n, err
+}
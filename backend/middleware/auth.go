@@ -1,44 +1,207 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/berkkaradalan/stackflow/models"
 	"github.com/berkkaradalan/stackflow/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// AgentTokenAuthenticator resolves an agent API token to its agent
+type AgentTokenAuthenticator interface {
+	AuthenticateAgentToken(ctx context.Context, token string) (*models.Agent, error)
+}
+
+// ProjectResolver maps route resources to the project they belong to
+// and looks up a user's role in that project
+type ProjectResolver interface {
+	ProjectIDForTask(ctx context.Context, taskID int) (int, error)
+	ProjectIDForAgent(ctx context.Context, agentID int) (int, error)
+	ProjectRole(ctx context.Context, projectID int, userID int) (string, error)
+}
+
+// Resource kinds identified by a route's :id parameter
+const (
+	ScopeProject = "project"
+	ScopeTask    = "task"
+	ScopeAgent   = "agent"
+)
+
+// AuthMiddleware only accepts user JWTs
 func AuthMiddleware(jwtManager *utils.JWTManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
-			c.Abort()
+		token, ok := bearerToken(c)
+		if !ok {
 			return
 		}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization format"})
+		claims, err := jwtManager.ValidateAccessToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		claims, err := jwtManager.ValidateAccessToken(parts[1])
+		setUserContext(c, claims)
+		c.Next()
+	}
+}
+
+// ActorAuthMiddleware accepts user JWTs as well as agent API tokens.
+// Agent requests get actor_type=agent and carry the agent's ID and project.
+func ActorAuthMiddleware(jwtManager *utils.JWTManager, agentAuth AgentTokenAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			return
+		}
+
+		if strings.HasPrefix(token, models.AgentTokenPrefix) {
+			agent, err := agentAuth.AuthenticateAgentToken(c.Request.Context(), token)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+
+			// Set agent context
+			c.Set("actor_id", agent.ID)
+			c.Set("actor_type", models.CreatorTypeAgent)
+			c.Set("agent_id", agent.ID)
+			c.Set("agent_project_id", agent.ProjectID)
+			c.Set("agent_role", agent.Role)
+			c.Next()
+			return
+		}
+
+		claims, err := jwtManager.ValidateAccessToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		// Set user context
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
+		setUserContext(c, claims)
+		c.Next()
+	}
+}
+
+// ProjectScope requires the caller to hold at least minRole in the project
+// that the route's :id parameter (interpreted according to kind) belongs to.
+// Admins bypass membership checks. Agent tokens are further restricted to
+// their own agent and project and act as contributors, or as maintainers
+// for project manager agents.
+func ProjectScope(resolver ProjectResolver, kind string, minRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") == "admin" {
+			c.Next()
+			return
+		}
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + kind + " ID"})
+			c.Abort()
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		projectID := id
+		switch kind {
+		case ScopeTask:
+			projectID, err = resolver.ProjectIDForTask(ctx, id)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+				c.Abort()
+				return
+			}
+		case ScopeAgent:
+			projectID, err = resolver.ProjectIDForAgent(ctx, id)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+				c.Abort()
+				return
+			}
+		}
+
+		if c.GetString("actor_type") == models.CreatorTypeAgent {
+			allowed := projectID == c.GetInt("agent_project_id")
+			if kind == ScopeAgent {
+				allowed = id == c.GetInt("agent_id")
+			}
+			if !allowed {
+				c.JSON(http.StatusForbidden, gin.H{"error": "agent token is not allowed to access this " + kind})
+				c.Abort()
+				return
+			}
+
+			agentRole := models.ProjectRoleContributor
+			if c.GetString("agent_role") == "project_manager" {
+				agentRole = models.ProjectRoleMaintainer
+			}
+			if models.ProjectRoleRank(agentRole) < models.ProjectRoleRank(minRole) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "insufficient project permissions"})
+				c.Abort()
+				return
+			}
+
+			c.Next()
+			return
+		}
+
+		role, err := resolver.ProjectRole(ctx, projectID, c.GetInt("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project membership"})
+			c.Abort()
+			return
+		}
+		if role == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this project"})
+			c.Abort()
+			return
+		}
+		if models.ProjectRoleRank(role) < models.ProjectRoleRank(minRole) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient project permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Set("project_role", role)
 		c.Next()
 	}
 }
 
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
+		c.Abort()
+		return "", false
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization format"})
+		c.Abort()
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+func setUserContext(c *gin.Context, claims *utils.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("actor_id", claims.UserID)
+	c.Set("actor_type", models.CreatorTypeUser)
+}
+
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("role")
@@ -65,4 +228,4 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
 		c.Abort()
 	}
-}
\ No newline at end of file
+}
@@ -2,6 +2,33 @@ package models
 
 import "time"
 
+// Agent statuses
+const (
+	AgentStatusIdle         = "idle"
+	AgentStatusActive       = "active"
+	AgentStatusBusy         = "busy"
+	AgentStatusError        = "error"
+	AgentStatusDisabled     = "disabled"
+	AgentStatusInitializing = "initializing"
+)
+
+// Agent roles
+const (
+	AgentRoleBackendDeveloper   = "backend_developer"
+	AgentRoleFrontendDeveloper  = "frontend_developer"
+	AgentRoleFullstackDeveloper = "fullstack_developer"
+	AgentRoleTester             = "tester"
+	AgentRoleDevops             = "devops"
+	AgentRoleProjectManager     = "project_manager"
+)
+
+// Agent levels
+const (
+	AgentLevelJunior = "junior"
+	AgentLevelMid    = "mid"
+	AgentLevelSenior = "senior"
+)
+
 // Agent represents an AI agent in the system
 type Agent struct {
 	ID          int        `json:"id"`
@@ -13,8 +40,16 @@ type Agent struct {
 	Level       string     `json:"level"`
 	Provider    string     `json:"provider"`
 	Model       string     `json:"model"`
-	APIKey      string     `json:"api_key"`
+	// APIKey holds the encrypted key as stored; it is only decrypted when calling the provider
+	APIKey        string `json:"-"`
+	APIKeyVersion int    `json:"-"`
+	APIKeyHint    string `json:"-"`
+	APIKeyMasked  string `json:"api_key_masked,omitempty"`
+	// CredentialID is a shared provider credential used instead of the agent's own key
+	CredentialID *int `json:"credential_id,omitempty"`
 	Config      AgentConfig `json:"config"`
+	// FallbackModels are tried in order when the primary model is unavailable
+	FallbackModels []FallbackModel `json:"fallback_models"`
 	Status      string     `json:"status"`
 	IsActive    bool       `json:"is_active"`
 	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
@@ -34,6 +69,24 @@ type AgentConfig struct {
 	PresencePenalty  float64 `json:"presence_penalty"`
 }
 
+// FallbackModel is a model used when the agent's primary model is unavailable.
+// Fallbacks on the agent's own provider share its API key; others carry their own.
+type FallbackModel struct {
+	Provider      string `json:"provider"`
+	Model         string `json:"model"`
+	APIKey        string `json:"-"`
+	APIKeyVersion int    `json:"-"`
+	APIKeyHint    string `json:"-"`
+	APIKeyMasked  string `json:"api_key_masked,omitempty"`
+}
+
+// FallbackModelRequest is a fallback model in agent create and update requests
+type FallbackModelRequest struct {
+	Provider string `json:"provider" binding:"required"`
+	Model    string `json:"model" binding:"required"`
+	APIKey   string `json:"api_key"`
+}
+
 // CreateAgentRequest is the request model for creating an agent
 type CreateAgentRequest struct {
 	Name        string      `json:"name" binding:"required,min=3,max=100"`
@@ -41,10 +94,14 @@ type CreateAgentRequest struct {
 	ProjectID   int         `json:"project_id" binding:"required"`
 	Role        string      `json:"role" binding:"required,oneof=backend_developer frontend_developer fullstack_developer tester devops project_manager"`
 	Level       string      `json:"level" binding:"required,oneof=junior mid senior"`
-	Provider    string      `json:"provider" binding:"required,oneof=openrouter anthropic gemini kimi zai"`
+	Provider    string      `json:"provider" binding:"required"`
 	Model       string      `json:"model" binding:"required"`
-	APIKey      string      `json:"api_key" binding:"required"`
+	// APIKey is required unless the provider works without one or a credential is given
+	APIKey      string      `json:"api_key"`
+	// CredentialID references a shared credential of the project or the organization
+	CredentialID *int       `json:"credential_id" binding:"omitempty,min=1"`
 	Config      AgentConfig `json:"config"`
+	FallbackModels []FallbackModelRequest `json:"fallback_models" binding:"omitempty,max=5,dive"`
 }
 
 // UpdateAgentRequest is the request model for updating an agent
@@ -53,14 +110,24 @@ type UpdateAgentRequest struct {
 	Description *string      `json:"description" binding:"omitempty,max=500"`
 	Role        *string      `json:"role" binding:"omitempty,oneof=backend_developer frontend_developer fullstack_developer tester devops project_manager"`
 	Level       *string      `json:"level" binding:"omitempty,oneof=junior mid senior"`
-	Provider    *string      `json:"provider" binding:"omitempty,oneof=openrouter anthropic gemini kimi zai"`
+	Provider    *string      `json:"provider" binding:"omitempty"`
 	Model       *string      `json:"model" binding:"omitempty"`
 	APIKey      *string      `json:"api_key" binding:"omitempty"`
+	// CredentialID switches the agent to a shared credential; 0 goes back to the agent's own key
+	CredentialID *int        `json:"credential_id" binding:"omitempty,min=0"`
 	Config      *AgentConfig `json:"config" binding:"omitempty"`
+	// FallbackModels replaces the whole list; an empty list removes all fallbacks
+	FallbackModels *[]FallbackModelRequest `json:"fallback_models" binding:"omitempty,max=5,dive"`
 	Status      *string      `json:"status" binding:"omitempty,oneof=idle active busy error disabled initializing"`
 	IsActive    *bool        `json:"is_active"`
 }
 
+// FieldError describes a single invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // AgentListResponse is the response model for listing agents
 type AgentListResponse struct {
 	Agents     []Agent `json:"agents"`
@@ -107,4 +174,15 @@ type AgentHealthResponse struct {
 	Healthy      bool       `json:"healthy"`
 	Message      string     `json:"message"`
 	TestResponse string     `json:"test_response,omitempty"` // AI's response to health check test
+	LatencyMs    int64      `json:"latency_ms"`
+	// ConsecutiveFailures counts failed checks since the last successful one
+	ConsecutiveFailures int `json:"consecutive_failures"`
+}
+
+// ReencryptKeysResponse is the response model for re-encrypting stored API keys
+type ReencryptKeysResponse struct {
+	KeyVersion  int   `json:"key_version"`
+	Reencrypted int   `json:"reencrypted"`
+	Failed      []int `json:"failed_agent_ids"`
+	FailedCredentials []int `json:"failed_credential_ids"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// AgentAPIKey is an extra provider key in the key pool of an agent, or of a provider credential
+// when CredentialID is set instead of AgentID. Calls rotate round-robin over the agent's own or
+// credential key, the active pool keys of its credential and the agent's active pool keys of
+// its provider; a key that hits a rate limit rests until CooldownUntil.
+type AgentAPIKey struct {
+	ID           int    `json:"id"`
+	AgentID      *int   `json:"agent_id,omitempty"`
+	CredentialID *int   `json:"credential_id,omitempty"`
+	Provider     string `json:"provider"`
+	Label        string `json:"label"`
+	// APIKey holds the encrypted key as stored; it is only decrypted when calling the provider
+	APIKey           string     `json:"-"`
+	APIKeyVersion    int        `json:"-"`
+	APIKeyHint       string     `json:"-"`
+	APIKeyMasked     string     `json:"api_key_masked"`
+	IsActive         bool       `json:"is_active"`
+	TotalRequests    int64      `json:"total_requests"`
+	TotalTokensUsed  int64      `json:"total_tokens_used"`
+	TotalCost        float64    `json:"total_cost"`
+	RateLimitedCount int64      `json:"rate_limited_count"`
+	CooldownUntil    *time.Time `json:"cooldown_until,omitempty"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	CreatedBy        int        `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// CreateAgentAPIKeyRequest is the request model for adding a key to an agent's or credential's pool
+type CreateAgentAPIKeyRequest struct {
+	APIKey string `json:"api_key" binding:"required,min=8,max=500"`
+	Label  string `json:"label" binding:"omitempty,max=100"`
+}
+
+// UpdateAgentAPIKeyRequest is the request model for updating a pool key
+type UpdateAgentAPIKeyRequest struct {
+	Label    *string `json:"label" binding:"omitempty,max=100"`
+	IsActive *bool   `json:"is_active"`
+}
+
+// AgentAPIKeyListResponse is the response model for listing an agent's or credential's pool keys
+type AgentAPIKeyListResponse struct {
+	Keys       []AgentAPIKey `json:"keys"`
+	TotalCount int           `json:"total_count"`
+}
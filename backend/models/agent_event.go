@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Agent event types
+const (
+	AgentEventBudgetExceeded    = "budget_exceeded"
+	AgentEventHealthCheckFailed = "health_check_failed"
+)
+
+// AgentEvent is a notable change in an agent's lifecycle, such as being disabled by a budget
+type AgentEvent struct {
+	ID        int       `json:"id"`
+	AgentID   int       `json:"agent_id"`
+	EventType string    `json:"event_type"`
+	Message   string    `json:"message"`
+	Data      any       `json:"data,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AgentEventListResponse is the response model for listing agent events
+type AgentEventListResponse struct {
+	Events []AgentEvent `json:"events"`
+}
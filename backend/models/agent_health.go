@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Health check sources
+const (
+	HealthCheckSourceScheduled = "scheduled"
+	HealthCheckSourceManual    = "manual"
+)
+
+// AgentHealthCheck is the result of one real provider health check of an agent
+type AgentHealthCheck struct {
+	ID        int64     `json:"id"`
+	AgentID   int       `json:"agent_id"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Healthy   bool      `json:"healthy"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	Source    string    `json:"source"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// AgentHealthHistoryResponse is the response model for an agent's health check history
+type AgentHealthHistoryResponse struct {
+	Checks              []AgentHealthCheck `json:"checks"`
+	ConsecutiveFailures int                `json:"consecutive_failures"`
+	// FailureThreshold is the number of consecutive failures that marks the agent as error
+	FailureThreshold int `json:"failure_threshold"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// AgentTokenPrefix marks machine credentials so the auth middleware can tell them apart from user JWTs
+const AgentTokenPrefix = "sfa_"
+
+// AgentToken is an API credential that lets an agent call the API as itself
+type AgentToken struct {
+	ID          int        `json:"id"`
+	AgentID     int        `json:"agent_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	TokenHash   string     `json:"-"`
+	CreatedBy   int        `json:"created_by"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateAgentTokenRequest is the request model for issuing an agent token
+type CreateAgentTokenRequest struct {
+	Name          string `json:"name" binding:"required,min=1,max=100"`
+	ExpiresInDays *int   `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// CreateAgentTokenResponse contains the plaintext token, which is only returned once
+type CreateAgentTokenResponse struct {
+	AgentToken
+	Token string `json:"token"`
+}
+
+// AgentTokenListResponse is the response model for listing agent tokens
+type AgentTokenListResponse struct {
+	Tokens     []AgentToken `json:"tokens"`
+	TotalCount int          `json:"total_count"`
+}
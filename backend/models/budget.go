@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Budget periods
+const (
+	BudgetPeriodDaily    = "daily"
+	BudgetPeriodMonthly  = "monthly"
+	BudgetPeriodLifetime = "lifetime"
+)
+
+// Budget scopes
+const (
+	BudgetScopeAgent   = "agent"
+	BudgetScopeProject = "project"
+)
+
+// Budget caps the tokens and/or cost an agent or a whole project may spend per period
+type Budget struct {
+	ID        int       `json:"id"`
+	AgentID   *int      `json:"agent_id,omitempty"`
+	ProjectID *int      `json:"project_id,omitempty"`
+	Period    string    `json:"period"`
+	MaxTokens *int64    `json:"max_tokens,omitempty"`
+	MaxCost   *float64  `json:"max_cost,omitempty"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetBudgetRequest is the request model for creating or replacing a budget
+type SetBudgetRequest struct {
+	Period    string   `json:"period" binding:"required,oneof=daily monthly lifetime"`
+	MaxTokens *int64   `json:"max_tokens" binding:"omitempty,min=1"`
+	MaxCost   *float64 `json:"max_cost" binding:"omitempty,gt=0"`
+}
+
+// PeriodUsage is the tokens and cost spent in one budget period
+type PeriodUsage struct {
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost"`
+}
+
+// UsageTotals is spending in the current day, the current month and overall
+type UsageTotals struct {
+	Daily    PeriodUsage `json:"daily"`
+	Monthly  PeriodUsage `json:"monthly"`
+	Lifetime PeriodUsage `json:"lifetime"`
+}
+
+// BudgetStatus is a budget with its spending in the current period
+type BudgetStatus struct {
+	Scope           string   `json:"scope"`
+	Period          string   `json:"period"`
+	MaxTokens       *int64   `json:"max_tokens,omitempty"`
+	MaxCost         *float64 `json:"max_cost,omitempty"`
+	UsedTokens      int64    `json:"used_tokens"`
+	UsedCost        float64  `json:"used_cost"`
+	RemainingTokens *int64   `json:"remaining_tokens,omitempty"`
+	RemainingCost   *float64 `json:"remaining_cost,omitempty"`
+	Exceeded        bool     `json:"exceeded"`
+}
+
+// BudgetResponse lists the budgets that apply to an agent or project
+type BudgetResponse struct {
+	AgentID   *int           `json:"agent_id,omitempty"`
+	ProjectID int            `json:"project_id"`
+	Budgets   []BudgetStatus `json:"budgets"`
+	Exceeded  bool           `json:"exceeded"`
+}
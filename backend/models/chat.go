@@ -0,0 +1,84 @@
+package models
+
+import "encoding/json"
+
+// ChatMessage is a single message sent to an agent's model.
+// Assistant messages may carry the tool calls the model made instead of content;
+// tool messages return the result of one of them.
+type ChatMessage struct {
+	Role       string         `json:"role" binding:"required,oneof=system user assistant tool"`
+	Content    string         `json:"content" binding:"required_without=ToolCalls"`
+	ToolCalls  []ChatToolCall `json:"tool_calls" binding:"omitempty,dive"`
+	ToolCallID string         `json:"tool_call_id" binding:"required_if=Role tool"`
+	// Name is the called tool's name on tool messages
+	Name string `json:"name"`
+	// AttachmentIDs are task attachments sent with user messages as images; the model must support vision
+	AttachmentIDs []int `json:"attachment_ids" binding:"omitempty,max=10,unique"`
+}
+
+// ChatTool is a function the model may call, described by a JSON Schema for its arguments.
+// Calls to these tools are returned to the client to execute.
+type ChatTool struct {
+	Name        string          `json:"name" binding:"required,max=64"`
+	Description string          `json:"description" binding:"omitempty,max=1024"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ChatRequest is the request model for chatting with an agent
+type ChatRequest struct {
+	Messages []ChatMessage `json:"messages" binding:"required,min=1,dive"`
+	Tools    []ChatTool    `json:"tools" binding:"omitempty,max=64,dive"`
+	// BuiltinTools lets the model call Stackflow operations, executed server-side as the agent
+	BuiltinTools bool `json:"builtin_tools"`
+	// Optional attribution of the call in the usage ledger
+	TaskID       *int `json:"task_id"`
+	AssignmentID *int `json:"assignment_id"`
+}
+
+// ChatUsage is the token usage of a chat completion
+type ChatUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// ChatToolCall is a tool call requested by the model. Arguments is a JSON object.
+type ChatToolCall struct {
+	ID        string          `json:"id" binding:"required"`
+	Name      string          `json:"name" binding:"required"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ChatToolExecution is a built-in tool call the server executed on the agent's behalf
+type ChatToolExecution struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// ChatResponse is the normalized response of a chat completion.
+// With built-in tools it covers every round of the conversation: usage and cost are
+// totals and content is the model's final answer.
+type ChatResponse struct {
+	AgentID  int    `json:"agent_id"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Content  string `json:"content"`
+	// ToolCalls are calls for the client to execute
+	ToolCalls      []ChatToolCall      `json:"tool_calls,omitempty"`
+	ToolExecutions []ChatToolExecution `json:"tool_executions,omitempty"`
+	FinishReason   string              `json:"finish_reason"`
+	Usage          ChatUsage           `json:"usage"`
+	Cost           float64             `json:"cost"`
+	// Fallback is set when the primary model was unavailable and a fallback answered
+	Fallback bool `json:"fallback"`
+	// PromptTemplate is the template version of the system prompt, unless the request brought its own
+	PromptTemplate *PromptTemplateRef `json:"prompt_template,omitempty"`
+}
+
+// BuiltinToolListResponse is the response model for listing the built-in agent tools
+type BuiltinToolListResponse struct {
+	Tools []ChatTool `json:"tools"`
+}
@@ -0,0 +1,33 @@
+package models
+
+// EmbeddingRequest is the request model for creating embeddings.
+// Users bill the call to exactly one of an agent or a project; agent tokens always bill their own agent.
+type EmbeddingRequest struct {
+	AgentID   *int `json:"agent_id"`
+	ProjectID *int `json:"project_id"`
+	// Provider and Model default to the agent's provider and its first embedding model
+	Provider   string   `json:"provider"`
+	Model      string   `json:"model"`
+	Input      []string `json:"input" binding:"required,min=1,max=2048,dive,required,max=32000"`
+	Dimensions int      `json:"dimensions" binding:"omitempty,min=1,max=4096"`
+	// APIKey is used for project-billed calls to providers that require a key; it is never stored
+	APIKey string `json:"api_key"`
+}
+
+// Embedding is the vector of one input
+type Embedding struct {
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// EmbeddingResponse is the response model for creating embeddings
+type EmbeddingResponse struct {
+	AgentID    *int        `json:"agent_id,omitempty"`
+	ProjectID  int         `json:"project_id"`
+	Provider   string      `json:"provider"`
+	Model      string      `json:"model"`
+	Dimensions int         `json:"dimensions"`
+	Embeddings []Embedding `json:"embeddings"`
+	Usage      ChatUsage   `json:"usage"`
+	Cost       float64     `json:"cost"`
+}
@@ -8,6 +8,8 @@ const (
 	ExecutionPlanStatusCompleted = "completed"
 	ExecutionPlanStatusCancelled = "cancelled"
 	ExecutionPlanStatusDraft     = "draft"
+	// ExecutionPlanStatusSuperseded marks an active plan replaced by a newer one
+	ExecutionPlanStatusSuperseded = "superseded"
 )
 
 // Agent assignment status constants
@@ -71,6 +73,15 @@ type ExecutionPlanWithDetails struct {
 	ExecutionPlan
 	ProjectName string `json:"project_name"`
 	CreatorName string `json:"creator_name"`
+	// AssignmentSync reports how a create or update changed the plan's assignments
+	AssignmentSync *AssignmentSyncResult `json:"assignment_sync,omitempty"`
+}
+
+// AssignmentSyncResult counts the assignment changes made when reconciling a plan's priority order
+type AssignmentSyncResult struct {
+	Created    int `json:"created"`
+	Reassigned int `json:"reassigned"`
+	Skipped    int `json:"skipped"`
 }
 
 // AgentAssignment represents a task assignment to an agent within a plan
@@ -82,6 +93,11 @@ type AgentAssignment struct {
 	Status      string     `json:"status"`
 	StartedAt   *time.Time `json:"started_at,omitempty"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// LeaseExpiresAt is when an in-progress assignment goes back to pending without a heartbeat
+	LeaseExpiresAt  *time.Time `json:"lease_expires_at,omitempty"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at,omitempty"`
+	// ReclaimCount counts the times the assignment's lease lapsed
+	ReclaimCount int       `json:"reclaim_count"`
 	ReportData  any        `json:"report_data,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
@@ -94,6 +110,13 @@ type AgentAssignmentWithDetails struct {
 	TaskTitle string `json:"task_title"`
 }
 
+// PendingAssignment is a pending assignment with what next-task selection ranks it by
+type PendingAssignment struct {
+	AgentAssignmentWithDetails
+	TaskPriority string
+	PlanData     PlanData
+}
+
 // ExecutionReport represents a generated execution report
 type ExecutionReport struct {
 	ID            int       `json:"id"`
@@ -130,6 +153,8 @@ type TaskCompleteRequest struct {
 	TaskID     int    `json:"task_id" binding:"required"`
 	ReportData any    `json:"report_data" binding:"omitempty"`
 	Message    string `json:"message" binding:"omitempty,max=2000"`
+	// TestCoverage is the percentage of the work covered by tests, checked against the plan's TestCoverageMin
+	TestCoverage *float64 `json:"test_coverage" binding:"omitempty,min=0,max=100"`
 }
 
 // GenerateReportRequest is the request model for generating a report
@@ -152,11 +177,28 @@ type AgentAssignmentListResponse struct {
 	TotalCount  int                          `json:"total_count"`
 }
 
-// NextTaskResponse is the response model for an agent asking what to do next
+// NextTaskResponse is the response model for an agent asking what to do next.
+// When every pending task waits on unfinished dependencies, BlockedBy explains why.
 type NextTaskResponse struct {
 	Assignment *AgentAssignmentWithDetails `json:"assignment,omitempty"`
 	Context    *AgentContextResponse       `json:"context,omitempty"`
 	Message    string                      `json:"message"`
+	BlockedBy  []BlockedAssignment         `json:"blocked_by,omitempty"`
+}
+
+// BlockedAssignment is a pending assignment whose dependency tasks are not finished yet
+type BlockedAssignment struct {
+	AssignmentID int            `json:"assignment_id"`
+	TaskID       int            `json:"task_id"`
+	TaskTitle    string         `json:"task_title"`
+	WaitingOn    []BlockingTask `json:"waiting_on"`
+}
+
+// BlockingTask is an unfinished dependency of a blocked assignment
+type BlockingTask struct {
+	TaskID int    `json:"task_id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
 }
 
 // AgentContextResponse provides PM constraints and notes for the agent
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Project role constants, ordered from least to most privileged
+const (
+	ProjectRoleViewer      = "viewer"
+	ProjectRoleContributor = "contributor"
+	ProjectRoleMaintainer  = "maintainer"
+	ProjectRoleOwner       = "owner"
+)
+
+// ProjectRoleRank returns the privilege level of a project role (0 for unknown roles)
+func ProjectRoleRank(role string) int {
+	switch role {
+	case ProjectRoleViewer:
+		return 1
+	case ProjectRoleContributor:
+		return 2
+	case ProjectRoleMaintainer:
+		return 3
+	case ProjectRoleOwner:
+		return 4
+	}
+	return 0
+}
+
+// ProjectMember represents a user's membership and role in a project
+type ProjectMember struct {
+	ProjectID int       `json:"project_id"`
+	UserID    int       `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProjectMemberWithDetails includes user details for display
+type ProjectMemberWithDetails struct {
+	ProjectMember
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	AvatarUrl string `json:"avatar_url"`
+}
+
+// AddProjectMemberRequest is the request model for adding a member to a project
+type AddProjectMemberRequest struct {
+	UserID int    `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=owner maintainer contributor viewer"`
+}
+
+// UpdateProjectMemberRequest is the request model for changing a member's role
+type UpdateProjectMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner maintainer contributor viewer"`
+}
+
+// ProjectMemberListResponse is the response model for listing project members
+type ProjectMemberListResponse struct {
+	Members    []ProjectMemberWithDetails `json:"members"`
+	TotalCount int                        `json:"total_count"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import "time"
+
+// Prompt template sources
+const (
+	PromptTemplateSourceDefault = "default"
+	PromptTemplateSourceProject = "project"
+)
+
+// PromptTemplate is one version of the system prompt for an agent role and level.
+// Defaults have no project; a project's templates override the defaults for its agents.
+// An empty level applies to every level of the role.
+type PromptTemplate struct {
+	ID         int       `json:"id"`
+	ProjectID  *int      `json:"project_id,omitempty"`
+	Role       string    `json:"role"`
+	Level      string    `json:"level"`
+	Version    int       `json:"version"`
+	Content    string    `json:"content"`
+	ChangeNote string    `json:"change_note,omitempty"`
+	CreatedBy  int       `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Source tells whether the template is a default or a project override
+func (t *PromptTemplate) Source() string {
+	if t.ProjectID != nil {
+		return PromptTemplateSourceProject
+	}
+	return PromptTemplateSourceDefault
+}
+
+// CreatePromptTemplateRequest is the request model for saving a new template version
+type CreatePromptTemplateRequest struct {
+	Role       string `json:"role" binding:"required,oneof=backend_developer frontend_developer fullstack_developer tester devops project_manager"`
+	Level      string `json:"level" binding:"omitempty,oneof=junior mid senior"`
+	Content    string `json:"content" binding:"required,max=20000"`
+	ChangeNote string `json:"change_note" binding:"omitempty,max=500"`
+}
+
+// PromptTemplateQuery selects the versions of a role and level
+type PromptTemplateQuery struct {
+	Role  string `form:"role" binding:"required,oneof=backend_developer frontend_developer fullstack_developer tester devops project_manager"`
+	Level string `form:"level" binding:"omitempty,oneof=junior mid senior"`
+}
+
+// PromptTemplateDiffQuery names the two template versions to compare by ID
+type PromptTemplateDiffQuery struct {
+	From int `form:"from" binding:"required"`
+	To   int `form:"to" binding:"required"`
+}
+
+// PromptTemplateListResponse is the response model for listing prompt templates
+type PromptTemplateListResponse struct {
+	Templates []PromptTemplate `json:"templates"`
+	Total     int              `json:"total"`
+}
+
+// PromptTemplateDiff is a line diff between two template versions.
+// Lines start with "+ " when added, "- " when removed and two spaces when unchanged.
+type PromptTemplateDiff struct {
+	From    PromptTemplate `json:"from"`
+	To      PromptTemplate `json:"to"`
+	Diff    string         `json:"diff"`
+	Added   int            `json:"added"`
+	Removed int            `json:"removed"`
+}
+
+// PromptTemplateRef identifies the template version a prompt was rendered from
+type PromptTemplateRef struct {
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Source  string `json:"source"`
+}
+
+// RenderedPrompt is an agent's system prompt rendered for a run
+type RenderedPrompt struct {
+	Template PromptTemplateRef `json:"template"`
+	Role     string            `json:"role"`
+	Level    string            `json:"level"`
+	Content  string            `json:"content"`
+}
+
+// PromptContext holds the variables available to prompt templates,
+// e.g. {{.Agent.Name}}, {{.Project.Name}} or {{join .Plan.FocusAreas ", "}}.
+// Plan and Task are nil when the project has no active plan or the run has no task.
+type PromptContext struct {
+	Agent   PromptAgent   `json:"agent"`
+	Project PromptProject `json:"project"`
+	Plan    *PromptPlan   `json:"plan,omitempty"`
+	Task    *PromptTask   `json:"task,omitempty"`
+}
+
+// PromptAgent is the agent a prompt is rendered for
+type PromptAgent struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	Level string `json:"level"`
+}
+
+// PromptProject is the agent's project
+type PromptProject struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PromptPlan is the project's active execution plan
+type PromptPlan struct {
+	FocusAreas  []string        `json:"focus_areas"`
+	Constraints PlanConstraints `json:"constraints"`
+	Notes       string          `json:"notes"`
+}
+
+// PromptTask is the task of the run, with its entry in the active plan when there is one
+type PromptTask struct {
+	ID              int      `json:"id"`
+	Title           string   `json:"title"`
+	Description     string   `json:"description"`
+	Priority        string   `json:"priority"`
+	Tags            []string `json:"tags"`
+	Dependencies    []int    `json:"dependencies"`
+	EstimatedEffort string   `json:"estimated_effort"`
+	Notes           string   `json:"notes"`
+}
@@ -7,8 +7,12 @@ type ProviderConfig struct {
 	BaseURL            string        `json:"base_url"`
 	HealthCheckPath    string        `json:"health_check_path"`
 	ChatCompletionPath string        `json:"chat_completion_path"`
+	// EmbeddingPath is the embeddings endpoint; empty when the provider offers none
+	EmbeddingPath      string        `json:"embedding_path,omitempty"`
+	APIFormat          string        `json:"api_format"` // wire format: "openai", "anthropic" or "gemini"
 	RequiresAPIKey     bool          `json:"requires_api_key"`
 	Models             []ModelConfig `json:"models"`
+	Custom             bool          `json:"custom"` // registered by an admin rather than the registry file
 }
 
 // ModelConfig represents a model configuration
@@ -19,6 +23,10 @@ type ModelConfig struct {
 	MaxTokens          int     `json:"max_tokens"`
 	SupportsStreaming  bool    `json:"supports_streaming"`
 	SupportsVision     bool    `json:"supports_vision"`
+	// Embedding marks models that produce embeddings rather than chat completions
+	Embedding          bool    `json:"embedding,omitempty"`
+	// Dimensions is the vector size of an embedding model
+	Dimensions         int     `json:"dimensions,omitempty"`
 	InputPricePerMToken  float64 `json:"input_price_per_m_token"`
 	OutputPricePerMToken float64 `json:"output_price_per_m_token"`
 }
@@ -33,3 +41,15 @@ type ModelListResponse struct {
 	Provider string        `json:"provider"`
 	Models   []ModelConfig `json:"models"`
 }
+
+// CustomProviderRequest is the request model for registering or updating a custom OpenAI-compatible provider
+type CustomProviderRequest struct {
+	Name               string        `json:"name" binding:"required"`
+	DisplayName        string        `json:"display_name" binding:"required,max=100"`
+	BaseURL            string        `json:"base_url" binding:"required,url"`
+	ChatCompletionPath string        `json:"chat_completion_path" binding:"omitempty"`
+	HealthCheckPath    string        `json:"health_check_path" binding:"omitempty"`
+	EmbeddingPath      string        `json:"embedding_path" binding:"omitempty"`
+	RequiresAPIKey     *bool         `json:"requires_api_key"`
+	Models             []ModelConfig `json:"models" binding:"omitempty,dive"`
+}
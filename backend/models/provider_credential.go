@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// ProviderCredential is a provider API key shared by agents, which reference it by ID instead
+// of carrying a key of their own. Credentials without a project are organization-wide and
+// usable by agents of every project.
+type ProviderCredential struct {
+	ID        int    `json:"id"`
+	ProjectID *int   `json:"project_id"`
+	Name      string `json:"name"`
+	Provider  string `json:"provider"`
+	// APIKey holds the encrypted key as stored; it is only decrypted when calling the provider
+	APIKey          string     `json:"-"`
+	APIKeyVersion   int        `json:"-"`
+	APIKeyHint      string     `json:"-"`
+	APIKeyMasked    string     `json:"api_key_masked"`
+	LastTestedAt    *time.Time `json:"last_tested_at,omitempty"`
+	LastTestHealthy *bool      `json:"last_test_healthy,omitempty"`
+	CreatedBy       int        `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// CreateProviderCredentialRequest is the request model for creating a credential
+type CreateProviderCredentialRequest struct {
+	Name     string `json:"name" binding:"required,min=3,max=100"`
+	Provider string `json:"provider" binding:"required"`
+	APIKey   string `json:"api_key" binding:"required,min=8,max=500"`
+}
+
+// UpdateProviderCredentialRequest is the request model for renaming a credential or rotating its key
+type UpdateProviderCredentialRequest struct {
+	Name   *string `json:"name" binding:"omitempty,min=3,max=100"`
+	APIKey *string `json:"api_key" binding:"omitempty,min=8,max=500"`
+}
+
+// TestProviderCredentialRequest picks the model a credential is tested against;
+// the provider's first chat model is used when it is empty
+type TestProviderCredentialRequest struct {
+	Model string `json:"model"`
+}
+
+// ProviderCredentialListResponse is the response model for listing credentials
+type ProviderCredentialListResponse struct {
+	Credentials []ProviderCredential `json:"credentials"`
+	TotalCount  int                  `json:"total_count"`
+}
+
+// ProviderCredentialTestResponse is the result of calling the provider with a credential
+type ProviderCredentialTestResponse struct {
+	CredentialID int    `json:"credential_id"`
+	Provider     string `json:"provider"`
+	Model        string `json:"model"`
+	Healthy      bool   `json:"healthy"`
+	Message      string `json:"message"`
+	TestResponse string `json:"test_response,omitempty"`
+	LatencyMs    int64  `json:"latency_ms"`
+}
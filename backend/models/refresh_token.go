@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RefreshToken is the server-side record of an issued refresh token.
+// Tokens issued from the same login share a FamilyID so that reuse of a
+// rotated token can revoke the whole chain.
+type RefreshToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	TokenID    string     `json:"token_id"`
+	FamilyID   string     `json:"family_id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy *string    `json:"replaced_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// LogoutRequest optionally carries the refresh token of the session to end.
+// Without it, every session of the user is revoked.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"omitempty"`
+}
@@ -6,6 +6,7 @@ import "time"
 const (
 	TaskStatusOpen       = "open"
 	TaskStatusInProgress = "in_progress"
+	TaskStatusInReview   = "in_review"
 	TaskStatusDone       = "done"
 	TaskStatusClosed     = "closed"
 	TaskStatusWontDo     = "wont_do"
@@ -19,6 +20,21 @@ const (
 	TaskPriorityCritical = "critical"
 )
 
+// TaskPriorityRank returns the urgency of a task priority (0 for unknown priorities)
+func TaskPriorityRank(priority string) int {
+	switch priority {
+	case TaskPriorityLow:
+		return 1
+	case TaskPriorityMedium:
+		return 2
+	case TaskPriorityHigh:
+		return 3
+	case TaskPriorityCritical:
+		return 4
+	}
+	return 0
+}
+
 // Creator type constants
 const (
 	CreatorTypeUser  = "user"
@@ -33,6 +49,7 @@ const (
 	TaskActionReviewerSet   = "reviewer_set"
 	TaskActionCommented     = "commented"
 	TaskActionProgress      = "progress"
+	TaskActionAttached      = "attached"
 )
 
 // Task represents a task in the system
@@ -125,6 +142,8 @@ type TaskFilters struct {
 	Priority        *string `form:"priority"`
 	AssignedAgentID *int    `form:"assigned_agent_id"`
 	ReviewerID      *int    `form:"reviewer_id"`
+	// MemberUserID limits results to projects the user is a member of (set server-side)
+	MemberUserID *int `form:"-"`
 }
 
 // TaskListResponse is the response model for listing tasks
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// MaxAttachmentBytes caps the size of a single task attachment
+const MaxAttachmentBytes = 5 << 20
+
+// AttachmentContentTypes are the image types tasks accept as attachments, which are also
+// the types every vision-capable provider accepts
+var AttachmentContentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// TaskAttachment is an image attached to a task. Data is only loaded when the image is
+// downloaded or sent to a model.
+type TaskAttachment struct {
+	ID           int       `json:"id"`
+	TaskID       int       `json:"task_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int       `json:"size_bytes"`
+	Data         []byte    `json:"-"`
+	UploadedBy   int       `json:"uploaded_by"`
+	UploaderType string    `json:"uploader_type"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TaskAttachmentListResponse is the response model for listing task attachments
+type TaskAttachmentListResponse struct {
+	Attachments []TaskAttachment `json:"attachments"`
+	TotalCount  int              `json:"total_count"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// LLM usage statuses
+const (
+	UsageStatusSuccess = "success"
+	UsageStatusError   = "error"
+)
+
+// Ledgered provider operations
+const (
+	UsageOperationChat      = "chat"
+	UsageOperationEmbedding = "embedding"
+	// Test messages sent by agent health checks
+	UsageOperationHealthCheck = "health_check"
+)
+
+// Usage report groupings
+const (
+	UsageGroupByDay       = "day"
+	UsageGroupByAgent     = "agent"
+	UsageGroupByModel     = "model"
+	UsageGroupByTask      = "task"
+	UsageGroupByOperation = "operation"
+	// Compares the spending and error rate of prompt template versions
+	UsageGroupByPromptTemplate = "prompt_template"
+	// Splits traffic by the provider key that served it
+	UsageGroupByAPIKey = "api_key"
+)
+
+// LLMUsage is one provider call recorded in the usage ledger.
+// AgentID is zero for calls billed to a project.
+type LLMUsage struct {
+	ID           int64 `json:"id"`
+	AgentID      int   `json:"agent_id,omitempty"`
+	ProjectID    int   `json:"project_id"`
+	TaskID       *int  `json:"task_id,omitempty"`
+	AssignmentID *int  `json:"assignment_id,omitempty"`
+	// PromptTemplateID is the template version the call's system prompt was rendered from
+	PromptTemplateID *int `json:"prompt_template_id,omitempty"`
+	// APIKeyID is the agent pool key that served the call; APIKeyMasked is set for every key
+	APIKeyID     *int      `json:"api_key_id,omitempty"`
+	APIKeyMasked string    `json:"api_key_masked,omitempty"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Operation    string    `json:"operation"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	TotalTokens  int       `json:"total_tokens"`
+	Cost         float64   `json:"cost"`
+	LatencyMs    int64     `json:"latency_ms"`
+	Status       string    `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UsageQuery filters and groups the usage ledger
+type UsageQuery struct {
+	GroupBy   string     `form:"group_by" binding:"omitempty,oneof=day agent model task operation prompt_template api_key"`
+	From      *time.Time `form:"from" time_format:"2006-01-02"`
+	To        *time.Time `form:"to" time_format:"2006-01-02"`
+	AgentID   *int       `form:"agent_id"`
+	TaskID    *int       `form:"task_id"`
+	Model     *string    `form:"model"`
+	Operation *string    `form:"operation" binding:"omitempty,oneof=chat embedding health_check"`
+	ProjectID *int       `form:"-"` // set from the route
+}
+
+// UsageBucket aggregates ledger rows sharing a group key
+type UsageBucket struct {
+	Key          string  `json:"key"`
+	Label        string  `json:"label,omitempty"`
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	Cost         float64 `json:"cost"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// UsageReportResponse is the response model for usage aggregations
+type UsageReportResponse struct {
+	GroupBy string        `json:"group_by"`
+	Buckets []UsageBucket `json:"buckets"`
+	Totals  UsageBucket   `json:"totals"`
+}
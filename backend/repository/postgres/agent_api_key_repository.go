@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AgentAPIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAgentAPIKeyRepository(pool *pgxpool.Pool) *AgentAPIKeyRepository {
+	return &AgentAPIKeyRepository{
+		pool: pool,
+	}
+}
+
+const agentAPIKeyColumns = `id, agent_id, credential_id, provider, label, api_key, api_key_version, api_key_hint, is_active,
+	total_requests, total_tokens_used, total_cost, rate_limited_count, cooldown_until, last_used_at, created_by, created_at`
+
+func scanAgentAPIKey(row pgx.Row) (*models.AgentAPIKey, error) {
+	var k models.AgentAPIKey
+	err := row.Scan(
+		&k.ID, &k.AgentID, &k.CredentialID, &k.Provider, &k.Label, &k.APIKey, &k.APIKeyVersion, &k.APIKeyHint, &k.IsActive,
+		&k.TotalRequests, &k.TotalTokensUsed, &k.TotalCost, &k.RateLimitedCount, &k.CooldownUntil, &k.LastUsedAt,
+		&k.CreatedBy, &k.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Create adds a key to an agent's or a credential's pool
+func (r *AgentAPIKeyRepository) Create(ctx context.Context, key *models.AgentAPIKey) error {
+	query := `INSERT INTO agent_api_keys (agent_id, credential_id, provider, label, api_key, api_key_version, api_key_hint, is_active, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	          RETURNING id, created_at`
+
+	return r.pool.QueryRow(ctx, query,
+		key.AgentID, key.CredentialID, key.Provider, key.Label, key.APIKey, key.APIKeyVersion, key.APIKeyHint, key.IsActive, key.CreatedBy,
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetByID retrieves one of an agent's pool keys
+func (r *AgentAPIKeyRepository) GetByID(ctx context.Context, agentID, id int) (*models.AgentAPIKey, error) {
+	query := `SELECT ` + agentAPIKeyColumns + ` FROM agent_api_keys WHERE id = $1 AND agent_id = $2`
+	return scanAgentAPIKey(r.pool.QueryRow(ctx, query, id, agentID))
+}
+
+// GetByAgentID lists an agent's pool keys, oldest first
+func (r *AgentAPIKeyRepository) GetByAgentID(ctx context.Context, agentID int) ([]models.AgentAPIKey, error) {
+	return r.list(ctx, `SELECT `+agentAPIKeyColumns+` FROM agent_api_keys WHERE agent_id = $1 ORDER BY id`, agentID)
+}
+
+// GetByCredentialIDAndID retrieves one of a credential's pool keys
+func (r *AgentAPIKeyRepository) GetByCredentialIDAndID(ctx context.Context, credentialID, id int) (*models.AgentAPIKey, error) {
+	query := `SELECT ` + agentAPIKeyColumns + ` FROM agent_api_keys WHERE id = $1 AND credential_id = $2`
+	return scanAgentAPIKey(r.pool.QueryRow(ctx, query, id, credentialID))
+}
+
+// GetByCredentialID lists a credential's pool keys, oldest first
+func (r *AgentAPIKeyRepository) GetByCredentialID(ctx context.Context, credentialID int) ([]models.AgentAPIKey, error) {
+	return r.list(ctx, `SELECT `+agentAPIKeyColumns+` FROM agent_api_keys WHERE credential_id = $1 ORDER BY id`, credentialID)
+}
+
+// GetActiveForCredential lists the active pool keys of a credential, oldest first
+func (r *AgentAPIKeyRepository) GetActiveForCredential(ctx context.Context, credentialID int) ([]models.AgentAPIKey, error) {
+	return r.list(ctx, `SELECT `+agentAPIKeyColumns+` FROM agent_api_keys
+	                    WHERE credential_id = $1 AND is_active ORDER BY id`, credentialID)
+}
+
+// GetActive lists the active pool keys an agent has for a provider, oldest first
+func (r *AgentAPIKeyRepository) GetActive(ctx context.Context, agentID int, provider string) ([]models.AgentAPIKey, error) {
+	return r.list(ctx, `SELECT `+agentAPIKeyColumns+` FROM agent_api_keys
+	                    WHERE agent_id = $1 AND provider = $2 AND is_active ORDER BY id`, agentID, provider)
+}
+
+// GetKeysNotAtVersion lists pool keys encrypted under another master key version
+func (r *AgentAPIKeyRepository) GetKeysNotAtVersion(ctx context.Context, version int) ([]models.AgentAPIKey, error) {
+	return r.list(ctx, `SELECT `+agentAPIKeyColumns+` FROM agent_api_keys WHERE api_key_version <> $1 ORDER BY id`, version)
+}
+
+func (r *AgentAPIKeyRepository) list(ctx context.Context, query string, args ...any) ([]models.AgentAPIKey, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.AgentAPIKey{}
+	for rows.Next() {
+		key, err := scanAgentAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Update changes the label and active flag of a pool key
+func (r *AgentAPIKeyRepository) Update(ctx context.Context, key *models.AgentAPIKey) error {
+	query := `UPDATE agent_api_keys SET label = $1, is_active = $2
+	          WHERE id = $3 AND agent_id IS NOT DISTINCT FROM $4 AND credential_id IS NOT DISTINCT FROM $5`
+	_, err := r.pool.Exec(ctx, query, key.Label, key.IsActive, key.ID, key.AgentID, key.CredentialID)
+	return err
+}
+
+// UpdateAPIKey replaces the stored ciphertext if the key is still at fromVersion
+func (r *AgentAPIKeyRepository) UpdateAPIKey(ctx context.Context, id int, fromVersion int, apiKey string, version int, hint string) (bool, error) {
+	query := `UPDATE agent_api_keys SET api_key = $1, api_key_version = $2, api_key_hint = $3
+	          WHERE id = $4 AND api_key_version = $5`
+
+	tag, err := r.pool.Exec(ctx, query, apiKey, version, hint, id, fromVersion)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkRateLimited counts a rate limit on a key and records when its cooldown ends
+func (r *AgentAPIKeyRepository) MarkRateLimited(ctx context.Context, id int, cooldownUntil time.Time) error {
+	query := `UPDATE agent_api_keys
+	          SET rate_limited_count = rate_limited_count + 1,
+	              cooldown_until = GREATEST(COALESCE(cooldown_until, $2), $2)
+	          WHERE id = $1`
+
+	_, err := r.pool.Exec(ctx, query, id, cooldownUntil)
+	return err
+}
+
+// Delete removes a key from an agent's pool
+func (r *AgentAPIKeyRepository) Delete(ctx context.Context, agentID, id int) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM agent_api_keys WHERE id = $1 AND agent_id = $2`, id, agentID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteForCredential removes a key from a credential's pool
+func (r *AgentAPIKeyRepository) DeleteForCredential(ctx context.Context, credentialID, id int) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM agent_api_keys WHERE id = $1 AND credential_id = $2`, id, credentialID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AgentEventRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAgentEventRepository(pool *pgxpool.Pool) *AgentEventRepository {
+	return &AgentEventRepository{
+		pool: pool,
+	}
+}
+
+// Create records an agent event
+func (r *AgentEventRepository) Create(ctx context.Context, event *models.AgentEvent) error {
+	var dataJSON []byte
+	if event.Data != nil {
+		var err error
+		dataJSON, err = json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `INSERT INTO agent_events (agent_id, event_type, message, data)
+	          VALUES ($1, $2, $3, $4)
+	          RETURNING id, created_at`
+
+	return r.pool.QueryRow(ctx, query,
+		event.AgentID, event.EventType, event.Message, dataJSON,
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+// GetByAgentID retrieves an agent's most recent events, newest first
+func (r *AgentEventRepository) GetByAgentID(ctx context.Context, agentID int, limit int) ([]models.AgentEvent, error) {
+	query := `SELECT id, agent_id, event_type, message, data, created_at
+	          FROM agent_events WHERE agent_id = $1
+	          ORDER BY created_at DESC, id DESC
+	          LIMIT $2`
+
+	rows, err := r.pool.Query(ctx, query, agentID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AgentEvent{}
+	for rows.Next() {
+		var event models.AgentEvent
+		var dataJSON []byte
+		if err := rows.Scan(&event.ID, &event.AgentID, &event.EventType, &event.Message, &dataJSON, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if dataJSON != nil {
+			_ = json.Unmarshal(dataJSON, &event.Data)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AgentHealthCheckRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAgentHealthCheckRepository(pool *pgxpool.Pool) *AgentHealthCheckRepository {
+	return &AgentHealthCheckRepository{
+		pool: pool,
+	}
+}
+
+// Create records a health check and returns the agent's consecutive failures including it
+func (r *AgentHealthCheckRepository) Create(ctx context.Context, check *models.AgentHealthCheck) (int, error) {
+	query := `INSERT INTO agent_health_checks (agent_id, provider, model, healthy, latency_ms, error, source)
+	          VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+	          RETURNING id, checked_at`
+
+	err := r.pool.QueryRow(ctx, query,
+		check.AgentID, check.Provider, check.Model, check.Healthy, check.LatencyMs, check.Error, check.Source,
+	).Scan(&check.ID, &check.CheckedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.ConsecutiveFailures(ctx, check.AgentID)
+}
+
+// ConsecutiveFailures counts the failed checks since the agent's last successful one
+func (r *AgentHealthCheckRepository) ConsecutiveFailures(ctx context.Context, agentID int) (int, error) {
+	query := `SELECT COUNT(*) FROM agent_health_checks
+	          WHERE agent_id = $1 AND NOT healthy
+	            AND id > COALESCE((SELECT MAX(id) FROM agent_health_checks WHERE agent_id = $1 AND healthy), 0)`
+
+	var count int
+	err := r.pool.QueryRow(ctx, query, agentID).Scan(&count)
+	return count, err
+}
+
+// GetByAgentID retrieves an agent's most recent health checks, newest first
+func (r *AgentHealthCheckRepository) GetByAgentID(ctx context.Context, agentID int, limit int) ([]models.AgentHealthCheck, error) {
+	query := `SELECT id, agent_id, provider, model, healthy, latency_ms, COALESCE(error, ''), source, checked_at
+	          FROM agent_health_checks WHERE agent_id = $1
+	          ORDER BY id DESC
+	          LIMIT $2`
+
+	rows, err := r.pool.Query(ctx, query, agentID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checks := []models.AgentHealthCheck{}
+	for rows.Next() {
+		var check models.AgentHealthCheck
+		if err := rows.Scan(&check.ID, &check.AgentID, &check.Provider, &check.Model, &check.Healthy,
+			&check.LatencyMs, &check.Error, &check.Source, &check.CheckedAt); err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+
+	return checks, rows.Err()
+}
+
+// DeleteOlderThan removes health checks recorded before the cutoff
+func (r *AgentHealthCheckRepository) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM agent_health_checks WHERE checked_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
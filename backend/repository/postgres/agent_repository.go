@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -19,35 +20,62 @@ func NewAgentRepository(pool *pgxpool.Pool) *AgentRepository {
 	}
 }
 
-func (r *AgentRepository) Create(ctx context.Context, agent *models.Agent) error {
-	configJSON, err := json.Marshal(agent.Config)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+// agentColumns is the column list scanned by scanAgent
+const agentColumns = `id, name, description, project_id, created_by, role, level, provider, model,
+	api_key, api_key_version, api_key_hint, credential_id, config, fallback_models, status, is_active, last_active_at,
+	total_tokens_used, total_cost, total_requests, created_at, updated_at`
+
+// storedFallback is the stored form of a fallback model, including its encrypted key
+type storedFallback struct {
+	Provider      string `json:"provider"`
+	Model         string `json:"model"`
+	APIKey        string `json:"api_key,omitempty"`
+	APIKeyVersion int    `json:"api_key_version,omitempty"`
+	APIKeyHint    string `json:"api_key_hint,omitempty"`
+}
+
+func marshalFallbacks(fallbacks []models.FallbackModel) ([]byte, error) {
+	stored := make([]storedFallback, 0, len(fallbacks))
+	for _, f := range fallbacks {
+		stored = append(stored, storedFallback{
+			Provider:      f.Provider,
+			Model:         f.Model,
+			APIKey:        f.APIKey,
+			APIKeyVersion: f.APIKeyVersion,
+			APIKeyHint:    f.APIKeyHint,
+		})
 	}
+	return json.Marshal(stored)
+}
 
-	query := `INSERT INTO agents (name, description, project_id, created_by, role, level, provider, model, api_key, config, status, is_active)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	          RETURNING id, created_at, updated_at`
+func unmarshalFallbacks(data []byte) ([]models.FallbackModel, error) {
+	var stored []storedFallback
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
 
-	return r.pool.QueryRow(ctx, query,
-		agent.Name, agent.Description, agent.ProjectID, agent.CreatedBy,
-		agent.Role, agent.Level, agent.Provider, agent.Model, agent.APIKey,
-		configJSON, agent.Status, agent.IsActive,
-	).Scan(&agent.ID, &agent.CreatedAt, &agent.UpdatedAt)
+	fallbacks := make([]models.FallbackModel, 0, len(stored))
+	for _, s := range stored {
+		fallbacks = append(fallbacks, models.FallbackModel{
+			Provider:      s.Provider,
+			Model:         s.Model,
+			APIKey:        s.APIKey,
+			APIKeyVersion: s.APIKeyVersion,
+			APIKeyHint:    s.APIKeyHint,
+		})
+	}
+	return fallbacks, nil
 }
 
-func (r *AgentRepository) GetByID(ctx context.Context, id int) (*models.Agent, error) {
-	query := `SELECT id, name, description, project_id, created_by, role, level, provider, model, api_key, config,
-	          status, is_active, last_active_at, total_tokens_used, total_cost, total_requests, created_at, updated_at
-	          FROM agents WHERE id = $1`
-
+func scanAgent(row pgx.Row) (*models.Agent, error) {
 	var agent models.Agent
-	var configJSON []byte
+	var configJSON, fallbacksJSON []byte
 
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := row.Scan(
 		&agent.ID, &agent.Name, &agent.Description, &agent.ProjectID, &agent.CreatedBy,
-		&agent.Role, &agent.Level, &agent.Provider, &agent.Model, &agent.APIKey,
-		&configJSON, &agent.Status, &agent.IsActive, &agent.LastActiveAt,
+		&agent.Role, &agent.Level, &agent.Provider, &agent.Model,
+		&agent.APIKey, &agent.APIKeyVersion, &agent.APIKeyHint, &agent.CredentialID,
+		&configJSON, &fallbacksJSON, &agent.Status, &agent.IsActive, &agent.LastActiveAt,
 		&agent.TotalTokensUsed, &agent.TotalCost, &agent.TotalRequests,
 		&agent.CreatedAt, &agent.UpdatedAt,
 	)
@@ -58,17 +86,15 @@ func (r *AgentRepository) GetByID(ctx context.Context, id int) (*models.Agent, e
 	if err := json.Unmarshal(configJSON, &agent.Config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if agent.FallbackModels, err = unmarshalFallbacks(fallbacksJSON); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fallback models: %w", err)
+	}
 
 	return &agent, nil
 }
 
-func (r *AgentRepository) GetAll(ctx context.Context) ([]models.Agent, error) {
-	query := `SELECT id, name, description, project_id, created_by, role, level, provider, model, api_key, config,
-	          status, is_active, last_active_at, total_tokens_used, total_cost, total_requests, created_at, updated_at
-	          FROM agents
-	          ORDER BY created_at DESC`
-
-	rows, err := r.pool.Query(ctx, query)
+func (r *AgentRepository) queryAgents(ctx context.Context, query string, args ...interface{}) ([]models.Agent, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -76,25 +102,11 @@ func (r *AgentRepository) GetAll(ctx context.Context) ([]models.Agent, error) {
 
 	var agents []models.Agent
 	for rows.Next() {
-		var agent models.Agent
-		var configJSON []byte
-
-		err := rows.Scan(
-			&agent.ID, &agent.Name, &agent.Description, &agent.ProjectID, &agent.CreatedBy,
-			&agent.Role, &agent.Level, &agent.Provider, &agent.Model, &agent.APIKey,
-			&configJSON, &agent.Status, &agent.IsActive, &agent.LastActiveAt,
-			&agent.TotalTokensUsed, &agent.TotalCost, &agent.TotalRequests,
-			&agent.CreatedAt, &agent.UpdatedAt,
-		)
+		agent, err := scanAgent(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if err := json.Unmarshal(configJSON, &agent.Config); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal config: %w", err)
-		}
-
-		agents = append(agents, agent)
+		agents = append(agents, *agent)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -104,47 +116,84 @@ func (r *AgentRepository) GetAll(ctx context.Context) ([]models.Agent, error) {
 	return agents, nil
 }
 
-func (r *AgentRepository) GetByProjectID(ctx context.Context, projectID int) ([]models.Agent, error) {
-	query := `SELECT id, name, description, project_id, created_by, role, level, provider, model, api_key, config,
-	          status, is_active, last_active_at, total_tokens_used, total_cost, total_requests, created_at, updated_at
-	          FROM agents
-	          WHERE project_id = $1
-	          ORDER BY created_at DESC`
-
-	rows, err := r.pool.Query(ctx, query, projectID)
+func (r *AgentRepository) Create(ctx context.Context, agent *models.Agent) error {
+	configJSON, err := json.Marshal(agent.Config)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	fallbacksJSON, err := marshalFallbacks(agent.FallbackModels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fallback models: %w", err)
 	}
-	defer rows.Close()
 
-	var agents []models.Agent
-	for rows.Next() {
-		var agent models.Agent
-		var configJSON []byte
-
-		err := rows.Scan(
-			&agent.ID, &agent.Name, &agent.Description, &agent.ProjectID, &agent.CreatedBy,
-			&agent.Role, &agent.Level, &agent.Provider, &agent.Model, &agent.APIKey,
-			&configJSON, &agent.Status, &agent.IsActive, &agent.LastActiveAt,
-			&agent.TotalTokensUsed, &agent.TotalCost, &agent.TotalRequests,
-			&agent.CreatedAt, &agent.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
+	query := `INSERT INTO agents (name, description, project_id, created_by, role, level, provider, model,
+	          api_key, api_key_version, api_key_hint, credential_id, config, fallback_models, status, is_active)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	          RETURNING id, created_at, updated_at`
 
-		if err := json.Unmarshal(configJSON, &agent.Config); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal config: %w", err)
-		}
+	return r.pool.QueryRow(ctx, query,
+		agent.Name, agent.Description, agent.ProjectID, agent.CreatedBy,
+		agent.Role, agent.Level, agent.Provider, agent.Model,
+		agent.APIKey, agent.APIKeyVersion, agent.APIKeyHint, agent.CredentialID,
+		configJSON, fallbacksJSON, agent.Status, agent.IsActive,
+	).Scan(&agent.ID, &agent.CreatedAt, &agent.UpdatedAt)
+}
 
-		agents = append(agents, agent)
-	}
+func (r *AgentRepository) GetByID(ctx context.Context, id int) (*models.Agent, error) {
+	query := `SELECT ` + agentColumns + ` FROM agents WHERE id = $1`
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
+	return scanAgent(r.pool.QueryRow(ctx, query, id))
+}
 
-	return agents, nil
+func (r *AgentRepository) GetAll(ctx context.Context) ([]models.Agent, error) {
+	query := `SELECT ` + agentColumns + ` FROM agents ORDER BY created_at DESC`
+
+	return r.queryAgents(ctx, query)
+}
+
+// GetAllForUser retrieves the agents of every project a user is a member of
+func (r *AgentRepository) GetAllForUser(ctx context.Context, userID int) ([]models.Agent, error) {
+	query := `SELECT ` + agentColumns + ` FROM agents
+	          WHERE project_id IN (SELECT project_id FROM project_members WHERE user_id = $1)
+	          ORDER BY created_at DESC`
+
+	return r.queryAgents(ctx, query, userID)
+}
+
+func (r *AgentRepository) GetByProjectID(ctx context.Context, projectID int) ([]models.Agent, error) {
+	query := `SELECT ` + agentColumns + ` FROM agents WHERE project_id = $1 ORDER BY created_at DESC`
+
+	return r.queryAgents(ctx, query, projectID)
+}
+
+// GetHealthCheckable retrieves active agents that are not disabled
+func (r *AgentRepository) GetHealthCheckable(ctx context.Context) ([]models.Agent, error) {
+	query := `SELECT ` + agentColumns + ` FROM agents WHERE is_active = true AND status <> 'disabled' ORDER BY id`
+
+	return r.queryAgents(ctx, query)
+}
+
+// GetKeysNotAtVersion returns agents whose API key or a fallback key was not encrypted with the given key version
+func (r *AgentRepository) GetKeysNotAtVersion(ctx context.Context, version int) ([]models.Agent, error) {
+	query := `SELECT ` + agentColumns + ` FROM agents
+	          WHERE api_key_version <> $1
+	             OR EXISTS (SELECT 1 FROM jsonb_array_elements(fallback_models) f
+	                        WHERE f->>'api_key' IS NOT NULL AND COALESCE((f->>'api_key_version')::int, 0) <> $1)
+	          ORDER BY id`
+
+	return r.queryAgents(ctx, query, version)
+}
+
+// UpdateAPIKey replaces an agent's stored API key, guarded by the version it was read at
+func (r *AgentRepository) UpdateAPIKey(ctx context.Context, id int, fromVersion int, apiKey string, version int, hint string) (bool, error) {
+	query := `UPDATE agents SET api_key = $1, api_key_version = $2, api_key_hint = $3
+	          WHERE id = $4 AND api_key_version = $5`
+
+	result, err := r.pool.Exec(ctx, query, apiKey, version, hint, id, fromVersion)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
 }
 
 func (r *AgentRepository) UpdatePartial(ctx context.Context, id int, updates map[string]interface{}) (*models.Agent, error) {
@@ -160,6 +209,13 @@ func (r *AgentRepository) UpdatePartial(ctx context.Context, id int, updates map
 		}
 		updates["config"] = configJSON
 	}
+	if fallbacks, ok := updates["fallback_models"].([]models.FallbackModel); ok {
+		fallbacksJSON, err := marshalFallbacks(fallbacks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal fallback models: %w", err)
+		}
+		updates["fallback_models"] = fallbacksJSON
+	}
 
 	query := "UPDATE agents SET "
 	args := make([]interface{}, 0, len(updates)+1)
@@ -194,6 +250,13 @@ func (r *AgentRepository) Delete(ctx context.Context, id int) error {
 	return err
 }
 
+// CountByProvider returns how many agents use a provider
+func (r *AgentRepository) CountByProvider(ctx context.Context, provider string) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM agents WHERE provider = $1`, provider).Scan(&count)
+	return count, err
+}
+
 func (r *AgentRepository) UpdateStatus(ctx context.Context, id int, status string) error {
 	query := `UPDATE agents SET status = $1, last_active_at = NOW(), updated_at = NOW() WHERE id = $2`
 
@@ -201,17 +264,15 @@ func (r *AgentRepository) UpdateStatus(ctx context.Context, id int, status strin
 	return err
 }
 
-func (r *AgentRepository) IncrementUsage(ctx context.Context, id int, tokensUsed int64, cost float64) error {
-	query := `UPDATE agents
-	          SET total_tokens_used = total_tokens_used + $1,
-	              total_cost = total_cost + $2,
-	              total_requests = total_requests + 1,
-	              last_active_at = NOW(),
-	              updated_at = NOW()
-	          WHERE id = $3`
+// UpdateStatusFrom changes an agent's status only if it still is from, reporting whether it did
+func (r *AgentRepository) UpdateStatusFrom(ctx context.Context, id int, from, status string) (bool, error) {
+	query := `UPDATE agents SET status = $1, last_active_at = NOW(), updated_at = NOW() WHERE id = $2 AND status = $3`
 
-	_, err := r.pool.Exec(ctx, query, tokensUsed, cost, id)
-	return err
+	tag, err := r.pool.Exec(ctx, query, status, id, from)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
 }
 
 func (r *AgentRepository) GetStatus(ctx context.Context, id int) (*models.AgentStatusResponse, error) {
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AgentTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAgentTokenRepository(pool *pgxpool.Pool) *AgentTokenRepository {
+	return &AgentTokenRepository{
+		pool: pool,
+	}
+}
+
+// Create stores a new agent token (only its hash is persisted)
+func (r *AgentTokenRepository) Create(ctx context.Context, token *models.AgentToken) error {
+	query := `INSERT INTO agent_tokens (agent_id, name, token_prefix, token_hash, created_by, expires_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING id, created_at`
+
+	return r.pool.QueryRow(ctx, query,
+		token.AgentID, token.Name, token.TokenPrefix, token.TokenHash, token.CreatedBy, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetByHash retrieves a token by the hash of its plaintext value
+func (r *AgentTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.AgentToken, error) {
+	query := `SELECT id, agent_id, name, token_prefix, token_hash, created_by, last_used_at, expires_at, revoked_at, created_at
+	          FROM agent_tokens WHERE token_hash = $1`
+
+	var token models.AgentToken
+	err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID, &token.AgentID, &token.Name, &token.TokenPrefix, &token.TokenHash,
+		&token.CreatedBy, &token.LastUsedAt, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetByAgentID retrieves all tokens issued for an agent
+func (r *AgentTokenRepository) GetByAgentID(ctx context.Context, agentID int) ([]models.AgentToken, error) {
+	query := `SELECT id, agent_id, name, token_prefix, token_hash, created_by, last_used_at, expires_at, revoked_at, created_at
+	          FROM agent_tokens
+	          WHERE agent_id = $1
+	          ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.AgentToken
+	for rows.Next() {
+		var token models.AgentToken
+		err := rows.Scan(
+			&token.ID, &token.AgentID, &token.Name, &token.TokenPrefix, &token.TokenHash,
+			&token.CreatedBy, &token.LastUsedAt, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Revoke revokes a token of an agent, returning false if no active token matched
+func (r *AgentTokenRepository) Revoke(ctx context.Context, agentID int, tokenID int) (bool, error) {
+	query := `UPDATE agent_tokens
+	          SET revoked_at = NOW()
+	          WHERE id = $1 AND agent_id = $2 AND revoked_at IS NULL`
+
+	tag, err := r.pool.Exec(ctx, query, tokenID, agentID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// TouchLastUsed records that a token was just used
+func (r *AgentTokenRepository) TouchLastUsed(ctx context.Context, id int) error {
+	query := `UPDATE agent_tokens SET last_used_at = NOW() WHERE id = $1`
+
+	_, err := r.pool.Exec(ctx, query, id)
+	return err
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const budgetColumns = `id, agent_id, project_id, period, max_tokens, max_cost::float8, COALESCE(created_by, 0), created_at, updated_at`
+
+type BudgetRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewBudgetRepository(pool *pgxpool.Pool) *BudgetRepository {
+	return &BudgetRepository{
+		pool: pool,
+	}
+}
+
+// UpsertForAgent creates or replaces an agent's budget for the period
+func (r *BudgetRepository) UpsertForAgent(ctx context.Context, budget *models.Budget) error {
+	query := `INSERT INTO budgets (agent_id, period, max_tokens, max_cost, created_by)
+	          VALUES ($1, $2, $3, $4, $5)
+	          ON CONFLICT (agent_id, period) WHERE agent_id IS NOT NULL
+	          DO UPDATE SET max_tokens = EXCLUDED.max_tokens, max_cost = EXCLUDED.max_cost, updated_at = NOW()
+	          RETURNING id, created_at, updated_at`
+
+	return r.pool.QueryRow(ctx, query,
+		budget.AgentID, budget.Period, budget.MaxTokens, budget.MaxCost, budget.CreatedBy,
+	).Scan(&budget.ID, &budget.CreatedAt, &budget.UpdatedAt)
+}
+
+// UpsertForProject creates or replaces a project's budget for the period
+func (r *BudgetRepository) UpsertForProject(ctx context.Context, budget *models.Budget) error {
+	query := `INSERT INTO budgets (project_id, period, max_tokens, max_cost, created_by)
+	          VALUES ($1, $2, $3, $4, $5)
+	          ON CONFLICT (project_id, period) WHERE project_id IS NOT NULL
+	          DO UPDATE SET max_tokens = EXCLUDED.max_tokens, max_cost = EXCLUDED.max_cost, updated_at = NOW()
+	          RETURNING id, created_at, updated_at`
+
+	return r.pool.QueryRow(ctx, query,
+		budget.ProjectID, budget.Period, budget.MaxTokens, budget.MaxCost, budget.CreatedBy,
+	).Scan(&budget.ID, &budget.CreatedAt, &budget.UpdatedAt)
+}
+
+// GetForAgent retrieves the agent's own budgets and those of its project
+func (r *BudgetRepository) GetForAgent(ctx context.Context, agentID int, projectID int) ([]models.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets
+	          WHERE agent_id = $1 OR project_id = $2
+	          ORDER BY agent_id NULLS LAST, period`
+
+	return r.query(ctx, query, agentID, projectID)
+}
+
+// GetForProject retrieves the budgets set on a project
+func (r *BudgetRepository) GetForProject(ctx context.Context, projectID int) ([]models.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE project_id = $1 ORDER BY period`
+
+	return r.query(ctx, query, projectID)
+}
+
+// DeleteForAgent removes an agent's budget for the period
+func (r *BudgetRepository) DeleteForAgent(ctx context.Context, agentID int, period string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM budgets WHERE agent_id = $1 AND period = $2`, agentID, period)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteForProject removes a project's budget for the period
+func (r *BudgetRepository) DeleteForProject(ctx context.Context, projectID int, period string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM budgets WHERE project_id = $1 AND period = $2`, projectID, period)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *BudgetRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.Budget, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []models.Budget{}
+	for rows.Next() {
+		var b models.Budget
+		if err := rows.Scan(&b.ID, &b.AgentID, &b.ProjectID, &b.Period, &b.MaxTokens, &b.MaxCost,
+			&b.CreatedBy, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+
+	return budgets, rows.Err()
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const customProviderColumns = `name, display_name, base_url, chat_completion_path, health_check_path, requires_api_key, models, embedding_path`
+
+type CustomProviderRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCustomProviderRepository(pool *pgxpool.Pool) *CustomProviderRepository {
+	return &CustomProviderRepository{
+		pool: pool,
+	}
+}
+
+// scanCustomProvider scans a row selected with customProviderColumns
+func scanCustomProvider(row pgx.Row) (*models.ProviderConfig, error) {
+	var provider models.ProviderConfig
+	var modelsJSON []byte
+
+	err := row.Scan(
+		&provider.Name, &provider.DisplayName, &provider.BaseURL, &provider.ChatCompletionPath,
+		&provider.HealthCheckPath, &provider.RequiresAPIKey, &modelsJSON, &provider.EmbeddingPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(modelsJSON, &provider.Models); err != nil {
+		return nil, fmt.Errorf("failed to decode provider models: %w", err)
+	}
+	provider.APIFormat = "openai"
+	provider.Custom = true
+
+	return &provider, nil
+}
+
+// Create registers a custom provider
+func (r *CustomProviderRepository) Create(ctx context.Context, provider *models.ProviderConfig, createdBy int) error {
+	modelsJSON, err := json.Marshal(provider.Models)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO custom_providers (` + customProviderColumns + `, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err = r.pool.Exec(ctx, query,
+		provider.Name, provider.DisplayName, provider.BaseURL, provider.ChatCompletionPath,
+		provider.HealthCheckPath, provider.RequiresAPIKey, modelsJSON, provider.EmbeddingPath, createdBy,
+	)
+	return err
+}
+
+// GetAll retrieves every custom provider ordered by name
+func (r *CustomProviderRepository) GetAll(ctx context.Context) ([]models.ProviderConfig, error) {
+	query := `SELECT ` + customProviderColumns + ` FROM custom_providers ORDER BY name`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	providers := []models.ProviderConfig{}
+	for rows.Next() {
+		provider, err := scanCustomProvider(rows)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, *provider)
+	}
+
+	return providers, rows.Err()
+}
+
+// GetByName retrieves a custom provider by name
+func (r *CustomProviderRepository) GetByName(ctx context.Context, name string) (*models.ProviderConfig, error) {
+	query := `SELECT ` + customProviderColumns + ` FROM custom_providers WHERE name = $1`
+	return scanCustomProvider(r.pool.QueryRow(ctx, query, name))
+}
+
+// Update replaces a custom provider's definition
+func (r *CustomProviderRepository) Update(ctx context.Context, provider *models.ProviderConfig) error {
+	modelsJSON, err := json.Marshal(provider.Models)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE custom_providers
+	          SET display_name = $2, base_url = $3, chat_completion_path = $4, health_check_path = $5,
+	              requires_api_key = $6, models = $7, embedding_path = $8, updated_at = CURRENT_TIMESTAMP
+	          WHERE name = $1`
+
+	_, err = r.pool.Exec(ctx, query,
+		provider.Name, provider.DisplayName, provider.BaseURL, provider.ChatCompletionPath,
+		provider.HealthCheckPath, provider.RequiresAPIKey, modelsJSON, provider.EmbeddingPath,
+	)
+	return err
+}
+
+// Delete removes a custom provider
+func (r *CustomProviderRepository) Delete(ctx context.Context, name string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM custom_providers WHERE name = $1`, name)
+	return err
+}
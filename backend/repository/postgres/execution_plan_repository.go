@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -19,10 +22,59 @@ func NewExecutionPlanRepository(pool *pgxpool.Pool) *ExecutionPlanRepository {
 	}
 }
 
+// dbtx is the part of a pool or transaction the plan writes need
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // --- Execution Plans ---
 
 // CreatePlan creates a new execution plan
 func (r *ExecutionPlanRepository) CreatePlan(ctx context.Context, plan *models.ExecutionPlan) error {
+	return createPlan(ctx, r.pool, plan)
+}
+
+// CreatePlanWithAssignments creates a plan and materializes the assignments of its priority
+// order in one transaction. An active plan supersedes the project's previous active plans,
+// whose open assignments are skipped and counted in the result.
+func (r *ExecutionPlanRepository) CreatePlanWithAssignments(ctx context.Context, plan *models.ExecutionPlan) (*models.AssignmentSyncResult, error) {
+	var result *models.AssignmentSyncResult
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		superseded := 0
+		if plan.Status == models.ExecutionPlanStatusActive {
+			rows, err := tx.Query(ctx, `UPDATE execution_plans SET status = $1, updated_at = NOW()
+			                            WHERE project_id = $2 AND status = $3
+			                            RETURNING id`,
+				models.ExecutionPlanStatusSuperseded, plan.ProjectID, models.ExecutionPlanStatusActive)
+			if err != nil {
+				return err
+			}
+			planIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+			if err != nil {
+				return err
+			}
+			if superseded, err = skipOpenAssignments(ctx, tx, planIDs...); err != nil {
+				return err
+			}
+		}
+
+		if err := createPlan(ctx, tx, plan); err != nil {
+			return err
+		}
+		var err error
+		result, err = reconcileAssignments(ctx, tx, plan.ID, plan.PlanData.PriorityOrder, plan.CreatedBy, plan.CreatorType)
+		if err != nil {
+			return err
+		}
+		result.Skipped += superseded
+		return nil
+	})
+	return result, err
+}
+
+func createPlan(ctx context.Context, db dbtx, plan *models.ExecutionPlan) error {
 	planDataJSON, err := json.Marshal(plan.PlanData)
 	if err != nil {
 		return fmt.Errorf("failed to marshal plan_data: %w", err)
@@ -32,7 +84,7 @@ func (r *ExecutionPlanRepository) CreatePlan(ctx context.Context, plan *models.E
 	          VALUES ($1, $2, $3, $4, $5)
 	          RETURNING id, created_at, updated_at`
 
-	return r.pool.QueryRow(ctx, query,
+	return db.QueryRow(ctx, query,
 		plan.ProjectID, plan.CreatedBy, plan.CreatorType, planDataJSON, plan.Status,
 	).Scan(&plan.ID, &plan.CreatedAt, &plan.UpdatedAt)
 }
@@ -171,6 +223,40 @@ func (r *ExecutionPlanRepository) GetPlansByProjectID(ctx context.Context, proje
 
 // UpdatePlan updates an execution plan
 func (r *ExecutionPlanRepository) UpdatePlan(ctx context.Context, id int, updates map[string]interface{}) error {
+	return updatePlan(ctx, r.pool, id, updates)
+}
+
+// UpdatePlanWithAssignments updates a plan and reconciles its assignments with the new
+// priority order in one transaction
+func (r *ExecutionPlanRepository) UpdatePlanWithAssignments(ctx context.Context, id int, updates map[string]interface{}, priorityOrder []models.TaskPriorityItem, actorID int, actorType string) (*models.AssignmentSyncResult, error) {
+	var result *models.AssignmentSyncResult
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := updatePlan(ctx, tx, id, updates); err != nil {
+			return err
+		}
+		var err error
+		result, err = reconcileAssignments(ctx, tx, id, priorityOrder, actorID, actorType)
+		return err
+	})
+	return result, err
+}
+
+// UpdatePlanAndSkipAssignments updates a plan that stops being active and skips its open
+// assignments in one transaction, returning how many were skipped
+func (r *ExecutionPlanRepository) UpdatePlanAndSkipAssignments(ctx context.Context, id int, updates map[string]interface{}) (int, error) {
+	var skipped int
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := updatePlan(ctx, tx, id, updates); err != nil {
+			return err
+		}
+		var err error
+		skipped, err = skipOpenAssignments(ctx, tx, id)
+		return err
+	})
+	return skipped, err
+}
+
+func updatePlan(ctx context.Context, db dbtx, id int, updates map[string]interface{}) error {
 	if len(updates) == 0 {
 		return nil
 	}
@@ -200,7 +286,7 @@ func (r *ExecutionPlanRepository) UpdatePlan(ctx context.Context, id int, update
 	query += fmt.Sprintf(", updated_at = NOW() WHERE id = $%d", argPos)
 	args = append(args, id)
 
-	_, err := r.pool.Exec(ctx, query, args...)
+	_, err := db.Exec(ctx, query, args...)
 	return err
 }
 
@@ -224,44 +310,253 @@ func (r *ExecutionPlanRepository) CreateAssignment(ctx context.Context, assignme
 	).Scan(&assignment.ID, &assignment.CreatedAt, &assignment.UpdatedAt)
 }
 
-// GetNextAssignmentForAgent finds the next pending assignment for an agent
-func (r *ExecutionPlanRepository) GetNextAssignmentForAgent(ctx context.Context, agentID int) (*models.AgentAssignmentWithDetails, error) {
+// reconcileAssignments brings a plan's open assignments in line with its priority order. Tasks
+// with a new agent get a pending assignment, tasks whose agent changed are reassigned, and open
+// assignments of tasks that left the plan or lost their agent are skipped; replaced assignments
+// are skipped too, so the history stays. Tasks that are in review or finished are not assigned
+// again until they are reopened.
+// tasks.assigned_agent_id follows every change.
+func reconcileAssignments(ctx context.Context, tx pgx.Tx, planID int, priorityOrder []models.TaskPriorityItem, actorID int, actorType string) (*models.AssignmentSyncResult, error) {
+	rows, err := tx.Query(ctx, `SELECT id, agent_id, task_id FROM agent_assignments
+	                            WHERE plan_id = $1 AND status IN ('pending', 'in_progress')
+	                            ORDER BY id FOR UPDATE`, planID)
+	if err != nil {
+		return nil, err
+	}
+	type openAssignment struct{ id, agentID int }
+	open := make(map[int]openAssignment)
+	var openOrder []int
+	for rows.Next() {
+		var a openAssignment
+		var taskID int
+		if err := rows.Scan(&a.id, &a.agentID, &taskID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		open[taskID] = a
+		openOrder = append(openOrder, taskID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Finished tasks are not handed out again, whichever plan they were finished under
+	taskIDs := make([]int, 0, len(priorityOrder))
+	for _, item := range priorityOrder {
+		taskIDs = append(taskIDs, item.TaskID)
+	}
+	rows, err = tx.Query(ctx, `SELECT id FROM tasks
+	                           WHERE id = ANY($1::int[]) AND status IN ('in_review', 'done', 'closed', 'wont_do')`, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	finishedIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, err
+	}
+	completed := make(map[int]bool, len(finishedIDs))
+	for _, taskID := range finishedIDs {
+		completed[taskID] = true
+	}
+
+	desired := make(map[int]int)
+	for _, item := range priorityOrder {
+		if item.AssignedAgentID != nil {
+			desired[item.TaskID] = *item.AssignedAgentID
+		}
+	}
+
+	result := &models.AssignmentSyncResult{}
+	logActivity := func(taskID int, oldValue, newValue *string, message string) error {
+		_, err := tx.Exec(ctx, `INSERT INTO task_activities (task_id, actor_id, actor_type, action, old_value, new_value, message)
+		                        VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			taskID, actorID, actorType, models.TaskActionAssigned, oldValue, newValue, message)
+		return err
+	}
+
+	// Open assignments of tasks that left the plan or lost their agent
+	for _, taskID := range openOrder {
+		a := open[taskID]
+		if _, ok := desired[taskID]; ok {
+			continue
+		}
+		if err := skipAssignment(ctx, tx, a.id); err != nil {
+			return nil, err
+		}
+		tag, err := tx.Exec(ctx, `UPDATE tasks SET assigned_agent_id = NULL, updated_at = NOW()
+		                          WHERE id = $1 AND assigned_agent_id = $2`, taskID, a.agentID)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() > 0 {
+			name, err := agentName(ctx, tx, a.agentID)
+			if err != nil {
+				return nil, err
+			}
+			if err := logActivity(taskID, &name, nil, fmt.Sprintf("Agent '%s' unassigned from task by execution plan %d", name, planID)); err != nil {
+				return nil, err
+			}
+		}
+		result.Skipped++
+	}
+
+	// New and changed assignments, created in priority order
+	for _, item := range priorityOrder {
+		if item.AssignedAgentID == nil {
+			continue
+		}
+		agentID := *item.AssignedAgentID
+
+		a, isOpen := open[item.TaskID]
+		switch {
+		case isOpen && a.agentID == agentID:
+		case isOpen:
+			if err := skipAssignment(ctx, tx, a.id); err != nil {
+				return nil, err
+			}
+			if err := createAssignment(ctx, tx, planID, agentID, item.TaskID); err != nil {
+				return nil, err
+			}
+			result.Reassigned++
+		case completed[item.TaskID]:
+			continue
+		default:
+			if err := createAssignment(ctx, tx, planID, agentID, item.TaskID); err != nil {
+				return nil, err
+			}
+			result.Created++
+		}
+
+		tag, err := tx.Exec(ctx, `UPDATE tasks SET assigned_agent_id = $1, updated_at = NOW()
+		                          WHERE id = $2 AND assigned_agent_id IS DISTINCT FROM $1`, agentID, item.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() > 0 {
+			name, err := agentName(ctx, tx, agentID)
+			if err != nil {
+				return nil, err
+			}
+			if err := logActivity(item.TaskID, nil, &name, fmt.Sprintf("Agent '%s' assigned to task by execution plan %d", name, planID)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// RequeueCompletedAssignment gives a task back to the agent that last completed it under an
+// active plan, as a new pending assignment. It does nothing when the task already has an open
+// assignment, and reports whether one was created.
+func (r *ExecutionPlanRepository) RequeueCompletedAssignment(ctx context.Context, taskID int) (bool, error) {
+	query := `INSERT INTO agent_assignments (plan_id, agent_id, task_id, status)
+	          SELECT aa.plan_id, aa.agent_id, aa.task_id, 'pending'
+	          FROM agent_assignments aa
+	          JOIN execution_plans ep ON aa.plan_id = ep.id
+	          WHERE aa.task_id = $1 AND aa.status = 'completed' AND ep.status = 'active'
+	            AND NOT EXISTS (
+	                SELECT 1 FROM agent_assignments o
+	                WHERE o.task_id = $1 AND o.status IN ('pending', 'in_progress')
+	            )
+	          ORDER BY aa.completed_at DESC NULLS LAST, aa.id DESC
+	          LIMIT 1`
+
+	tag, err := r.pool.Exec(ctx, query, taskID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func createAssignment(ctx context.Context, tx pgx.Tx, planID, agentID, taskID int) error {
+	_, err := tx.Exec(ctx, `INSERT INTO agent_assignments (plan_id, agent_id, task_id, status) VALUES ($1, $2, $3, $4)`,
+		planID, agentID, taskID, models.AssignmentStatusPending)
+	return err
+}
+
+// skipOpenAssignments skips the pending and in-progress assignments of plans that no longer
+// hand out work, releasing their leases
+func skipOpenAssignments(ctx context.Context, tx pgx.Tx, planIDs ...int) (int, error) {
+	if len(planIDs) == 0 {
+		return 0, nil
+	}
+	tag, err := tx.Exec(ctx, `UPDATE agent_assignments SET status = $1, lease_expires_at = NULL, updated_at = NOW()
+	                          WHERE plan_id = ANY($2::int[]) AND status IN ('pending', 'in_progress')`,
+		models.AssignmentStatusSkipped, planIDs)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func skipAssignment(ctx context.Context, tx pgx.Tx, id int) error {
+	_, err := tx.Exec(ctx, `UPDATE agent_assignments SET status = $1, updated_at = NOW() WHERE id = $2`,
+		models.AssignmentStatusSkipped, id)
+	return err
+}
+
+func agentName(ctx context.Context, tx pgx.Tx, id int) (string, error) {
+	var name string
+	err := tx.QueryRow(ctx, `SELECT name FROM agents WHERE id = $1`, id).Scan(&name)
+	return name, err
+}
+
+// GetPendingAssignmentsForAgent lists an agent's pending assignments under active plans with
+// the priority of their task and the data of their plan, oldest first
+func (r *ExecutionPlanRepository) GetPendingAssignmentsForAgent(ctx context.Context, agentID int) ([]models.PendingAssignment, error) {
 	query := `SELECT
 		aa.id, aa.plan_id, aa.agent_id, aa.task_id, aa.status,
-		aa.started_at, aa.completed_at, aa.report_data, aa.created_at, aa.updated_at,
+		aa.started_at, aa.completed_at, aa.lease_expires_at, aa.last_heartbeat_at, aa.reclaim_count, aa.report_data, aa.created_at, aa.updated_at,
 		ag.name as agent_name,
-		t.title as task_title
+		t.title as task_title,
+		t.priority as task_priority,
+		ep.plan_data
 	FROM agent_assignments aa
+	JOIN tasks t ON aa.task_id = t.id
+	JOIN execution_plans ep ON aa.plan_id = ep.id
 	LEFT JOIN agents ag ON aa.agent_id = ag.id
-	LEFT JOIN tasks t ON aa.task_id = t.id
-	WHERE aa.agent_id = $1 AND aa.status = 'pending'
-	ORDER BY aa.created_at ASC
-	LIMIT 1`
+	WHERE aa.agent_id = $1 AND aa.status = 'pending' AND ep.status = 'active'
+	ORDER BY aa.created_at ASC, aa.id ASC`
 
-	var assignment models.AgentAssignmentWithDetails
-	var reportDataJSON []byte
-	err := r.pool.QueryRow(ctx, query, agentID).Scan(
-		&assignment.ID, &assignment.PlanID, &assignment.AgentID, &assignment.TaskID,
-		&assignment.Status, &assignment.StartedAt, &assignment.CompletedAt,
-		&reportDataJSON, &assignment.CreatedAt, &assignment.UpdatedAt,
-		&assignment.AgentName, &assignment.TaskTitle,
-	)
+	rows, err := r.pool.Query(ctx, query, agentID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	if reportDataJSON != nil {
-		_ = json.Unmarshal(reportDataJSON, &assignment.ReportData)
+	var assignments []models.PendingAssignment
+	for rows.Next() {
+		var assignment models.PendingAssignment
+		var reportDataJSON, planDataJSON []byte
+		err := rows.Scan(
+			&assignment.ID, &assignment.PlanID, &assignment.AgentID, &assignment.TaskID,
+			&assignment.Status, &assignment.StartedAt, &assignment.CompletedAt,
+			&assignment.LeaseExpiresAt, &assignment.LastHeartbeatAt, &assignment.ReclaimCount,
+			&reportDataJSON, &assignment.CreatedAt, &assignment.UpdatedAt,
+			&assignment.AgentName, &assignment.TaskTitle, &assignment.TaskPriority, &planDataJSON,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if reportDataJSON != nil {
+			_ = json.Unmarshal(reportDataJSON, &assignment.ReportData)
+		}
+		_ = json.Unmarshal(planDataJSON, &assignment.PlanData)
+
+		assignments = append(assignments, assignment)
 	}
 
-	return &assignment, nil
+	return assignments, rows.Err()
 }
 
 // GetAssignmentsByPlanID retrieves all assignments for a plan
 func (r *ExecutionPlanRepository) GetAssignmentsByPlanID(ctx context.Context, planID int) ([]models.AgentAssignmentWithDetails, error) {
 	query := `SELECT
 		aa.id, aa.plan_id, aa.agent_id, aa.task_id, aa.status,
-		aa.started_at, aa.completed_at, aa.report_data, aa.created_at, aa.updated_at,
+		aa.started_at, aa.completed_at, aa.lease_expires_at, aa.last_heartbeat_at, aa.reclaim_count, aa.report_data, aa.created_at, aa.updated_at,
 		ag.name as agent_name,
 		t.title as task_title
 	FROM agent_assignments aa
@@ -283,6 +578,7 @@ func (r *ExecutionPlanRepository) GetAssignmentsByPlanID(ctx context.Context, pl
 		err := rows.Scan(
 			&assignment.ID, &assignment.PlanID, &assignment.AgentID, &assignment.TaskID,
 			&assignment.Status, &assignment.StartedAt, &assignment.CompletedAt,
+			&assignment.LeaseExpiresAt, &assignment.LastHeartbeatAt, &assignment.ReclaimCount,
 			&reportDataJSON, &assignment.CreatedAt, &assignment.UpdatedAt,
 			&assignment.AgentName, &assignment.TaskTitle,
 		)
@@ -300,41 +596,37 @@ func (r *ExecutionPlanRepository) GetAssignmentsByPlanID(ctx context.Context, pl
 	return assignments, rows.Err()
 }
 
-// CompleteAssignment marks an assignment as completed with report data
-func (r *ExecutionPlanRepository) CompleteAssignment(ctx context.Context, assignmentID int, reportData any) error {
+// CompleteAssignment marks an assignment the agent has claimed as completed with report data.
+// It reports false when the assignment is not in progress with that agent, such as one never
+// claimed or one whose lease was reclaimed.
+func (r *ExecutionPlanRepository) CompleteAssignment(ctx context.Context, assignmentID, agentID int, reportData any) (bool, error) {
 	reportJSON, err := json.Marshal(reportData)
 	if err != nil {
-		return fmt.Errorf("failed to marshal report_data: %w", err)
+		return false, fmt.Errorf("failed to marshal report_data: %w", err)
 	}
 
 	query := `UPDATE agent_assignments
-	          SET status = 'completed', completed_at = NOW(), report_data = $1, updated_at = NOW()
-	          WHERE id = $2`
+	          SET status = 'completed', completed_at = NOW(), lease_expires_at = NULL, report_data = $1, updated_at = NOW()
+	          WHERE id = $2 AND agent_id = $3 AND status = 'in_progress'`
 
-	_, err = r.pool.Exec(ctx, query, reportJSON, assignmentID)
-	return err
-}
-
-// StartAssignment marks an assignment as in_progress
-func (r *ExecutionPlanRepository) StartAssignment(ctx context.Context, assignmentID int) error {
-	query := `UPDATE agent_assignments
-	          SET status = 'in_progress', started_at = NOW(), updated_at = NOW()
-	          WHERE id = $1`
-
-	_, err := r.pool.Exec(ctx, query, assignmentID)
-	return err
+	tag, err := r.pool.Exec(ctx, query, reportJSON, assignmentID, agentID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
 }
 
-// GetAssignmentByID retrieves an assignment by ID
-func (r *ExecutionPlanRepository) GetAssignmentByID(ctx context.Context, id int) (*models.AgentAssignment, error) {
-	query := `SELECT id, plan_id, agent_id, task_id, status, started_at, completed_at, report_data, created_at, updated_at
-	          FROM agent_assignments WHERE id = $1`
+// assignmentColumns is the column list scanned by scanAssignment
+const assignmentColumns = `id, plan_id, agent_id, task_id, status, started_at, completed_at,
+	lease_expires_at, last_heartbeat_at, reclaim_count, report_data, created_at, updated_at`
 
+func scanAssignment(row pgx.Row) (*models.AgentAssignment, error) {
 	var assignment models.AgentAssignment
 	var reportDataJSON []byte
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := row.Scan(
 		&assignment.ID, &assignment.PlanID, &assignment.AgentID, &assignment.TaskID,
 		&assignment.Status, &assignment.StartedAt, &assignment.CompletedAt,
+		&assignment.LeaseExpiresAt, &assignment.LastHeartbeatAt, &assignment.ReclaimCount,
 		&reportDataJSON, &assignment.CreatedAt, &assignment.UpdatedAt,
 	)
 	if err != nil {
@@ -348,29 +640,105 @@ func (r *ExecutionPlanRepository) GetAssignmentByID(ctx context.Context, id int)
 	return &assignment, nil
 }
 
-// GetAssignmentByAgentAndTask finds assignment by agent and task
-func (r *ExecutionPlanRepository) GetAssignmentByAgentAndTask(ctx context.Context, agentID int, taskID int) (*models.AgentAssignment, error) {
-	query := `SELECT id, plan_id, agent_id, task_id, status, started_at, completed_at, report_data, created_at, updated_at
-	          FROM agent_assignments
-	          WHERE agent_id = $1 AND task_id = $2 AND status IN ('pending', 'in_progress')
-	          ORDER BY created_at DESC LIMIT 1`
+// ClaimAssignment atomically moves the first of an agent's candidate assignments that is still
+// pending to in_progress and leases it to the agent. Candidates locked by a concurrent claim
+// are skipped, so two workers never get the same assignment. It returns pgx.ErrNoRows when
+// none is left to claim.
+//
+// When maxParallel is positive, claims in the project are serialized and nothing is claimed
+// while the project already has maxParallel assignments in progress; the assignment is then
+// nil. The returned count is the project's in-progress assignments before the claim, and is
+// only computed when maxParallel is positive.
+func (r *ExecutionPlanRepository) ClaimAssignment(ctx context.Context, agentID, projectID int, candidateIDs []int, lease time.Duration, maxParallel int) (*models.AgentAssignment, int, error) {
+	query := `UPDATE agent_assignments
+	          SET status = 'in_progress', started_at = NOW(), lease_expires_at = NOW() + make_interval(secs => $3),
+	              last_heartbeat_at = NOW(), updated_at = NOW()
+	          WHERE status = 'pending' AND id = (
+	              SELECT id FROM agent_assignments
+	              WHERE id = ANY($1::int[]) AND agent_id = $2 AND status = 'pending'
+	              ORDER BY array_position($1::int[], id)
+	              LIMIT 1
+	              FOR UPDATE SKIP LOCKED
+	          )
+	          RETURNING ` + assignmentColumns
+
+	var claimed *models.AgentAssignment
+	var inProgress int
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if maxParallel > 0 {
+			// Held until commit, so a concurrent claim counts this one
+			if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('agent_assignments'), $1)`, projectID); err != nil {
+				return err
+			}
+			err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM agent_assignments aa
+			                         JOIN execution_plans ep ON aa.plan_id = ep.id
+			                         WHERE ep.project_id = $1 AND aa.status = 'in_progress'`, projectID).Scan(&inProgress)
+			if err != nil {
+				return err
+			}
+			if inProgress >= maxParallel {
+				return nil
+			}
+		}
 
-	var assignment models.AgentAssignment
-	var reportDataJSON []byte
-	err := r.pool.QueryRow(ctx, query, agentID, taskID).Scan(
-		&assignment.ID, &assignment.PlanID, &assignment.AgentID, &assignment.TaskID,
-		&assignment.Status, &assignment.StartedAt, &assignment.CompletedAt,
-		&reportDataJSON, &assignment.CreatedAt, &assignment.UpdatedAt,
-	)
+		var err error
+		claimed, err = scanAssignment(tx.QueryRow(ctx, query, candidateIDs, agentID, lease.Seconds()))
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, inProgress, err
 	}
+	return claimed, inProgress, nil
+}
 
-	if reportDataJSON != nil {
-		_ = json.Unmarshal(reportDataJSON, &assignment.ReportData)
+// HeartbeatAssignment extends the lease of an agent's in-progress assignment. It returns
+// pgx.ErrNoRows when the assignment is not in progress with that agent.
+func (r *ExecutionPlanRepository) HeartbeatAssignment(ctx context.Context, agentID, assignmentID int, lease time.Duration) (*models.AgentAssignment, error) {
+	query := `UPDATE agent_assignments
+	          SET lease_expires_at = NOW() + make_interval(secs => $3), last_heartbeat_at = NOW(), updated_at = NOW()
+	          WHERE id = $1 AND agent_id = $2 AND status = 'in_progress'
+	          RETURNING ` + assignmentColumns
+
+	return scanAssignment(r.pool.QueryRow(ctx, query, assignmentID, agentID, lease.Seconds()))
+}
+
+// ReclaimExpiredAssignments puts in-progress assignments whose lease lapsed back to pending
+// so they can be claimed again, and returns how many were reclaimed. A nil projectID
+// reclaims across all projects.
+func (r *ExecutionPlanRepository) ReclaimExpiredAssignments(ctx context.Context, projectID *int) (int64, error) {
+	query := `UPDATE agent_assignments
+	          SET status = 'pending', started_at = NULL, lease_expires_at = NULL,
+	              reclaim_count = reclaim_count + 1, updated_at = NOW()
+	          WHERE id IN (
+	              SELECT aa.id FROM agent_assignments aa
+	              JOIN execution_plans ep ON aa.plan_id = ep.id
+	              WHERE aa.status = 'in_progress' AND aa.lease_expires_at < NOW()
+	                AND ($1::int IS NULL OR ep.project_id = $1)
+	              FOR UPDATE OF aa SKIP LOCKED
+	          )`
+
+	tag, err := r.pool.Exec(ctx, query, projectID)
+	if err != nil {
+		return 0, err
 	}
+	return tag.RowsAffected(), nil
+}
 
-	return &assignment, nil
+// GetAssignmentByID retrieves an assignment by ID
+func (r *ExecutionPlanRepository) GetAssignmentByID(ctx context.Context, id int) (*models.AgentAssignment, error) {
+	query := `SELECT ` + assignmentColumns + ` FROM agent_assignments WHERE id = $1`
+
+	return scanAssignment(r.pool.QueryRow(ctx, query, id))
+}
+
+// GetAssignmentByAgentAndTask finds assignment by agent and task
+func (r *ExecutionPlanRepository) GetAssignmentByAgentAndTask(ctx context.Context, agentID int, taskID int) (*models.AgentAssignment, error) {
+	query := `SELECT ` + assignmentColumns + `
+	          FROM agent_assignments
+	          WHERE agent_id = $1 AND task_id = $2 AND status IN ('pending', 'in_progress')
+	          ORDER BY created_at DESC LIMIT 1`
+
+	return scanAssignment(r.pool.QueryRow(ctx, query, agentID, taskID))
 }
 
 // --- Execution Reports ---
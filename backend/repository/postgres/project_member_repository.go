@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ProjectMemberRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewProjectMemberRepository(pool *pgxpool.Pool) *ProjectMemberRepository {
+	return &ProjectMemberRepository{
+		pool: pool,
+	}
+}
+
+// Create adds a user to a project with the given role
+func (r *ProjectMemberRepository) Create(ctx context.Context, member *models.ProjectMember) error {
+	query := `INSERT INTO project_members (project_id, user_id, role)
+	          VALUES ($1, $2, $3)
+	          RETURNING created_at, updated_at`
+
+	return r.pool.QueryRow(ctx, query,
+		member.ProjectID, member.UserID, member.Role,
+	).Scan(&member.CreatedAt, &member.UpdatedAt)
+}
+
+// GetByProjectAndUser retrieves a single membership
+func (r *ProjectMemberRepository) GetByProjectAndUser(ctx context.Context, projectID int, userID int) (*models.ProjectMember, error) {
+	query := `SELECT project_id, user_id, role, created_at, updated_at
+	          FROM project_members WHERE project_id = $1 AND user_id = $2`
+
+	var member models.ProjectMember
+	err := r.pool.QueryRow(ctx, query, projectID, userID).Scan(
+		&member.ProjectID, &member.UserID, &member.Role, &member.CreatedAt, &member.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// GetByProjectID retrieves all members of a project with user details
+func (r *ProjectMemberRepository) GetByProjectID(ctx context.Context, projectID int) ([]models.ProjectMemberWithDetails, error) {
+	query := `SELECT
+		pm.project_id, pm.user_id, pm.role, pm.created_at, pm.updated_at,
+		u.username, u.email, u.avatar_url
+	FROM project_members pm
+	JOIN users u ON pm.user_id = u.id
+	WHERE pm.project_id = $1
+	ORDER BY pm.created_at ASC`
+
+	rows, err := r.pool.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.ProjectMemberWithDetails
+	for rows.Next() {
+		var member models.ProjectMemberWithDetails
+		err := rows.Scan(
+			&member.ProjectID, &member.UserID, &member.Role, &member.CreatedAt, &member.UpdatedAt,
+			&member.Username, &member.Email, &member.AvatarUrl,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// UpdateRole changes a member's role. It reports false, changing nothing, when the member
+// is the project's last owner and role is not owner.
+func (r *ProjectMemberRepository) UpdateRole(ctx context.Context, projectID int, userID int, role string) (bool, error) {
+	query := `UPDATE project_members SET role = $1, updated_at = NOW() WHERE project_id = $2 AND user_id = $3`
+
+	return r.keepingOwner(ctx, projectID, userID, role != models.ProjectRoleOwner, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query, role, projectID, userID)
+		return err
+	})
+}
+
+// Delete removes a user from a project. It reports false, removing nothing, when the user
+// is the project's last owner.
+func (r *ProjectMemberRepository) Delete(ctx context.Context, projectID int, userID int) (bool, error) {
+	query := `DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`
+
+	return r.keepingOwner(ctx, projectID, userID, true, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query, projectID, userID)
+		return err
+	})
+}
+
+// keepingOwner runs change in a transaction holding the project's owner rows, so concurrent
+// changes cannot each see another owner and together remove them all. When dropsOwnership
+// is set and userID is the only owner, change is skipped and false is returned.
+func (r *ProjectMemberRepository) keepingOwner(ctx context.Context, projectID int, userID int, dropsOwnership bool, change func(pgx.Tx) error) (bool, error) {
+	query := `SELECT user_id FROM project_members WHERE project_id = $1 AND role = $2 FOR UPDATE`
+
+	kept := true
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, projectID, models.ProjectRoleOwner)
+		if err != nil {
+			return err
+		}
+		owners, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		if dropsOwnership && len(owners) == 1 && owners[0] == userID {
+			kept = false
+			return nil
+		}
+		return change(tx)
+	})
+	if err != nil {
+		return false, err
+	}
+	return kept, nil
+}
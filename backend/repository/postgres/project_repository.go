@@ -18,10 +18,17 @@ func NewProjectRepository(pool *pgxpool.Pool) *ProjectRepository {
 	}
 }
 
+// Create creates a project and makes its creator the owner in the same statement
 func (r *ProjectRepository) Create(ctx context.Context, project *models.Project) error {
-	query := `INSERT INTO projects (name, description, status, created_by)
-	          VALUES ($1, $2, $3, $4)
-	          RETURNING id, created_at, updated_at`
+	query := `WITH p AS (
+	              INSERT INTO projects (name, description, status, created_by)
+	              VALUES ($1, $2, $3, $4)
+	              RETURNING id, created_by, created_at, updated_at
+	          ), m AS (
+	              INSERT INTO project_members (project_id, user_id, role)
+	              SELECT id, created_by, 'owner' FROM p
+	          )
+	          SELECT id, created_at, updated_at FROM p`
 
 	return r.pool.QueryRow(ctx, query,
 		project.Name, project.Description, project.Status, project.CreatedBy,
@@ -74,6 +81,40 @@ func (r *ProjectRepository) GetAll(ctx context.Context) ([]models.Project, error
 	return projects, nil
 }
 
+// GetAllForUser retrieves the projects a user is a member of
+func (r *ProjectRepository) GetAllForUser(ctx context.Context, userID int) ([]models.Project, error) {
+	query := `SELECT p.id, p.name, p.description, p.status, p.created_by, p.created_at, p.updated_at
+	          FROM projects p
+	          JOIN project_members pm ON pm.project_id = p.id
+	          WHERE pm.user_id = $1
+	          ORDER BY p.created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []models.Project
+	for rows.Next() {
+		var project models.Project
+		err := rows.Scan(
+			&project.ID, &project.Name, &project.Description, &project.Status,
+			&project.CreatedBy, &project.CreatedAt, &project.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
 func (r *ProjectRepository) Update(ctx context.Context, project *models.Project) error {
 	query := `UPDATE projects
 	          SET name = $1, description = $2, status = $3, updated_at = NOW()
@@ -130,7 +171,7 @@ func (r *ProjectRepository) GetStats(ctx context.Context, projectID int) (*model
 	taskQuery := `SELECT
 		COUNT(*) as total,
 		COUNT(*) FILTER (WHERE status IN ('done', 'closed')) as completed,
-		COUNT(*) FILTER (WHERE status IN ('open', 'in_progress')) as pending
+		COUNT(*) FILTER (WHERE status IN ('open', 'in_progress', 'in_review')) as pending
 	FROM tasks WHERE project_id = $1`
 
 	err := r.pool.QueryRow(ctx, taskQuery, projectID).Scan(
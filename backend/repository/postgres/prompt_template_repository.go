@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const promptTemplateColumns = `id, project_id, role, level, version, content, change_note, COALESCE(created_by, 0), created_at`
+
+type PromptTemplateRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPromptTemplateRepository(pool *pgxpool.Pool) *PromptTemplateRepository {
+	return &PromptTemplateRepository{
+		pool: pool,
+	}
+}
+
+// Create saves the next version of the template's project, role and level.
+// The version is assigned in the insert; concurrent saves of the same template fail on the unique index.
+func (r *PromptTemplateRepository) Create(ctx context.Context, template *models.PromptTemplate) error {
+	query := `INSERT INTO prompt_templates (project_id, role, level, version, content, change_note, created_by)
+	          SELECT $1::int, $2, $3, COALESCE(MAX(version), 0) + 1, $4, $5, NULLIF($6, 0)
+	          FROM prompt_templates
+	          WHERE project_id IS NOT DISTINCT FROM $1::int AND role = $2 AND level = $3
+	          RETURNING id, version, created_at`
+
+	return r.pool.QueryRow(ctx, query,
+		template.ProjectID, template.Role, template.Level, template.Content, template.ChangeNote, template.CreatedBy,
+	).Scan(&template.ID, &template.Version, &template.CreatedAt)
+}
+
+// GetByID retrieves a template version
+func (r *PromptTemplateRepository) GetByID(ctx context.Context, id int) (*models.PromptTemplate, error) {
+	query := `SELECT ` + promptTemplateColumns + ` FROM prompt_templates WHERE id = $1`
+
+	templates, err := r.query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &templates[0], nil
+}
+
+// GetLatest retrieves the newest version of every role and level of a project,
+// or of the defaults when projectID is nil
+func (r *PromptTemplateRepository) GetLatest(ctx context.Context, projectID *int) ([]models.PromptTemplate, error) {
+	query := `SELECT DISTINCT ON (role, level) ` + promptTemplateColumns + `
+	          FROM prompt_templates
+	          WHERE project_id IS NOT DISTINCT FROM $1::int
+	          ORDER BY role, level, version DESC`
+
+	return r.query(ctx, query, projectID)
+}
+
+// GetVersions retrieves every version of a role and level, newest first
+func (r *PromptTemplateRepository) GetVersions(ctx context.Context, projectID *int, role, level string) ([]models.PromptTemplate, error) {
+	query := `SELECT ` + promptTemplateColumns + `
+	          FROM prompt_templates
+	          WHERE project_id IS NOT DISTINCT FROM $1::int AND role = $2 AND level = $3
+	          ORDER BY version DESC`
+
+	return r.query(ctx, query, projectID, role, level)
+}
+
+// Resolve finds the template an agent is prompted with: the newest version of the project's
+// template for the role and level, else of the project's template for the role, else the
+// defaults in the same order
+func (r *PromptTemplateRepository) Resolve(ctx context.Context, projectID int, role, level string) (*models.PromptTemplate, error) {
+	query := `SELECT ` + promptTemplateColumns + `
+	          FROM prompt_templates
+	          WHERE (project_id = $1 OR project_id IS NULL) AND role = $2 AND level IN ($3, '')
+	          ORDER BY project_id IS NULL, level = '', version DESC
+	          LIMIT 1`
+
+	templates, err := r.query(ctx, query, projectID, role, level)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &templates[0], nil
+}
+
+func (r *PromptTemplateRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.PromptTemplate, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.PromptTemplate{}
+	for rows.Next() {
+		var t models.PromptTemplate
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Role, &t.Level, &t.Version, &t.Content,
+			&t.ChangeNote, &t.CreatedBy, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, rows.Err()
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ProviderCredentialRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewProviderCredentialRepository(pool *pgxpool.Pool) *ProviderCredentialRepository {
+	return &ProviderCredentialRepository{
+		pool: pool,
+	}
+}
+
+const providerCredentialColumns = `id, project_id, name, provider, api_key, api_key_version, api_key_hint,
+	last_tested_at, last_test_healthy, created_by, created_at, updated_at`
+
+func scanProviderCredential(row pgx.Row) (*models.ProviderCredential, error) {
+	var c models.ProviderCredential
+	err := row.Scan(
+		&c.ID, &c.ProjectID, &c.Name, &c.Provider, &c.APIKey, &c.APIKeyVersion, &c.APIKeyHint,
+		&c.LastTestedAt, &c.LastTestHealthy, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create stores a credential; a nil project makes it organization-wide
+func (r *ProviderCredentialRepository) Create(ctx context.Context, credential *models.ProviderCredential) error {
+	query := `INSERT INTO provider_credentials (project_id, name, provider, api_key, api_key_version, api_key_hint, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)
+	          RETURNING id, created_at, updated_at`
+
+	return r.pool.QueryRow(ctx, query,
+		credential.ProjectID, credential.Name, credential.Provider,
+		credential.APIKey, credential.APIKeyVersion, credential.APIKeyHint, credential.CreatedBy,
+	).Scan(&credential.ID, &credential.CreatedAt, &credential.UpdatedAt)
+}
+
+// GetByID retrieves a credential by ID, whatever its scope
+func (r *ProviderCredentialRepository) GetByID(ctx context.Context, id int) (*models.ProviderCredential, error) {
+	query := `SELECT ` + providerCredentialColumns + ` FROM provider_credentials WHERE id = $1`
+	return scanProviderCredential(r.pool.QueryRow(ctx, query, id))
+}
+
+// GetInScope retrieves a credential of a project, or an organization-wide one when projectID is nil
+func (r *ProviderCredentialRepository) GetInScope(ctx context.Context, projectID *int, id int) (*models.ProviderCredential, error) {
+	query := `SELECT ` + providerCredentialColumns + ` FROM provider_credentials
+	          WHERE id = $1 AND project_id IS NOT DISTINCT FROM $2`
+	return scanProviderCredential(r.pool.QueryRow(ctx, query, id, projectID))
+}
+
+// GetOrganization lists the organization-wide credentials
+func (r *ProviderCredentialRepository) GetOrganization(ctx context.Context) ([]models.ProviderCredential, error) {
+	return r.list(ctx, `SELECT `+providerCredentialColumns+` FROM provider_credentials
+	                    WHERE project_id IS NULL ORDER BY name, id`)
+}
+
+// GetAvailableToProject lists a project's credentials followed by the organization-wide ones
+func (r *ProviderCredentialRepository) GetAvailableToProject(ctx context.Context, projectID int) ([]models.ProviderCredential, error) {
+	return r.list(ctx, `SELECT `+providerCredentialColumns+` FROM provider_credentials
+	                    WHERE project_id = $1 OR project_id IS NULL
+	                    ORDER BY project_id NULLS LAST, name, id`, projectID)
+}
+
+// GetKeysNotAtVersion lists credentials encrypted under another master key version
+func (r *ProviderCredentialRepository) GetKeysNotAtVersion(ctx context.Context, version int) ([]models.ProviderCredential, error) {
+	return r.list(ctx, `SELECT `+providerCredentialColumns+` FROM provider_credentials WHERE api_key_version <> $1 ORDER BY id`, version)
+}
+
+func (r *ProviderCredentialRepository) list(ctx context.Context, query string, args ...any) ([]models.ProviderCredential, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := []models.ProviderCredential{}
+	for rows.Next() {
+		credential, err := scanProviderCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, *credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+// Update saves a credential's name and key
+func (r *ProviderCredentialRepository) Update(ctx context.Context, credential *models.ProviderCredential) error {
+	query := `UPDATE provider_credentials
+	          SET name = $1, api_key = $2, api_key_version = $3, api_key_hint = $4, updated_at = NOW()
+	          WHERE id = $5
+	          RETURNING updated_at`
+
+	return r.pool.QueryRow(ctx, query,
+		credential.Name, credential.APIKey, credential.APIKeyVersion, credential.APIKeyHint, credential.ID,
+	).Scan(&credential.UpdatedAt)
+}
+
+// UpdateAPIKey replaces the stored ciphertext if the key is still at fromVersion
+func (r *ProviderCredentialRepository) UpdateAPIKey(ctx context.Context, id int, fromVersion int, apiKey string, version int, hint string) (bool, error) {
+	query := `UPDATE provider_credentials SET api_key = $1, api_key_version = $2, api_key_hint = $3
+	          WHERE id = $4 AND api_key_version = $5`
+
+	tag, err := r.pool.Exec(ctx, query, apiKey, version, hint, id, fromVersion)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RecordTest stores the outcome of the latest provider test
+func (r *ProviderCredentialRepository) RecordTest(ctx context.Context, id int, healthy bool) error {
+	_, err := r.pool.Exec(ctx, `UPDATE provider_credentials SET last_tested_at = NOW(), last_test_healthy = $1 WHERE id = $2`, healthy, id)
+	return err
+}
+
+// DeleteUnused removes a credential unless agents reference it, returning how many do.
+// The row is locked first, so an agent cannot start referencing it between the count and
+// the delete. pgx.ErrNoRows is returned for a missing credential.
+func (r *ProviderCredentialRepository) DeleteUnused(ctx context.Context, id int) (int, error) {
+	var agents int
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var locked int
+		if err := tx.QueryRow(ctx, `SELECT id FROM provider_credentials WHERE id = $1 FOR UPDATE`, id).Scan(&locked); err != nil {
+			return err
+		}
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM agents WHERE credential_id = $1`, id).Scan(&agents); err != nil {
+			return err
+		}
+		if agents > 0 {
+			return nil
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM provider_credentials WHERE id = $1`, id)
+		return err
+	})
+	return agents, err
+}
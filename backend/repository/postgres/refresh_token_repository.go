@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RefreshTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRefreshTokenRepository(pool *pgxpool.Pool) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		pool: pool,
+	}
+}
+
+// Create stores a newly issued refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (user_id, token_id, family_id, expires_at)
+	          VALUES ($1, $2, $3, $4)
+	          RETURNING id, created_at`
+
+	return r.pool.QueryRow(ctx, query,
+		token.UserID, token.TokenID, token.FamilyID, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetByTokenID retrieves a refresh token by its jti
+func (r *RefreshTokenRepository) GetByTokenID(ctx context.Context, tokenID string) (*models.RefreshToken, error) {
+	query := `SELECT id, user_id, token_id, family_id, expires_at, used_at, revoked_at, replaced_by, created_at
+	          FROM refresh_tokens WHERE token_id = $1`
+
+	var token models.RefreshToken
+	err := r.pool.QueryRow(ctx, query, tokenID).Scan(
+		&token.ID, &token.UserID, &token.TokenID, &token.FamilyID, &token.ExpiresAt,
+		&token.UsedAt, &token.RevokedAt, &token.ReplacedBy, &token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Rotate consumes a refresh token exactly once and stores its replacement in the same
+// transaction. It returns false, storing nothing, when the token was already used,
+// revoked or expired, which callers must treat as reuse.
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, tokenID string, next *models.RefreshToken) (bool, error) {
+	rotated := false
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE refresh_tokens
+		                          SET used_at = NOW(), replaced_by = $1
+		                          WHERE token_id = $2 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()`,
+			next.TokenID, tokenID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() != 1 {
+			return nil
+		}
+
+		query := `INSERT INTO refresh_tokens (user_id, token_id, family_id, expires_at)
+		          VALUES ($1, $2, $3, $4)
+		          RETURNING id, created_at`
+		if err := tx.QueryRow(ctx, query,
+			next.UserID, next.TokenID, next.FamilyID, next.ExpiresAt,
+		).Scan(&next.ID, &next.CreatedAt); err != nil {
+			return err
+		}
+		rotated = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rotated, nil
+}
+
+// RevokeFamily revokes every token issued from the same login
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens
+	          SET revoked_at = NOW()
+	          WHERE family_id = $1 AND revoked_at IS NULL`
+
+	_, err := r.pool.Exec(ctx, query, familyID)
+	return err
+}
+
+// RevokeAllForUser revokes every active refresh token of a user
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID int) error {
+	query := `UPDATE refresh_tokens
+	          SET revoked_at = NOW()
+	          WHERE user_id = $1 AND revoked_at IS NULL`
+
+	_, err := r.pool.Exec(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TaskAttachmentRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTaskAttachmentRepository(pool *pgxpool.Pool) *TaskAttachmentRepository {
+	return &TaskAttachmentRepository{
+		pool: pool,
+	}
+}
+
+// Create stores a new attachment with its data
+func (r *TaskAttachmentRepository) Create(ctx context.Context, attachment *models.TaskAttachment) error {
+	query := `INSERT INTO task_attachments (task_id, file_name, content_type, size_bytes, data, uploaded_by, uploader_type)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)
+	          RETURNING id, created_at`
+
+	return r.pool.QueryRow(ctx, query,
+		attachment.TaskID, attachment.FileName, attachment.ContentType, attachment.SizeBytes,
+		attachment.Data, attachment.UploadedBy, attachment.UploaderType,
+	).Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+// GetByTaskID lists a task's attachments without their data, oldest first
+func (r *TaskAttachmentRepository) GetByTaskID(ctx context.Context, taskID int) ([]models.TaskAttachment, error) {
+	query := `SELECT id, task_id, file_name, content_type, size_bytes, uploaded_by, uploader_type, created_at
+	          FROM task_attachments WHERE task_id = $1 ORDER BY id`
+
+	rows, err := r.pool.Query(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.TaskAttachment{}
+	for rows.Next() {
+		var a models.TaskAttachment
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.UploadedBy, &a.UploaderType, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// GetWithData loads attachments including their data, in the order of ids.
+// Ids that do not exist are left out.
+func (r *TaskAttachmentRepository) GetWithData(ctx context.Context, ids []int) ([]models.TaskAttachment, error) {
+	query := `SELECT id, task_id, file_name, content_type, size_bytes, data, uploaded_by, uploader_type, created_at
+	          FROM task_attachments WHERE id = ANY($1)
+	          ORDER BY array_position($1, id)`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.TaskAttachment
+	for rows.Next() {
+		var a models.TaskAttachment
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.Data, &a.UploadedBy, &a.UploaderType, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// Delete removes an attachment of a task
+func (r *TaskAttachmentRepository) Delete(ctx context.Context, taskID, id int) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM task_attachments WHERE id = $1 AND task_id = $2`, id, taskID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -145,6 +145,11 @@ func (r *TaskRepository) GetAll(ctx context.Context, filters *models.TaskFilters
 			args = append(args, *filters.ReviewerID)
 			argPos++
 		}
+		if filters.MemberUserID != nil {
+			query += fmt.Sprintf(" AND t.project_id IN (SELECT project_id FROM project_members WHERE user_id = $%d)", argPos)
+			args = append(args, *filters.MemberUserID)
+			argPos++
+		}
 	}
 
 	query += " ORDER BY t.created_at DESC"
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// usageGroupings maps a group_by value to its key and label expressions and the join providing the label
+var usageGroupings = map[string]struct {
+	key   string
+	label string
+	join  string
+}{
+	models.UsageGroupByDay:       {key: `to_char(date_trunc('day', u.created_at), 'YYYY-MM-DD')`, label: `''`},
+	models.UsageGroupByAgent:     {key: `COALESCE(u.agent_id::text, 'none')`, label: `COALESCE(MAX(a.name), '')`, join: `LEFT JOIN agents a ON a.id = u.agent_id`},
+	models.UsageGroupByModel:     {key: `u.provider || '/' || u.model`, label: `''`},
+	models.UsageGroupByTask:      {key: `COALESCE(u.task_id::text, 'none')`, label: `COALESCE(MAX(t.title), '')`, join: `LEFT JOIN tasks t ON t.id = u.task_id`},
+	models.UsageGroupByOperation: {key: `u.operation`, label: `''`},
+	models.UsageGroupByPromptTemplate: {
+		key:   `COALESCE(u.prompt_template_id::text, 'none')`,
+		label: `COALESCE(MAX(pt.role || CASE WHEN pt.level <> '' THEN '/' || pt.level ELSE '' END || ' v' || pt.version), '')`,
+		join:  `LEFT JOIN prompt_templates pt ON pt.id = u.prompt_template_id`,
+	},
+	models.UsageGroupByAPIKey: {
+		key:   `COALESCE(NULLIF(u.api_key_masked, ''), 'none')`,
+		label: `COALESCE(MAX(k.label), '')`,
+		join:  `LEFT JOIN agent_api_keys k ON k.id = u.api_key_id`,
+	},
+}
+
+const usageAggregates = `COUNT(*),
+	COUNT(*) FILTER (WHERE u.status = 'error'),
+	COALESCE(SUM(u.input_tokens), 0),
+	COALESCE(SUM(u.output_tokens), 0),
+	COALESCE(SUM(u.total_tokens), 0),
+	COALESCE(SUM(u.cost), 0)::float8,
+	COALESCE(AVG(u.latency_ms), 0)::float8`
+
+type UsageRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewUsageRepository(pool *pgxpool.Pool) *UsageRepository {
+	return &UsageRepository{
+		pool: pool,
+	}
+}
+
+// Create appends a provider call to the ledger and adds it to the agent's lifetime counters
+// in the same statement, so the counters never drift from the ledger. Calls billed to a
+// project have no agent.
+func (r *UsageRepository) Create(ctx context.Context, usage *models.LLMUsage) error {
+	query := `WITH inserted AS (
+	              INSERT INTO llm_usage (agent_id, project_id, task_id, assignment_id, provider, model,
+	                                     input_tokens, output_tokens, total_tokens, cost, latency_ms, status, error,
+	                                     prompt_template_id, operation, api_key_id, api_key_masked)
+	              VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	              RETURNING id, agent_id, api_key_id, total_tokens, cost, created_at
+	          ), counters AS (
+	              UPDATE agents a
+	              SET total_tokens_used = a.total_tokens_used + i.total_tokens,
+	                  total_cost = a.total_cost + i.cost,
+	                  total_requests = a.total_requests + 1,
+	                  last_active_at = i.created_at,
+	                  updated_at = NOW()
+	              FROM inserted i
+	              WHERE a.id = i.agent_id
+	          ), key_counters AS (
+	              UPDATE agent_api_keys k
+	              SET total_tokens_used = k.total_tokens_used + i.total_tokens,
+	                  total_cost = k.total_cost + i.cost,
+	                  total_requests = k.total_requests + 1,
+	                  last_used_at = i.created_at
+	              FROM inserted i
+	              WHERE k.id = i.api_key_id
+	          )
+	          SELECT id, created_at FROM inserted`
+
+	return r.pool.QueryRow(ctx, query,
+		usage.AgentID, usage.ProjectID, usage.TaskID, usage.AssignmentID, usage.Provider, usage.Model,
+		usage.InputTokens, usage.OutputTokens, usage.TotalTokens, usage.Cost, usage.LatencyMs,
+		usage.Status, usage.Error, usage.PromptTemplateID, usage.Operation, usage.APIKeyID, usage.APIKeyMasked,
+	).Scan(&usage.ID, &usage.CreatedAt)
+}
+
+// GetTotals sums an agent's or, with agentID nil, a project's spending in the current day,
+// the current month and overall
+func (r *UsageRepository) GetTotals(ctx context.Context, agentID *int, projectID int) (*models.UsageTotals, error) {
+	query := `SELECT
+	              COALESCE(SUM(total_tokens) FILTER (WHERE created_at >= date_trunc('day', NOW())), 0),
+	              COALESCE(SUM(cost) FILTER (WHERE created_at >= date_trunc('day', NOW())), 0)::float8,
+	              COALESCE(SUM(total_tokens) FILTER (WHERE created_at >= date_trunc('month', NOW())), 0),
+	              COALESCE(SUM(cost) FILTER (WHERE created_at >= date_trunc('month', NOW())), 0)::float8,
+	              COALESCE(SUM(total_tokens), 0),
+	              COALESCE(SUM(cost), 0)::float8
+	          FROM llm_usage
+	          WHERE project_id = $1 AND ($2::int IS NULL OR agent_id = $2)`
+
+	var totals models.UsageTotals
+	err := r.pool.QueryRow(ctx, query, projectID, agentID).Scan(
+		&totals.Daily.Tokens, &totals.Daily.Cost,
+		&totals.Monthly.Tokens, &totals.Monthly.Cost,
+		&totals.Lifetime.Tokens, &totals.Lifetime.Cost,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &totals, nil
+}
+
+// Aggregate sums the ledger rows matching the query, grouped by query.GroupBy.
+// It returns the buckets, newest or largest first, and the overall totals.
+func (r *UsageRepository) Aggregate(ctx context.Context, query *models.UsageQuery) ([]models.UsageBucket, *models.UsageBucket, error) {
+	grouping, ok := usageGroupings[query.GroupBy]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported usage grouping: %s", query.GroupBy)
+	}
+
+	where, args := usageFilters(query)
+
+	orderBy := "SUM(u.cost) DESC, 1"
+	if query.GroupBy == models.UsageGroupByDay {
+		orderBy = "1 DESC"
+	}
+
+	bucketQuery := fmt.Sprintf(`SELECT %s, %s, %s FROM llm_usage u %s %s GROUP BY 1 ORDER BY %s`,
+		grouping.key, grouping.label, usageAggregates, grouping.join, where, orderBy)
+
+	rows, err := r.pool.Query(ctx, bucketQuery, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.UsageBucket{}
+	for rows.Next() {
+		var b models.UsageBucket
+		if err := rows.Scan(&b.Key, &b.Label, &b.Requests, &b.Errors, &b.InputTokens, &b.OutputTokens,
+			&b.TotalTokens, &b.Cost, &b.AvgLatencyMs); err != nil {
+			return nil, nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	totals := models.UsageBucket{Key: "total"}
+	totalsQuery := fmt.Sprintf(`SELECT %s FROM llm_usage u %s`, usageAggregates, where)
+	err = r.pool.QueryRow(ctx, totalsQuery, args...).Scan(&totals.Requests, &totals.Errors, &totals.InputTokens,
+		&totals.OutputTokens, &totals.TotalTokens, &totals.Cost, &totals.AvgLatencyMs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buckets, &totals, nil
+}
+
+// usageFilters builds the WHERE clause for a usage query. To is inclusive of the whole day.
+func usageFilters(query *models.UsageQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if query.ProjectID != nil {
+		add("u.project_id = $%d", *query.ProjectID)
+	}
+	if query.AgentID != nil {
+		add("u.agent_id = $%d", *query.AgentID)
+	}
+	if query.TaskID != nil {
+		add("u.task_id = $%d", *query.TaskID)
+	}
+	if query.Model != nil {
+		add("u.model = $%d", *query.Model)
+	}
+	if query.Operation != nil {
+		add("u.operation = $%d", *query.Operation)
+	}
+	if query.From != nil {
+		add("u.created_at >= $%d", *query.From)
+	}
+	if query.To != nil {
+		add("u.created_at < $%d::date + 1", *query.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupAdminRoutes(r *gin.RouterGroup, adminHandler *handler.AdminHandler, providerHandler *handler.ProviderHandler, promptTemplateHandler *handler.PromptTemplateHandler, credentialHandler *handler.ProviderCredentialHandler, jwtManager *utils.JWTManager) {
+	admin := r.Group("/admin")
+
+	// Maintenance endpoints require authentication and admin role
+	admin.Use(middleware.AuthMiddleware(jwtManager))
+	admin.Use(middleware.RoleMiddleware("admin"))
+	{
+		admin.POST("/encryption/reencrypt", adminHandler.ReencryptKeys)
+
+		// Custom OpenAI-compatible providers
+		admin.GET("/providers", providerHandler.GetCustomProviders)
+		admin.POST("/providers", providerHandler.CreateCustomProvider)
+		admin.PUT("/providers/:name", providerHandler.UpdateCustomProvider)
+		admin.DELETE("/providers/:name", providerHandler.DeleteCustomProvider)
+
+		// New versions of the default system prompts
+		admin.POST("/prompt-templates", promptTemplateHandler.CreateDefaultTemplate)
+
+		// Organization-wide provider credentials
+		admin.GET("/credentials", credentialHandler.GetOrganizationCredentials)
+		admin.POST("/credentials", credentialHandler.CreateOrganizationCredential)
+		admin.PUT("/credentials/:credentialId", credentialHandler.UpdateOrganizationCredential)
+		admin.DELETE("/credentials/:credentialId", credentialHandler.DeleteOrganizationCredential)
+		admin.POST("/credentials/:credentialId/test", credentialHandler.TestOrganizationCredential)
+		admin.GET("/credentials/:credentialId/api-keys", credentialHandler.GetOrganizationCredentialKeys)
+		admin.POST("/credentials/:credentialId/api-keys", credentialHandler.CreateOrganizationCredentialKey)
+		admin.PUT("/credentials/:credentialId/api-keys/:keyId", credentialHandler.UpdateOrganizationCredentialKey)
+		admin.DELETE("/credentials/:credentialId/api-keys/:keyId", credentialHandler.DeleteOrganizationCredentialKey)
+	}
+}
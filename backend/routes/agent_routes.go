@@ -3,22 +3,39 @@ package routes
 import (
 	"github.com/berkkaradalan/stackflow/handler"
 	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
 	"github.com/berkkaradalan/stackflow/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func setupAgentsRoutes(r *gin.RouterGroup, agentHandler *handler.AgentHandler, jwtManager *utils.JWTManager) {
+func setupAgentsRoutes(r *gin.RouterGroup, agentHandler *handler.AgentHandler, agentTokenHandler *handler.AgentTokenHandler, agentAPIKeyHandler *handler.AgentAPIKeyHandler, jwtManager *utils.JWTManager, resolver middleware.ProjectResolver) {
 	agents := r.Group("/agents")
 	agents.Use(middleware.AuthMiddleware(jwtManager))
+	viewer := middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleViewer)
+	contributor := middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleContributor)
+	maintainer := middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleMaintainer)
 	{
 		agents.POST("", agentHandler.CreateAgent)
 		agents.GET("", agentHandler.GetAllAgents)
-		agents.GET("/:id", agentHandler.GetAgentByID)
-		agents.PUT("/:id", agentHandler.UpdateAgent)
-		agents.DELETE("/:id", agentHandler.DeleteAgent)
-		agents.GET("/:id/status", agentHandler.GetAgentStatus)
-		agents.GET("/:id/workload", agentHandler.GetAgentWorkload)
-		agents.GET("/:id/performance", agentHandler.GetAgentPerformance)
-		agents.GET("/:id/health", agentHandler.HealthCheck)
+		agents.GET("/:id", viewer, agentHandler.GetAgentByID)
+		agents.PUT("/:id", maintainer, agentHandler.UpdateAgent)
+		agents.DELETE("/:id", maintainer, agentHandler.DeleteAgent)
+		agents.GET("/:id/status", viewer, agentHandler.GetAgentStatus)
+		agents.GET("/:id/workload", viewer, agentHandler.GetAgentWorkload)
+		agents.GET("/:id/performance", viewer, agentHandler.GetAgentPerformance)
+		agents.GET("/:id/events", viewer, agentHandler.GetAgentEvents)
+		agents.GET("/:id/health", contributor, agentHandler.HealthCheck)
+		agents.GET("/:id/health/history", viewer, agentHandler.GetHealthHistory)
+
+		// Machine credentials the agent uses to call the API as itself
+		agents.POST("/:id/tokens", maintainer, agentTokenHandler.CreateToken)
+		agents.GET("/:id/tokens", maintainer, agentTokenHandler.GetTokens)
+		agents.DELETE("/:id/tokens/:tokenId", maintainer, agentTokenHandler.RevokeToken)
+
+		// Extra provider keys the agent's calls rotate over
+		agents.GET("/:id/api-keys", maintainer, agentAPIKeyHandler.GetKeys)
+		agents.POST("/:id/api-keys", maintainer, agentAPIKeyHandler.CreateKey)
+		agents.PUT("/:id/api-keys/:keyId", maintainer, agentAPIKeyHandler.UpdateKey)
+		agents.DELETE("/:id/api-keys/:keyId", maintainer, agentAPIKeyHandler.DeleteKey)
 	}
-}
\ No newline at end of file
+}
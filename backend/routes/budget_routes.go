@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupBudgetRoutes(r *gin.RouterGroup, budgetHandler *handler.BudgetHandler, jwtManager *utils.JWTManager, resolver middleware.ProjectResolver) {
+	// Spending caps are read by project viewers and managed by maintainers
+	agents := r.Group("/agents")
+	agents.Use(middleware.AuthMiddleware(jwtManager))
+	agentViewer := middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleViewer)
+	agentMaintainer := middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleMaintainer)
+	{
+		agents.GET("/:id/budgets", agentViewer, budgetHandler.GetAgentBudget)
+		agents.PUT("/:id/budgets", agentMaintainer, budgetHandler.SetAgentBudget)
+		agents.DELETE("/:id/budgets/:period", agentMaintainer, budgetHandler.DeleteAgentBudget)
+	}
+
+	projects := r.Group("/projects")
+	projects.Use(middleware.AuthMiddleware(jwtManager))
+	projectViewer := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleViewer)
+	projectMaintainer := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleMaintainer)
+	{
+		projects.GET("/:id/budgets", projectViewer, budgetHandler.GetProjectBudget)
+		projects.PUT("/:id/budgets", projectMaintainer, budgetHandler.SetProjectBudget)
+		projects.DELETE("/:id/budgets/:period", projectMaintainer, budgetHandler.DeleteProjectBudget)
+	}
+}
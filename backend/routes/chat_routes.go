@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupChatRoutes(r *gin.RouterGroup, chatHandler *handler.ChatHandler, jwtManager *utils.JWTManager, agentAuth middleware.AgentTokenAuthenticator, resolver middleware.ProjectResolver) {
+	// LLM gateway (requires user or agent auth; agents may only chat as themselves)
+	agents := r.Group("/agents")
+	agents.Use(middleware.ActorAuthMiddleware(jwtManager, agentAuth))
+	agents.Use(middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleContributor))
+	{
+		agents.POST("/:id/chat", chatHandler.Chat)
+		agents.POST("/:id/chat/stream", chatHandler.ChatStream)
+		agents.GET("/:id/tools", chatHandler.GetBuiltinTools)
+	}
+}
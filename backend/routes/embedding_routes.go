@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupEmbeddingRoutes(r *gin.RouterGroup, embeddingHandler *handler.EmbeddingHandler, jwtManager *utils.JWTManager, agentAuth middleware.AgentTokenAuthenticator) {
+	// Embeddings (requires user or agent auth; project access is checked against the billed agent or project)
+	embeddings := r.Group("/embeddings")
+	embeddings.Use(middleware.ActorAuthMiddleware(jwtManager, agentAuth))
+	{
+		embeddings.POST("", embeddingHandler.CreateEmbeddings)
+	}
+}
@@ -3,34 +3,40 @@ package routes
 import (
 	"github.com/berkkaradalan/stackflow/handler"
 	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
 	"github.com/berkkaradalan/stackflow/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func setupExecutionPlanRoutes(r *gin.RouterGroup, planHandler *handler.ExecutionPlanHandler, jwtManager *utils.JWTManager) {
-	// Project execution plan endpoints (requires auth)
+func setupExecutionPlanRoutes(r *gin.RouterGroup, planHandler *handler.ExecutionPlanHandler, jwtManager *utils.JWTManager, agentAuth middleware.AgentTokenAuthenticator, resolver middleware.ProjectResolver) {
+	// Project execution plan endpoints (requires user or agent auth)
 	projects := r.Group("/projects")
-	projects.Use(middleware.AuthMiddleware(jwtManager))
+	projects.Use(middleware.ActorAuthMiddleware(jwtManager, agentAuth))
+	viewer := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleViewer)
+	contributor := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleContributor)
+	maintainer := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleMaintainer)
 	{
 		// PM Agent creates and manages execution plans
-		projects.POST("/:id/execution-plan", planHandler.CreatePlan)
-		projects.GET("/:id/execution-plan", planHandler.GetActivePlan)
-		projects.GET("/:id/execution-plans", planHandler.GetAllPlans)
-		projects.PUT("/:id/execution-plan", planHandler.UpdatePlan)
+		projects.POST("/:id/execution-plan", maintainer, planHandler.CreatePlan)
+		projects.GET("/:id/execution-plan", viewer, planHandler.GetActivePlan)
+		projects.GET("/:id/execution-plans", viewer, planHandler.GetAllPlans)
+		projects.PUT("/:id/execution-plan", maintainer, planHandler.UpdatePlan)
 
 		// Reporting endpoints
-		projects.GET("/:id/reports/daily", planHandler.GetDailyReport)
-		projects.GET("/:id/reports/weekly", planHandler.GetWeeklyReport)
-		projects.POST("/:id/reports/generate", planHandler.GenerateReport)
+		projects.GET("/:id/reports/daily", viewer, planHandler.GetDailyReport)
+		projects.GET("/:id/reports/weekly", viewer, planHandler.GetWeeklyReport)
+		projects.POST("/:id/reports/generate", contributor, planHandler.GenerateReport)
 	}
 
-	// Agent task flow endpoints (requires auth)
+	// Agent task flow endpoints (requires user or agent auth; agents may only act as themselves)
 	agents := r.Group("/agents")
-	agents.Use(middleware.AuthMiddleware(jwtManager))
+	agents.Use(middleware.ActorAuthMiddleware(jwtManager, agentAuth))
+	agents.Use(middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleContributor))
 	{
 		// Developer/QA bots request tasks and report completion
 		agents.GET("/:id/next-task", planHandler.GetNextTask)
 		agents.POST("/:id/task-complete", planHandler.TaskComplete)
+		agents.POST("/:id/assignments/:assignmentId/heartbeat", planHandler.HeartbeatAssignment)
 		agents.GET("/:id/context", planHandler.GetAgentContext)
 	}
 }
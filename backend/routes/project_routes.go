@@ -3,21 +3,31 @@ package routes
 import (
 	"github.com/berkkaradalan/stackflow/handler"
 	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
 	"github.com/berkkaradalan/stackflow/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func setupProjectRoutes(r *gin.RouterGroup, projectHandler *handler.ProjectHandler, agentHandler *handler.AgentHandler, jwtManager *utils.JWTManager) {
+func setupProjectRoutes(r *gin.RouterGroup, projectHandler *handler.ProjectHandler, projectMemberHandler *handler.ProjectMemberHandler, agentHandler *handler.AgentHandler, jwtManager *utils.JWTManager, resolver middleware.ProjectResolver) {
 	projects := r.Group("/projects")
 
 	projects.Use(middleware.AuthMiddleware(jwtManager))
+	viewer := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleViewer)
+	maintainer := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleMaintainer)
+	owner := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleOwner)
 	{
 		projects.GET("", projectHandler.GetAllProjects)
 		projects.POST("", projectHandler.CreateProject)
-		projects.GET("/:id", projectHandler.GetProjectByID)
-		projects.PUT("/:id", projectHandler.UpdateProject)
-		projects.DELETE("/:id", projectHandler.DeleteProject)
-		projects.GET("/:id/stats", projectHandler.GetProjectStats)
-		projects.GET("/:id/agents", agentHandler.GetAgentsByProjectID)
+		projects.GET("/:id", viewer, projectHandler.GetProjectByID)
+		projects.PUT("/:id", maintainer, projectHandler.UpdateProject)
+		projects.DELETE("/:id", owner, projectHandler.DeleteProject)
+		projects.GET("/:id/stats", viewer, projectHandler.GetProjectStats)
+		projects.GET("/:id/agents", viewer, agentHandler.GetAgentsByProjectID)
+
+		// Membership management
+		projects.GET("/:id/members", viewer, projectMemberHandler.GetMembers)
+		projects.POST("/:id/members", owner, projectMemberHandler.AddMember)
+		projects.PUT("/:id/members/:userId", owner, projectMemberHandler.UpdateMemberRole)
+		projects.DELETE("/:id/members/:userId", owner, projectMemberHandler.RemoveMember)
 	}
-}
\ No newline at end of file
+}
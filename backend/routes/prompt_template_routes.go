@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupPromptTemplateRoutes(r *gin.RouterGroup, promptTemplateHandler *handler.PromptTemplateHandler, jwtManager *utils.JWTManager, resolver middleware.ProjectResolver) {
+	// Default templates are readable by every user; new versions are saved by admins under /admin
+	defaults := r.Group("/prompt-templates")
+	defaults.Use(middleware.AuthMiddleware(jwtManager))
+	{
+		defaults.GET("", promptTemplateHandler.GetDefaultTemplates)
+		defaults.GET("/versions", promptTemplateHandler.GetDefaultVersions)
+		defaults.GET("/diff", promptTemplateHandler.DiffDefaults)
+	}
+
+	// Project overrides are read by project viewers and managed by maintainers
+	projects := r.Group("/projects")
+	projects.Use(middleware.AuthMiddleware(jwtManager))
+	projectViewer := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleViewer)
+	projectMaintainer := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleMaintainer)
+	{
+		projects.GET("/:id/prompt-templates", projectViewer, promptTemplateHandler.GetProjectTemplates)
+		projects.GET("/:id/prompt-templates/versions", projectViewer, promptTemplateHandler.GetProjectVersions)
+		projects.GET("/:id/prompt-templates/diff", projectViewer, promptTemplateHandler.DiffProjectTemplates)
+		projects.POST("/:id/prompt-templates", projectMaintainer, promptTemplateHandler.CreateProjectTemplate)
+	}
+
+	agents := r.Group("/agents")
+	agents.Use(middleware.AuthMiddleware(jwtManager))
+	{
+		agents.GET("/:id/prompt", middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleViewer), promptTemplateHandler.GetAgentPrompt)
+	}
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupProviderCredentialRoutes(r *gin.RouterGroup, credentialHandler *handler.ProviderCredentialHandler, jwtManager *utils.JWTManager, resolver middleware.ProjectResolver) {
+	// Project credentials are managed by project owners; organization-wide ones by admins under /admin
+	projects := r.Group("/projects")
+	projects.Use(middleware.AuthMiddleware(jwtManager))
+	projectOwner := middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleOwner)
+	{
+		projects.GET("/:id/credentials", projectOwner, credentialHandler.GetProjectCredentials)
+		projects.POST("/:id/credentials", projectOwner, credentialHandler.CreateProjectCredential)
+		projects.PUT("/:id/credentials/:credentialId", projectOwner, credentialHandler.UpdateProjectCredential)
+		projects.DELETE("/:id/credentials/:credentialId", projectOwner, credentialHandler.DeleteProjectCredential)
+		projects.POST("/:id/credentials/:credentialId/test", projectOwner, credentialHandler.TestProjectCredential)
+		projects.GET("/:id/credentials/:credentialId/api-keys", projectOwner, credentialHandler.GetProjectCredentialKeys)
+		projects.POST("/:id/credentials/:credentialId/api-keys", projectOwner, credentialHandler.CreateProjectCredentialKey)
+		projects.PUT("/:id/credentials/:credentialId/api-keys/:keyId", projectOwner, credentialHandler.UpdateProjectCredentialKey)
+		projects.DELETE("/:id/credentials/:credentialId/api-keys/:keyId", projectOwner, credentialHandler.DeleteProjectCredentialKey)
+	}
+}
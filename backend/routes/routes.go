@@ -4,11 +4,12 @@ import (
 	"net/http"
 
 	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/middleware"
 	"github.com/berkkaradalan/stackflow/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(jwtManager *utils.JWTManager, authHandler *handler.AuthHandler, userHandler *handler.UserHandler, projectHandler *handler.ProjectHandler, agentHandler *handler.AgentHandler, providerHandler *handler.ProviderHandler, taskHandler *handler.TaskHandler, executionPlanHandler *handler.ExecutionPlanHandler) *gin.Engine {
+func SetupRouter(jwtManager *utils.JWTManager, authHandler *handler.AuthHandler, userHandler *handler.UserHandler, projectHandler *handler.ProjectHandler, agentHandler *handler.AgentHandler, providerHandler *handler.ProviderHandler, taskHandler *handler.TaskHandler, executionPlanHandler *handler.ExecutionPlanHandler, agentTokenHandler *handler.AgentTokenHandler, agentAPIKeyHandler *handler.AgentAPIKeyHandler, projectMemberHandler *handler.ProjectMemberHandler, adminHandler *handler.AdminHandler, chatHandler *handler.ChatHandler, usageHandler *handler.UsageHandler, budgetHandler *handler.BudgetHandler, promptTemplateHandler *handler.PromptTemplateHandler, embeddingHandler *handler.EmbeddingHandler, credentialHandler *handler.ProviderCredentialHandler, agentAuth middleware.AgentTokenAuthenticator, resolver middleware.ProjectResolver) *gin.Engine {
 	router := gin.New()
 	// Add logger and recovery middleware
 	router.Use(gin.Logger())
@@ -69,13 +70,20 @@ func SetupRouter(jwtManager *utils.JWTManager, authHandler *handler.AuthHandler,
 	{
 		setupAuthRoutes(api, authHandler, jwtManager)
 		setupUserRoutes(api, userHandler, jwtManager)
-		setupProjectRoutes(api, projectHandler, agentHandler, jwtManager)
-		setupTaskRoutes(api, taskHandler, jwtManager)
-		setupAgentsRoutes(api, agentHandler, jwtManager)
+		setupProjectRoutes(api, projectHandler, projectMemberHandler, agentHandler, jwtManager, resolver)
+		setupTaskRoutes(api, taskHandler, jwtManager, agentAuth, resolver)
+		setupAgentsRoutes(api, agentHandler, agentTokenHandler, agentAPIKeyHandler, jwtManager, resolver)
 		setupProviderRoutes(api, providerHandler)
-		setupExecutionPlanRoutes(api, executionPlanHandler, jwtManager)
+		setupExecutionPlanRoutes(api, executionPlanHandler, jwtManager, agentAuth, resolver)
 		setupCodeArtifactRoutes(api)
 		setupAnalyticsRoutes(api)
+		setupAdminRoutes(api, adminHandler, providerHandler, promptTemplateHandler, credentialHandler, jwtManager)
+		setupChatRoutes(api, chatHandler, jwtManager, agentAuth, resolver)
+		setupUsageRoutes(api, usageHandler, jwtManager, resolver)
+		setupBudgetRoutes(api, budgetHandler, jwtManager, resolver)
+		setupPromptTemplateRoutes(api, promptTemplateHandler, jwtManager, resolver)
+		setupEmbeddingRoutes(api, embeddingHandler, jwtManager, agentAuth)
+		setupProviderCredentialRoutes(api, credentialHandler, jwtManager, resolver)
 	}
 
 	return router
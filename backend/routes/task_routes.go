@@ -3,44 +3,54 @@ package routes
 import (
 	"github.com/berkkaradalan/stackflow/handler"
 	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
 	"github.com/berkkaradalan/stackflow/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func setupTaskRoutes(r *gin.RouterGroup, taskHandler *handler.TaskHandler, jwtManager *utils.JWTManager) {
-	// Tasks under projects (requires auth)
+func setupTaskRoutes(r *gin.RouterGroup, taskHandler *handler.TaskHandler, jwtManager *utils.JWTManager, agentAuth middleware.AgentTokenAuthenticator, resolver middleware.ProjectResolver) {
+	// Tasks under projects (requires user or agent auth)
 	projects := r.Group("/projects")
-	projects.Use(middleware.AuthMiddleware(jwtManager))
+	projects.Use(middleware.ActorAuthMiddleware(jwtManager, agentAuth))
 	{
-		projects.GET("/:id/tasks", taskHandler.GetTasksByProject)
-		projects.POST("/:id/tasks", taskHandler.CreateTask)
+		projects.GET("/:id/tasks", middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleViewer), taskHandler.GetTasksByProject)
+		projects.POST("/:id/tasks", middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleContributor), taskHandler.CreateTask)
 	}
 
-	// Individual task endpoints (requires auth)
+	// Individual task endpoints (requires user or agent auth)
 	tasks := r.Group("/tasks")
-	tasks.Use(middleware.AuthMiddleware(jwtManager))
+	tasks.Use(middleware.ActorAuthMiddleware(jwtManager, agentAuth))
+	viewer := middleware.ProjectScope(resolver, middleware.ScopeTask, models.ProjectRoleViewer)
+	contributor := middleware.ProjectScope(resolver, middleware.ScopeTask, models.ProjectRoleContributor)
+	maintainer := middleware.ProjectScope(resolver, middleware.ScopeTask, models.ProjectRoleMaintainer)
 	{
 		// List all tasks with filters
 		tasks.GET("", taskHandler.GetAllTasks)
 
 		// CRUD
-		tasks.GET("/:id", taskHandler.GetTaskByID)
-		tasks.PUT("/:id", taskHandler.UpdateTask)
-		tasks.DELETE("/:id", taskHandler.DeleteTask)
+		tasks.GET("/:id", viewer, taskHandler.GetTaskByID)
+		tasks.PUT("/:id", contributor, taskHandler.UpdateTask)
+		tasks.DELETE("/:id", maintainer, taskHandler.DeleteTask)
 
 		// Assignment
-		tasks.POST("/:id/assign", taskHandler.AssignAgent)
-		tasks.POST("/:id/reviewer", taskHandler.SetReviewer)
+		tasks.POST("/:id/assign", contributor, taskHandler.AssignAgent)
+		tasks.POST("/:id/reviewer", contributor, taskHandler.SetReviewer)
 
 		// Status transitions
-		tasks.POST("/:id/start", taskHandler.StartTask)
-		tasks.POST("/:id/done", taskHandler.CompleteTask)
-		tasks.POST("/:id/close", taskHandler.CloseTask)
-		tasks.POST("/:id/wontdo", taskHandler.WontDoTask)
-		tasks.POST("/:id/reopen", taskHandler.ReopenTask)
+		tasks.POST("/:id/start", contributor, taskHandler.StartTask)
+		tasks.POST("/:id/done", contributor, taskHandler.CompleteTask)
+		tasks.POST("/:id/close", contributor, taskHandler.CloseTask)
+		tasks.POST("/:id/wontdo", contributor, taskHandler.WontDoTask)
+		tasks.POST("/:id/reopen", contributor, taskHandler.ReopenTask)
 
 		// Activities (AI progress)
-		tasks.GET("/:id/activities", taskHandler.GetTaskActivities)
-		tasks.POST("/:id/activities", taskHandler.AddProgress)
+		tasks.GET("/:id/activities", viewer, taskHandler.GetTaskActivities)
+		tasks.POST("/:id/activities", contributor, taskHandler.AddProgress)
+
+		// Attachments (images agents on vision models can be shown)
+		tasks.GET("/:id/attachments", viewer, taskHandler.GetAttachments)
+		tasks.POST("/:id/attachments", contributor, taskHandler.UploadAttachment)
+		tasks.GET("/:id/attachments/:attachmentId", viewer, taskHandler.DownloadAttachment)
+		tasks.DELETE("/:id/attachments/:attachmentId", contributor, taskHandler.DeleteAttachment)
 	}
 }
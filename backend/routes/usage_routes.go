@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/berkkaradalan/stackflow/handler"
+	"github.com/berkkaradalan/stackflow/middleware"
+	"github.com/berkkaradalan/stackflow/models"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupUsageRoutes(r *gin.RouterGroup, usageHandler *handler.UsageHandler, jwtManager *utils.JWTManager, resolver middleware.ProjectResolver) {
+	// Usage across all projects (admin only)
+	usage := r.Group("/usage")
+	usage.Use(middleware.AuthMiddleware(jwtManager))
+	usage.Use(middleware.RoleMiddleware("admin"))
+	{
+		usage.GET("", usageHandler.GetUsage)
+	}
+
+	projects := r.Group("/projects")
+	projects.Use(middleware.AuthMiddleware(jwtManager))
+	{
+		projects.GET("/:id/usage", middleware.ProjectScope(resolver, middleware.ScopeProject, models.ProjectRoleViewer), usageHandler.GetProjectUsage)
+	}
+
+	agents := r.Group("/agents")
+	agents.Use(middleware.AuthMiddleware(jwtManager))
+	{
+		agents.GET("/:id/usage", middleware.ProjectScope(resolver, middleware.ScopeAgent, models.ProjectRoleViewer), usageHandler.GetAgentUsage)
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+// AccessService resolves which project a route's resource belongs to, so access can be scoped per project
+type AccessService struct {
+	taskRepo   *repository.TaskRepository
+	agentRepo  *repository.AgentRepository
+	memberRepo *repository.ProjectMemberRepository
+}
+
+func NewAccessService(taskRepo *repository.TaskRepository, agentRepo *repository.AgentRepository, memberRepo *repository.ProjectMemberRepository) *AccessService {
+	return &AccessService{
+		taskRepo:   taskRepo,
+		agentRepo:  agentRepo,
+		memberRepo: memberRepo,
+	}
+}
+
+// ProjectIDForTask returns the project a task belongs to
+func (s *AccessService) ProjectIDForTask(ctx context.Context, taskID int) (int, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return 0, ErrTaskNotFound
+	}
+	return task.ProjectID, nil
+}
+
+// ProjectIDForAgent returns the project an agent belongs to
+func (s *AccessService) ProjectIDForAgent(ctx context.Context, agentID int) (int, error) {
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return 0, ErrAgentNotFound
+	}
+	return agent.ProjectID, nil
+}
+
+// ProjectRole returns the user's role in a project, or an empty string if they are not a member
+func (s *AccessService) ProjectRole(ctx context.Context, projectID int, userID int) (string, error) {
+	member, err := s.memberRepo.GetByProjectAndUser(ctx, projectID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return member.Role, nil
+}
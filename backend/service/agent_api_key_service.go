@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/config"
+	"github.com/berkkaradalan/stackflow/llm"
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/berkkaradalan/stackflow/utils"
+)
+
+var (
+	ErrAgentAPIKeyNotFound = errors.New("agent api key not found")
+	ErrInvalidAgentAPIKey  = errors.New("invalid agent api key")
+)
+
+// AgentAPIKeyService manages the key pools of agents and provider credentials and builds the
+// pools agents rotate over for calls
+type AgentAPIKeyService struct {
+	keyRepo           *repository.AgentAPIKeyRepository
+	agentRepo         *repository.AgentRepository
+	credentialService *ProviderCredentialService
+	keyRing           *utils.KeyRing
+}
+
+func NewAgentAPIKeyService(keyRepo *repository.AgentAPIKeyRepository, agentRepo *repository.AgentRepository, credentialService *ProviderCredentialService, keyRing *utils.KeyRing) *AgentAPIKeyService {
+	return &AgentAPIKeyService{
+		keyRepo:           keyRepo,
+		agentRepo:         agentRepo,
+		credentialService: credentialService,
+		keyRing:           keyRing,
+	}
+}
+
+// CreateKey adds a key for the agent's current provider to its pool
+func (s *AgentAPIKeyService) CreateKey(ctx context.Context, agentID int, req *models.CreateAgentAPIKeyRequest, userID int) (*models.AgentAPIKey, error) {
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	providerConfig := config.GetProviderByName(agent.Provider)
+	if providerConfig == nil {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotConfigured, agent.Provider)
+	}
+	if !providerConfig.RequiresAPIKey {
+		return nil, fmt.Errorf("%w: provider %s does not use api keys", ErrInvalidAgentAPIKey, agent.Provider)
+	}
+
+	encryptedKey, keyVersion, err := s.keyRing.Encrypt(req.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt api key: %w", err)
+	}
+
+	key := &models.AgentAPIKey{
+		AgentID:       &agentID,
+		Provider:      agent.Provider,
+		Label:         req.Label,
+		APIKey:        encryptedKey,
+		APIKeyVersion: keyVersion,
+		APIKeyHint:    utils.SecretHint(req.APIKey),
+		IsActive:      true,
+		CreatedBy:     userID,
+	}
+	if err := s.keyRepo.Create(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create agent api key: %w", err)
+	}
+	llm.DropKeyPool(agentKeyPool(agentID))
+
+	maskPoolKey(key)
+	return key, nil
+}
+
+// GetKeys lists an agent's pool keys with their usage
+func (s *AgentAPIKeyService) GetKeys(ctx context.Context, agentID int) (*models.AgentAPIKeyListResponse, error) {
+	if _, err := s.agentRepo.GetByID(ctx, agentID); err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	keys, err := s.keyRepo.GetByAgentID(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent api keys: %w", err)
+	}
+	for i := range keys {
+		maskPoolKey(&keys[i])
+	}
+
+	return &models.AgentAPIKeyListResponse{Keys: keys, TotalCount: len(keys)}, nil
+}
+
+// UpdateKey relabels a pool key or takes it in or out of rotation
+func (s *AgentAPIKeyService) UpdateKey(ctx context.Context, agentID, keyID int, req *models.UpdateAgentAPIKeyRequest) (*models.AgentAPIKey, error) {
+	key, err := s.keyRepo.GetByID(ctx, agentID, keyID)
+	if err != nil {
+		return nil, ErrAgentAPIKeyNotFound
+	}
+
+	if req.Label != nil {
+		key.Label = *req.Label
+	}
+	if req.IsActive != nil {
+		key.IsActive = *req.IsActive
+	}
+	if err := s.keyRepo.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to update agent api key: %w", err)
+	}
+	llm.DropKeyPool(agentKeyPool(agentID))
+
+	maskPoolKey(key)
+	return key, nil
+}
+
+// DeleteKey removes a key from an agent's pool
+func (s *AgentAPIKeyService) DeleteKey(ctx context.Context, agentID, keyID int) error {
+	deleted, err := s.keyRepo.Delete(ctx, agentID, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete agent api key: %w", err)
+	}
+	if !deleted {
+		return ErrAgentAPIKeyNotFound
+	}
+	llm.DropKeyPool(agentKeyPool(agentID))
+	return nil
+}
+
+// CreateCredentialKey adds a key to the pool of a credential in the given scope, shared by
+// every agent using the credential
+func (s *AgentAPIKeyService) CreateCredentialKey(ctx context.Context, projectID *int, credentialID int, req *models.CreateAgentAPIKeyRequest, userID int) (*models.AgentAPIKey, error) {
+	credential, err := s.credentialService.GetCredential(ctx, projectID, credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedKey, keyVersion, err := s.keyRing.Encrypt(req.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt api key: %w", err)
+	}
+
+	key := &models.AgentAPIKey{
+		CredentialID:  &credentialID,
+		Provider:      credential.Provider,
+		Label:         req.Label,
+		APIKey:        encryptedKey,
+		APIKeyVersion: keyVersion,
+		APIKeyHint:    utils.SecretHint(req.APIKey),
+		IsActive:      true,
+		CreatedBy:     userID,
+	}
+	if err := s.keyRepo.Create(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create credential api key: %w", err)
+	}
+
+	maskPoolKey(key)
+	return key, nil
+}
+
+// GetCredentialKeys lists the pool keys of a credential in the given scope with their usage
+func (s *AgentAPIKeyService) GetCredentialKeys(ctx context.Context, projectID *int, credentialID int) (*models.AgentAPIKeyListResponse, error) {
+	if _, err := s.credentialService.GetCredential(ctx, projectID, credentialID); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.keyRepo.GetByCredentialID(ctx, credentialID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential api keys: %w", err)
+	}
+	for i := range keys {
+		maskPoolKey(&keys[i])
+	}
+
+	return &models.AgentAPIKeyListResponse{Keys: keys, TotalCount: len(keys)}, nil
+}
+
+// UpdateCredentialKey relabels a credential's pool key or takes it in or out of rotation
+func (s *AgentAPIKeyService) UpdateCredentialKey(ctx context.Context, projectID *int, credentialID, keyID int, req *models.UpdateAgentAPIKeyRequest) (*models.AgentAPIKey, error) {
+	if _, err := s.credentialService.GetCredential(ctx, projectID, credentialID); err != nil {
+		return nil, err
+	}
+	key, err := s.keyRepo.GetByCredentialIDAndID(ctx, credentialID, keyID)
+	if err != nil {
+		return nil, ErrAgentAPIKeyNotFound
+	}
+
+	if req.Label != nil {
+		key.Label = *req.Label
+	}
+	if req.IsActive != nil {
+		key.IsActive = *req.IsActive
+	}
+	if err := s.keyRepo.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to update credential api key: %w", err)
+	}
+
+	maskPoolKey(key)
+	return key, nil
+}
+
+// DeleteCredentialKey removes a key from a credential's pool
+func (s *AgentAPIKeyService) DeleteCredentialKey(ctx context.Context, projectID *int, credentialID, keyID int) error {
+	if _, err := s.credentialService.GetCredential(ctx, projectID, credentialID); err != nil {
+		return err
+	}
+
+	deleted, err := s.keyRepo.DeleteForCredential(ctx, credentialID, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete credential api key: %w", err)
+	}
+	if !deleted {
+		return ErrAgentAPIKeyNotFound
+	}
+	return nil
+}
+
+// Pool returns the agent's shared key pool and its primary key. The primary key, its own or
+// its credential's, comes first, followed by the active pool keys of its credential and then
+// the agent's active pool keys of its provider. Pool keys that cannot be decrypted are left out.
+func (s *AgentAPIKeyService) Pool(ctx context.Context, agent *models.Agent) (*llm.KeyPool, llm.PoolKey, error) {
+	primary, err := s.credentialService.AgentKey(ctx, agent)
+	if err != nil {
+		return nil, llm.PoolKey{}, err
+	}
+	keys := []llm.PoolKey{primary}
+
+	var poolKeys []models.AgentAPIKey
+	if agent.CredentialID != nil {
+		credentialKeys, err := s.keyRepo.GetActiveForCredential(ctx, *agent.CredentialID)
+		if err != nil {
+			return nil, llm.PoolKey{}, fmt.Errorf("failed to get credential api keys: %w", err)
+		}
+		poolKeys = append(poolKeys, credentialKeys...)
+	}
+	agentKeys, err := s.keyRepo.GetActive(ctx, agent.ID, agent.Provider)
+	if err != nil {
+		return nil, llm.PoolKey{}, fmt.Errorf("failed to get agent api keys: %w", err)
+	}
+	poolKeys = append(poolKeys, agentKeys...)
+
+	for _, k := range poolKeys {
+		plaintext, err := s.keyRing.Decrypt(k.APIKey, k.APIKeyVersion)
+		if err != nil {
+			log.Printf("Skipping api key %d of agent %d: %v", k.ID, agent.ID, err)
+			continue
+		}
+		keys = append(keys, llm.PoolKey{ID: k.ID, Key: plaintext, Masked: utils.MaskSecret(k.APIKeyHint), CooldownUntil: k.CooldownUntil})
+	}
+
+	return llm.KeyPoolFor(agentKeyPool(agent.ID), keys), primary, nil
+}
+
+// agentKeyPool names the shared key pool of an agent
+func agentKeyPool(agentID int) string {
+	return "agent:" + strconv.Itoa(agentID)
+}
+
+// RecordRateLimit persists a pool key's cooldown so it shows in the key list and is honoured
+// by other servers. The agent's own key, ID 0, is only rested in memory.
+func (s *AgentAPIKeyService) RecordRateLimit(key llm.PoolKey, cooldownUntil time.Time) {
+	if key.ID == 0 {
+		return
+	}
+	if err := s.keyRepo.MarkRateLimited(context.Background(), key.ID, cooldownUntil); err != nil {
+		log.Printf("Failed to record rate limit of api key %d: %v", key.ID, err)
+	}
+}
+
+// maskPoolKey strips the stored key and exposes only its masked form
+func maskPoolKey(key *models.AgentAPIKey) {
+	key.APIKey = ""
+	key.APIKeyMasked = utils.MaskSecret(key.APIKeyHint)
+}
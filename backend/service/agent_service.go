@@ -1,29 +1,92 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/berkkaradalan/stackflow/config"
+	"github.com/berkkaradalan/stackflow/llm"
 	"github.com/berkkaradalan/stackflow/models"
 	"github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/berkkaradalan/stackflow/utils"
 )
 
 type AgentService struct {
-	agentRepo *repository.AgentRepository
+	agentRepo       *repository.AgentRepository
+	eventRepo       *repository.AgentEventRepository
+	healthCheckRepo *repository.AgentHealthCheckRepository
+	apiKeyRepo      *repository.AgentAPIKeyRepository
+	credentialService *ProviderCredentialService
+	budgetService   *BudgetService
+	usageRepo       *repository.UsageRepository
+	keyRing         *utils.KeyRing
+	// healthFailureThreshold is the number of consecutive failed health checks that marks an agent as error
+	healthFailureThreshold int
 }
 
-func NewAgentService(agentRepo *repository.AgentRepository) *AgentService {
+func NewAgentService(
+	agentRepo *repository.AgentRepository,
+	eventRepo *repository.AgentEventRepository,
+	healthCheckRepo *repository.AgentHealthCheckRepository,
+	apiKeyRepo *repository.AgentAPIKeyRepository,
+	credentialService *ProviderCredentialService,
+	budgetService *BudgetService,
+	usageRepo *repository.UsageRepository,
+	keyRing *utils.KeyRing,
+	healthFailureThreshold int,
+) *AgentService {
+	if healthFailureThreshold < 1 {
+		healthFailureThreshold = 1
+	}
 	return &AgentService{
-		agentRepo: agentRepo,
+		agentRepo:              agentRepo,
+		eventRepo:              eventRepo,
+		healthCheckRepo:        healthCheckRepo,
+		apiKeyRepo:             apiKeyRepo,
+		credentialService:      credentialService,
+		budgetService:          budgetService,
+		usageRepo:              usageRepo,
+		keyRing:                keyRing,
+		healthFailureThreshold: healthFailureThreshold,
 	}
 }
 
 func (s *AgentService) CreateAgent(ctx context.Context, req *models.CreateAgentRequest, userID int) (*models.Agent, error) {
+	// Set default config values if not provided
+	if req.Config.Temperature == 0 {
+		req.Config.Temperature = 0.7
+	}
+	if req.Config.MaxTokens == 0 {
+		req.Config.MaxTokens = defaultMaxTokens(req.Provider, req.Model)
+	}
+	if req.Config.TopP == 0 {
+		req.Config.TopP = 1.0
+	}
+
+	fallbacks := fallbacksFromRequest(req.FallbackModels)
+	credential, err := s.agentCredential(ctx, req.CredentialID, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAgent(req.Provider, req.Model, req.APIKey != "", credential, &req.Config, fallbacks); err != nil {
+		return nil, err
+	}
+
+	encryptedKey, keyVersion, err := s.keyRing.Encrypt(req.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt api key: %w", err)
+	}
+	if err := s.encryptFallbackKeys(fallbacks); err != nil {
+		return nil, err
+	}
+
 	agent := &models.Agent{
 		Name:        req.Name,
 		Description: req.Description,
@@ -33,8 +96,12 @@ func (s *AgentService) CreateAgent(ctx context.Context, req *models.CreateAgentR
 		Level:       req.Level,
 		Provider:    req.Provider,
 		Model:       req.Model,
-		APIKey:      req.APIKey, // TODO: Encrypt this in production
+		APIKey:        encryptedKey,
+		APIKeyVersion: keyVersion,
+		APIKeyHint:    utils.SecretHint(req.APIKey),
+		CredentialID:  req.CredentialID,
 		Config:      req.Config,
+		FallbackModels: fallbacks,
 		Status:      "idle",
 		IsActive:    true,
 		TotalTokensUsed: 0,
@@ -42,34 +109,30 @@ func (s *AgentService) CreateAgent(ctx context.Context, req *models.CreateAgentR
 		TotalRequests:   0,
 	}
 
-	// Set default config values if not provided
-	if agent.Config.Temperature == 0 {
-		agent.Config.Temperature = 0.7
-	}
-	if agent.Config.MaxTokens == 0 {
-		agent.Config.MaxTokens = 2000
-	}
-	if agent.Config.TopP == 0 {
-		agent.Config.TopP = 1.0
-	}
-
-	err := s.agentRepo.Create(ctx, agent)
+	err = s.agentRepo.Create(ctx, agent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
 	}
 
+	maskAPIKey(agent)
 	return agent, nil
 }
 
-func (s *AgentService) GetAllAgents(ctx context.Context) (*models.AgentListResponse, error) {
-	agents, err := s.agentRepo.GetAll(ctx)
+// GetAllAgents lists every agent for admins and only agents of the user's projects otherwise
+func (s *AgentService) GetAllAgents(ctx context.Context, userID int, role string) (*models.AgentListResponse, error) {
+	var agents []models.Agent
+	var err error
+	if role == "admin" {
+		agents, err = s.agentRepo.GetAll(ctx)
+	} else {
+		agents, err = s.agentRepo.GetAllForUser(ctx, userID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get agents: %w", err)
 	}
 
-	// Remove API keys from response
 	for i := range agents {
-		agents[i].APIKey = ""
+		maskAPIKey(&agents[i])
 	}
 
 	return &models.AgentListResponse{
@@ -84,6 +147,7 @@ func (s *AgentService) GetAgentByID(ctx context.Context, id int) (*models.Agent,
 		return nil, fmt.Errorf("failed to get agent: %w", err)
 	}
 
+	maskAPIKey(agent)
 	return agent, nil
 }
 
@@ -93,9 +157,8 @@ func (s *AgentService) GetAgentsByProjectID(ctx context.Context, projectID int)
 		return nil, fmt.Errorf("failed to get agents by project: %w", err)
 	}
 
-	// Remove API keys from response
 	for i := range agents {
-		agents[i].APIKey = ""
+		maskAPIKey(&agents[i])
 	}
 
 	return &models.AgentListResponse{
@@ -106,11 +169,52 @@ func (s *AgentService) GetAgentsByProjectID(ctx context.Context, projectID int)
 
 func (s *AgentService) UpdateAgent(ctx context.Context, id int, req *models.UpdateAgentRequest) (*models.Agent, error) {
 	// First check if agent exists
-	_, err := s.agentRepo.GetByID(ctx, id)
+	existing, err := s.agentRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("agent not found: %w", err)
 	}
 
+	// Validate the provider, model, config and fallbacks the agent ends up with
+	var fallbacks []models.FallbackModel
+	if req.FallbackModels != nil {
+		fallbacks = fallbacksFromRequest(*req.FallbackModels)
+	}
+	if req.Provider != nil || req.Model != nil || req.APIKey != nil || req.CredentialID != nil || req.Config != nil || req.FallbackModels != nil {
+		provider, model, agentConfig, finalFallbacks := existing.Provider, existing.Model, existing.Config, existing.FallbackModels
+		credentialID := existing.CredentialID
+		if req.CredentialID != nil {
+			credentialID = nil
+			if *req.CredentialID != 0 {
+				credentialID = req.CredentialID
+			}
+		}
+		credential, err := s.agentCredential(ctx, credentialID, existing.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		hasAPIKey := req.APIKey != nil && *req.APIKey != ""
+		if req.APIKey == nil {
+			// Agents on keyless providers store an encrypted empty key
+			currentKey, err := s.keyRing.Decrypt(existing.APIKey, existing.APIKeyVersion)
+			hasAPIKey = err == nil && currentKey != ""
+		}
+		if req.Provider != nil {
+			provider = *req.Provider
+		}
+		if req.Model != nil {
+			model = *req.Model
+		}
+		if req.Config != nil {
+			agentConfig = *req.Config
+		}
+		if req.FallbackModels != nil {
+			finalFallbacks = fallbacks
+		}
+		if err := validateAgent(provider, model, hasAPIKey, credential, &agentConfig, finalFallbacks); err != nil {
+			return nil, err
+		}
+	}
+
 	// Build updates map
 	updates := make(map[string]interface{})
 
@@ -139,13 +243,34 @@ func (s *AgentService) UpdateAgent(ctx context.Context, id int, req *models.Upda
 	}
 
 	if req.APIKey != nil {
-		updates["api_key"] = *req.APIKey // TODO: Encrypt this in production
+		encryptedKey, keyVersion, err := s.keyRing.Encrypt(*req.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt api key: %w", err)
+		}
+		updates["api_key"] = encryptedKey
+		updates["api_key_version"] = keyVersion
+		updates["api_key_hint"] = utils.SecretHint(*req.APIKey)
+	}
+
+	if req.CredentialID != nil {
+		if *req.CredentialID == 0 {
+			updates["credential_id"] = nil
+		} else {
+			updates["credential_id"] = *req.CredentialID
+		}
 	}
 
 	if req.Config != nil {
 		updates["config"] = *req.Config
 	}
 
+	if req.FallbackModels != nil {
+		if err := s.encryptFallbackKeys(fallbacks); err != nil {
+			return nil, err
+		}
+		updates["fallback_models"] = fallbacks
+	}
+
 	if req.Status != nil {
 		updates["status"] = *req.Status
 	}
@@ -160,6 +285,7 @@ func (s *AgentService) UpdateAgent(ctx context.Context, id int, req *models.Upda
 		return nil, fmt.Errorf("failed to update agent: %w", err)
 	}
 
+	maskAPIKey(updatedAgent)
 	return updatedAgent, nil
 }
 
@@ -174,6 +300,7 @@ func (s *AgentService) DeleteAgent(ctx context.Context, id int) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete agent: %w", err)
 	}
+	llm.DropKeyPool(agentKeyPool(id))
 
 	return nil
 }
@@ -220,13 +347,18 @@ func (s *AgentService) UpdateAgentStatus(ctx context.Context, id int, status str
 	return nil
 }
 
-func (s *AgentService) IncrementAgentUsage(ctx context.Context, id int, tokensUsed int64, cost float64) error {
-	err := s.agentRepo.IncrementUsage(ctx, id, tokensUsed, cost)
+// GetAgentEvents returns the agent's most recent events
+func (s *AgentService) GetAgentEvents(ctx context.Context, id int, limit int) (*models.AgentEventListResponse, error) {
+	if _, err := s.agentRepo.GetByID(ctx, id); err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	events, err := s.eventRepo.GetByAgentID(ctx, id, limit)
 	if err != nil {
-		return fmt.Errorf("failed to increment agent usage: %w", err)
+		return nil, fmt.Errorf("failed to get agent events: %w", err)
 	}
 
-	return nil
+	return &models.AgentEventListResponse{Events: events}, nil
 }
 
 func (s *AgentService) HealthCheck(ctx context.Context, id int) (*models.AgentHealthResponse, error) {
@@ -246,148 +378,548 @@ func (s *AgentService) PerformRealHealthCheck(ctx context.Context, id int) (*mod
 		return nil, fmt.Errorf("agent not found: %w", err)
 	}
 
+	return s.checkHealth(ctx, agent, models.HealthCheckSourceManual)
+}
+
+// checkHealth tests the agent's provider, records the result in its health history and
+// updates its status. An agent is only marked as error after healthFailureThreshold
+// consecutive failures, so a single provider hiccup does not take it out. The test message
+// is a paid call, so it is refused for agents over budget and recorded in the usage ledger.
+func (s *AgentService) checkHealth(ctx context.Context, agent *models.Agent, source string) (*models.AgentHealthResponse, error) {
 	// Get provider configuration
 	providerConfig := config.GetProviderByName(agent.Provider)
 	if providerConfig == nil {
 		return nil, fmt.Errorf("provider configuration not found for: %s", agent.Provider)
 	}
 
+	if err := s.budgetService.Enforce(ctx, agent); err != nil {
+		return nil, err
+	}
+
+	apiKey, err := s.credentialService.AgentKey(ctx, agent)
+	if err != nil {
+		return nil, err
+	}
+
 	// Perform real API health check with a test message
-	isHealthy, testResponse, errorMsg := s.testProviderAPIWithMessage(providerConfig, agent)
+	started := time.Now()
+	resp, callErr := s.testProviderAPIWithMessage(ctx, providerConfig, agent, apiKey.Key)
+	latency := time.Since(started).Milliseconds()
+	s.recordHealthCheckUsage(context.WithoutCancel(ctx), agent, providerConfig, apiKey, resp, callErr, latency)
+
+	isHealthy, testResponse, errorMsg := true, "", ""
+	switch {
+	case callErr != nil:
+		isHealthy, errorMsg = false, callErr.Error()
+	case resp.Content == "":
+		isHealthy, errorMsg = false, "unable to extract AI response from API"
+	default:
+		testResponse = resp.Content
+	}
+	check := &models.AgentHealthCheck{
+		AgentID:   agent.ID,
+		Provider:  agent.Provider,
+		Model:     agent.Model,
+		Healthy:   isHealthy,
+		LatencyMs: latency,
+		Error:     errorMsg,
+		Source:    source,
+	}
+
+	failures, err := s.healthCheckRepo.Create(ctx, check)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record health check: %w", err)
+	}
 
 	// Update agent status based on test result
-	var newStatus string
+	newStatus := agent.Status
 	var message string
 
-	if isHealthy {
-		newStatus = "active"
+	switch {
+	case isHealthy:
 		message = "Agent is healthy and operational - API test successful"
-		// Update status to active
-		_ = s.agentRepo.UpdateStatus(ctx, id, newStatus)
-	} else {
-		newStatus = "error"
-		message = fmt.Sprintf("Agent health check failed: %s", errorMsg)
-		// Update status to error
-		_ = s.agentRepo.UpdateStatus(ctx, id, newStatus)
+		// Busy agents keep working; a disabled agent stays disabled until someone re-enables it
+		if agent.Status != models.AgentStatusBusy && agent.Status != models.AgentStatusDisabled {
+			newStatus = models.AgentStatusActive
+		}
+	case failures >= s.healthFailureThreshold:
+		message = fmt.Sprintf("Agent health check failed %d times in a row: %s", failures, errorMsg)
+		if agent.Status != models.AgentStatusDisabled {
+			newStatus = models.AgentStatusError
+		}
+	default:
+		message = fmt.Sprintf("Agent health check failed (%d of %d before the agent is marked as error): %s", failures, s.healthFailureThreshold, errorMsg)
+	}
+
+	if newStatus != agent.Status {
+		// The agent may have changed status, such as to busy, while its provider was tested
+		updated, err := s.agentRepo.UpdateStatusFrom(ctx, agent.ID, agent.Status, newStatus)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update agent status: %w", err)
+		}
+		if !updated {
+			current, err := s.agentRepo.GetByID(ctx, agent.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get agent status: %w", err)
+			}
+			newStatus = current.Status
+		}
+		if updated && newStatus == models.AgentStatusError {
+			event := &models.AgentEvent{
+				AgentID:   agent.ID,
+				EventType: models.AgentEventHealthCheckFailed,
+				Message:   message,
+				Data:      map[string]any{"consecutive_failures": failures, "error": errorMsg},
+			}
+			if err := s.eventRepo.Create(ctx, event); err != nil {
+				log.Printf("Failed to record health check event for agent %d: %v", agent.ID, err)
+			}
+		}
 	}
 
 	// Return health response
 	now := time.Now()
 	return &models.AgentHealthResponse{
-		ID:           agent.ID,
-		Name:         agent.Name,
-		Status:       newStatus,
-		IsActive:     agent.IsActive,
-		LastActiveAt: &now,
-		UpdatedAt:    now,
-		Healthy:      isHealthy,
-		Message:      message,
-		TestResponse: testResponse,
+		ID:                  agent.ID,
+		Name:                agent.Name,
+		Status:              newStatus,
+		IsActive:            agent.IsActive,
+		LastActiveAt:        &now,
+		UpdatedAt:           now,
+		Healthy:             isHealthy,
+		Message:             message,
+		TestResponse:        testResponse,
+		LatencyMs:           check.LatencyMs,
+		ConsecutiveFailures: failures,
 	}, nil
 }
 
-// testProviderAPIWithMessage sends a real test message to the AI and returns its response
-func (s *AgentService) testProviderAPIWithMessage(providerConfig *models.ProviderConfig, agent *models.Agent) (bool, string, string) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 15 * time.Second,
+// RunHealthChecks checks every active agent on each tick of interval until ctx is done.
+// History older than healthCheckRetention is pruned along the way.
+func (s *AgentService) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkAllAgents(ctx)
+
+			if _, err := s.healthCheckRepo.DeleteOlderThan(ctx, time.Now().Add(-healthCheckRetention)); err != nil {
+				log.Printf("Failed to prune agent health checks: %v", err)
+			}
+		}
+	}
+}
+
+// healthCheckRetention is how long health check history is kept
+const healthCheckRetention = 30 * 24 * time.Hour
+
+// healthCheckWorkers bounds how many agents are checked at once
+const healthCheckWorkers = 4
+
+func (s *AgentService) checkAllAgents(ctx context.Context) {
+	agents, err := s.agentRepo.GetHealthCheckable(ctx)
+	if err != nil {
+		log.Printf("Failed to load agents for health checks: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, healthCheckWorkers)
+	var wg sync.WaitGroup
+	for i := range agents {
+		agent := &agents[i]
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			_, err := s.checkHealth(ctx, agent, models.HealthCheckSourceScheduled)
+			var exceeded *BudgetExceededError
+			if errors.As(err, &exceeded) || errors.Is(err, ErrAgentDisabled) {
+				// Over budget, Enforce has disabled the agent; it is skipped from now on
+				return
+			}
+			if err != nil {
+				log.Printf("Health check of agent %d failed to run: %v", agent.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// GetHealthHistory returns the agent's most recent health checks
+func (s *AgentService) GetHealthHistory(ctx context.Context, id int, limit int) (*models.AgentHealthHistoryResponse, error) {
+	if _, err := s.agentRepo.GetByID(ctx, id); err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	checks, err := s.healthCheckRepo.GetByAgentID(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health checks: %w", err)
 	}
 
-	// Construct chat completion URL
-	chatURL := providerConfig.BaseURL + providerConfig.ChatCompletionPath
+	failures, err := s.healthCheckRepo.ConsecutiveFailures(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health checks: %w", err)
+	}
+
+	return &models.AgentHealthHistoryResponse{
+		Checks:              checks,
+		ConsecutiveFailures: failures,
+		FailureThreshold:    s.healthFailureThreshold,
+	}, nil
+}
 
-	// Prepare test message payload
-	payload := map[string]interface{}{
-		"model": agent.Model,
-		"messages": []map[string]string{
+// testProviderAPIWithMessage sends a real test message to the AI and returns its response
+func (s *AgentService) testProviderAPIWithMessage(ctx context.Context, providerConfig *models.ProviderConfig, agent *models.Agent, apiKey string) (*llm.ChatResponse, error) {
+	provider, err := llm.New(providerConfig, apiKey, &http.Client{Timeout: 15 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.Chat(ctx, &llm.ChatRequest{
+		Model: agent.Model,
+		Messages: []llm.Message{
 			{
-				"role":    "user",
-				"content": "This is a health check test. Please respond with a brief confirmation that you are operational.",
+				Role:    llm.RoleUser,
+				Content: "This is a health check test. Please respond with a brief confirmation that you are operational.",
 			},
 		},
-		"max_tokens":  50,
-		"temperature": 0.7,
+		MaxTokens:   50,
+		Temperature: 0.7,
+	})
+}
+
+// recordHealthCheckUsage appends a health check's test message to the usage ledger, so its
+// cost counts against the agent's budgets
+func (s *AgentService) recordHealthCheckUsage(ctx context.Context, agent *models.Agent, providerConfig *models.ProviderConfig, key llm.PoolKey, resp *llm.ChatResponse, callErr error, latencyMs int64) {
+	usage := &models.LLMUsage{
+		AgentID:      agent.ID,
+		ProjectID:    agent.ProjectID,
+		APIKeyMasked: key.Masked,
+		Provider:     agent.Provider,
+		Model:        agent.Model,
+		Operation:    models.UsageOperationHealthCheck,
+		LatencyMs:    latencyMs,
+		Status:       models.UsageStatusSuccess,
+	}
+	if callErr != nil {
+		usage.Status = models.UsageStatusError
+		usage.Error = callErr.Error()
+	}
+	if resp != nil {
+		usage.InputTokens = resp.Usage.InputTokens
+		usage.OutputTokens = resp.Usage.OutputTokens
+		usage.TotalTokens = resp.Usage.TotalTokens
+		usage.Cost = calculateCost(providerConfig, agent.Model, resp.Usage)
+	}
+
+	if err := s.usageRepo.Create(ctx, usage); err != nil {
+		log.Printf("Failed to record health check usage for agent %d: %v", agent.ID, err)
 	}
+}
 
-	// Convert payload to JSON
-	jsonPayload, err := json.Marshal(payload)
+// ReencryptAPIKeys moves every stored API key to the current master key version,
+// encrypting keys that were stored before encryption was enabled
+func (s *AgentService) ReencryptAPIKeys(ctx context.Context) (*models.ReencryptKeysResponse, error) {
+	agents, err := s.agentRepo.GetKeysNotAtVersion(ctx, s.keyRing.CurrentVersion())
 	if err != nil {
-		return false, "", fmt.Sprintf("failed to marshal payload: %v", err)
+		return nil, fmt.Errorf("failed to get agents: %w", err)
+	}
+
+	result := &models.ReencryptKeysResponse{
+		KeyVersion: s.keyRing.CurrentVersion(),
+		Failed:     []int{},
 	}
 
-	// Create request
-	req, err := http.NewRequest("POST", chatURL, bytes.NewBuffer(jsonPayload))
+	for _, agent := range agents {
+		updated, err := s.reencryptAgentKeys(ctx, &agent)
+		if err != nil {
+			result.Failed = append(result.Failed, agent.ID)
+			continue
+		}
+		if updated {
+			result.Reencrypted++
+		}
+	}
+
+	// Keys in agents' and credentials' key pools
+	var failedPoolCredentials []int
+	poolKeys, err := s.apiKeyRepo.GetKeysNotAtVersion(ctx, s.keyRing.CurrentVersion())
 	if err != nil {
-		return false, "", fmt.Sprintf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to get agent api keys: %w", err)
 	}
+	for _, key := range poolKeys {
+		hint := key.APIKeyHint
+		if key.APIKeyVersion == utils.PlaintextKeyVersion {
+			hint = utils.SecretHint(key.APIKey)
+		}
 
-	// Add authorization header
-	req.Header.Set("Authorization", "Bearer "+agent.APIKey)
-	req.Header.Set("Content-Type", "application/json")
+		encryptedKey, keyVersion, err := s.keyRing.Reencrypt(key.APIKey, key.APIKeyVersion)
+		if err == nil {
+			var updated bool
+			updated, err = s.apiKeyRepo.UpdateAPIKey(ctx, key.ID, key.APIKeyVersion, encryptedKey, keyVersion, hint)
+			if updated {
+				result.Reencrypted++
+			}
+		}
+		if err == nil {
+			continue
+		}
+		// Keys of a credential's pool are reported with the credential
+		if key.CredentialID != nil {
+			failedPoolCredentials = appendMissing(failedPoolCredentials, *key.CredentialID)
+		} else if key.AgentID != nil {
+			result.Failed = appendMissing(result.Failed, *key.AgentID)
+		}
+	}
 
-	// Perform request
-	resp, err := client.Do(req)
+	// Shared provider credentials
+	reencrypted, failedCredentials, err := s.credentialService.ReencryptKeys(ctx)
 	if err != nil {
-		return false, "", fmt.Sprintf("request failed: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	result.Reencrypted += reencrypted
+	for _, id := range failedPoolCredentials {
+		failedCredentials = appendMissing(failedCredentials, id)
+	}
+	result.FailedCredentials = failedCredentials
+
+	return result, nil
+}
+
+// appendMissing appends id unless ids already holds it
+func appendMissing(ids []int, id int) []int {
+	if slices.Contains(ids, id) {
+		return ids
+	}
+	return append(ids, id)
+}
+
+// reencryptAgentKeys moves an agent's primary and fallback keys to the current key version
+func (s *AgentService) reencryptAgentKeys(ctx context.Context, agent *models.Agent) (bool, error) {
+	current := s.keyRing.CurrentVersion()
+	updated := false
+
+	if agent.APIKeyVersion != current {
+		hint := agent.APIKeyHint
+		if agent.APIKeyVersion == utils.PlaintextKeyVersion {
+			hint = utils.SecretHint(agent.APIKey)
+		}
+
+		encryptedKey, keyVersion, err := s.keyRing.Reencrypt(agent.APIKey, agent.APIKeyVersion)
+		if err != nil {
+			return false, err
+		}
 
-	// Check response status
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return false, "", fmt.Sprintf("API returned status code: %d", resp.StatusCode)
+		updated, err = s.agentRepo.UpdateAPIKey(ctx, agent.ID, agent.APIKeyVersion, encryptedKey, keyVersion, hint)
+		if err != nil {
+			return false, err
+		}
 	}
 
-	// Parse response
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return false, "", fmt.Sprintf("failed to decode response: %v", err)
+	fallbacksChanged := false
+	for i := range agent.FallbackModels {
+		f := &agent.FallbackModels[i]
+		if f.APIKey == "" || f.APIKeyVersion == current {
+			continue
+		}
+		encryptedKey, keyVersion, err := s.keyRing.Reencrypt(f.APIKey, f.APIKeyVersion)
+		if err != nil {
+			return updated, err
+		}
+		f.APIKey, f.APIKeyVersion = encryptedKey, keyVersion
+		fallbacksChanged = true
+	}
+	if fallbacksChanged {
+		if _, err := s.agentRepo.UpdatePartial(ctx, agent.ID, map[string]interface{}{"fallback_models": agent.FallbackModels}); err != nil {
+			return updated, err
+		}
+		updated = true
 	}
 
-	// Extract AI response (OpenAI-compatible format)
-	if choices, ok := result["choices"].([]interface{}); ok && len(choices) > 0 {
-		if choice, ok := choices[0].(map[string]interface{}); ok {
-			if message, ok := choice["message"].(map[string]interface{}); ok {
-				if content, ok := message["content"].(string); ok {
-					return true, content, ""
-				}
-			}
+	return updated, nil
+}
+
+// maskAPIKey strips the stored key and exposes only its masked form
+func maskAPIKey(agent *models.Agent) {
+	agent.APIKey = ""
+	agent.APIKeyMasked = utils.MaskSecret(agent.APIKeyHint)
+
+	for i := range agent.FallbackModels {
+		f := &agent.FallbackModels[i]
+		if f.APIKey != "" {
+			f.APIKeyMasked = utils.MaskSecret(f.APIKeyHint)
 		}
+		f.APIKey = ""
 	}
+}
 
-	return false, "", "unable to extract AI response from API"
+// AgentValidationError lists every invalid field of an agent's provider, model and config
+type AgentValidationError struct {
+	Fields      []models.FieldError
+	ValidModels []string
 }
 
-// testProviderAPI tests if the provider API is accessible and working
-func (s *AgentService) testProviderAPI(providerConfig *models.ProviderConfig, apiKey string) (bool, string) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+func (e *AgentValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
 	}
+	return "invalid agent configuration: " + strings.Join(messages, "; ")
+}
 
-	// Construct health check URL
-	healthCheckURL := providerConfig.BaseURL + providerConfig.HealthCheckPath
+func (e *AgentValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, models.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
 
-	// Create request
-	req, err := http.NewRequest("GET", healthCheckURL, nil)
-	if err != nil {
-		return false, fmt.Sprintf("failed to create request: %v", err)
+// validateAgent checks the provider and model against the live registry and the config
+// against the model's limits. Providers without a model catalog accept any model.
+// A credential stands in for the agent's own key and must be for the same provider.
+func validateAgent(providerName, modelID string, hasAPIKey bool, credential *models.ProviderCredential, agentConfig *models.AgentConfig, fallbacks []models.FallbackModel) error {
+	v := &AgentValidationError{}
+	maxTokens := 0
+
+	providerConfig := config.GetProviderByName(providerName)
+	if credential != nil && credential.Provider != providerName {
+		v.add("credential_id", "is a %q credential and cannot be used with provider %q", credential.Provider, providerName)
+	}
+	if providerConfig != nil && providerConfig.RequiresAPIKey && !hasAPIKey && credential == nil {
+		v.add("api_key", "is required for provider %q unless a credential_id is given", providerName)
+	}
+	if providerConfig == nil {
+		v.add("provider", "unknown provider %q", providerName)
+	} else if len(providerConfig.Models) > 0 {
+		if model := findModel(providerConfig, modelID); model != nil && !model.Embedding {
+			maxTokens = model.MaxTokens
+		} else {
+			if model != nil {
+				v.add("model", "model %q is an embedding model and cannot chat", modelID)
+			} else {
+				v.add("model", "model %q is not available for provider %q", modelID, providerName)
+			}
+			for _, m := range providerConfig.Models {
+				if !m.Embedding {
+					v.ValidModels = append(v.ValidModels, m.ID)
+				}
+			}
+		}
 	}
 
-	// Add authorization header
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	if agentConfig.Temperature < 0 || agentConfig.Temperature > 2 {
+		v.add("config.temperature", "must be between 0 and 2")
+	}
+	if agentConfig.TopP < 0 || agentConfig.TopP > 1 {
+		v.add("config.top_p", "must be between 0 and 1")
+	}
+	if agentConfig.FrequencyPenalty < -2 || agentConfig.FrequencyPenalty > 2 {
+		v.add("config.frequency_penalty", "must be between -2 and 2")
+	}
+	if agentConfig.PresencePenalty < -2 || agentConfig.PresencePenalty > 2 {
+		v.add("config.presence_penalty", "must be between -2 and 2")
+	}
+	if agentConfig.MaxTokens < 0 {
+		v.add("config.max_tokens", "must not be negative")
+	} else if maxTokens > 0 && agentConfig.MaxTokens > maxTokens {
+		v.add("config.max_tokens", "must not exceed the model limit of %d", maxTokens)
+	}
 
-	// Perform request
-	resp, err := client.Do(req)
-	if err != nil {
-		return false, fmt.Sprintf("request failed: %v", err)
+	for i, f := range fallbacks {
+		field := fmt.Sprintf("fallback_models[%d]", i)
+		fallbackProvider := config.GetProviderByName(f.Provider)
+		if fallbackProvider == nil {
+			v.add(field+".provider", "unknown provider %q", f.Provider)
+			continue
+		}
+		if len(fallbackProvider.Models) > 0 {
+			if model := findModel(fallbackProvider, f.Model); model == nil {
+				v.add(field+".model", "model %q is not available for provider %q", f.Model, f.Provider)
+			} else if model.Embedding {
+				v.add(field+".model", "model %q is an embedding model and cannot chat", f.Model)
+			}
+		}
+		if f.Provider == providerName && f.Model == modelID {
+			v.add(field+".model", "must differ from the primary model")
+		}
+		if f.Provider != providerName && f.APIKey == "" && fallbackProvider.RequiresAPIKey {
+			v.add(field+".api_key", "is required for a fallback on another provider")
+		}
+	}
+
+	if len(v.Fields) > 0 {
+		return v
+	}
+	return nil
+}
+
+// agentCredential looks up the credential an agent of projectID references, if any. A credential
+// that does not exist or belongs to another project is reported as an invalid field.
+func (s *AgentService) agentCredential(ctx context.Context, credentialID *int, projectID int) (*models.ProviderCredential, error) {
+	if credentialID == nil {
+		return nil, nil
+	}
+
+	credential, err := s.credentialService.ForAgent(ctx, projectID, *credentialID)
+	if errors.Is(err, ErrProviderCredentialNotFound) {
+		v := &AgentValidationError{}
+		v.add("credential_id", "credential %d is not available to this project", *credentialID)
+		return nil, v
 	}
-	defer resp.Body.Close()
+	return credential, err
+}
+
+// fallbacksFromRequest converts requested fallbacks; keys stay in plaintext until encryptFallbackKeys
+func fallbacksFromRequest(reqs []models.FallbackModelRequest) []models.FallbackModel {
+	fallbacks := make([]models.FallbackModel, 0, len(reqs))
+	for _, r := range reqs {
+		fallbacks = append(fallbacks, models.FallbackModel{
+			Provider: r.Provider,
+			Model:    r.Model,
+			APIKey:   r.APIKey,
+		})
+	}
+	return fallbacks
+}
 
-	// Check response status
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return true, ""
+// encryptFallbackKeys encrypts the plaintext keys of fallbacks in place
+func (s *AgentService) encryptFallbackKeys(fallbacks []models.FallbackModel) error {
+	for i := range fallbacks {
+		if fallbacks[i].APIKey == "" {
+			continue
+		}
+		plaintext := fallbacks[i].APIKey
+		encryptedKey, keyVersion, err := s.keyRing.Encrypt(plaintext)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt fallback api key: %w", err)
+		}
+		fallbacks[i].APIKey = encryptedKey
+		fallbacks[i].APIKeyVersion = keyVersion
+		fallbacks[i].APIKeyHint = utils.SecretHint(plaintext)
 	}
+	return nil
+}
+
+// defaultMaxTokens is the max_tokens given to new agents, capped at the model limit
+func defaultMaxTokens(providerName, modelID string) int {
+	const fallback = 2000
 
-	return false, fmt.Sprintf("API returned status code: %d", resp.StatusCode)
+	providerConfig := config.GetProviderByName(providerName)
+	if providerConfig == nil {
+		return fallback
+	}
+	if model := findModel(providerConfig, modelID); model != nil && model.MaxTokens > 0 && model.MaxTokens < fallback {
+		return model.MaxTokens
+	}
+	return fallback
 }
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+)
+
+var (
+	ErrAgentTokenNotFound = errors.New("agent token not found")
+	ErrInvalidAgentToken  = errors.New("invalid or revoked agent token")
+	ErrAgentInactive      = errors.New("agent is inactive")
+)
+
+type AgentTokenService struct {
+	tokenRepo *repository.AgentTokenRepository
+	agentRepo *repository.AgentRepository
+}
+
+func NewAgentTokenService(tokenRepo *repository.AgentTokenRepository, agentRepo *repository.AgentRepository) *AgentTokenService {
+	return &AgentTokenService{
+		tokenRepo: tokenRepo,
+		agentRepo: agentRepo,
+	}
+}
+
+// CreateToken issues a new API token for an agent. The plaintext token is only returned here.
+func (s *AgentTokenService) CreateToken(ctx context.Context, agentID int, req *models.CreateAgentTokenRequest, userID int) (*models.CreateAgentTokenResponse, error) {
+	if _, err := s.agentRepo.GetByID(ctx, agentID); err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	secret, err := generateSecureToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plaintext := models.AgentTokenPrefix + strings.TrimRight(secret, "=")
+
+	token := &models.AgentToken{
+		AgentID:     agentID,
+		Name:        req.Name,
+		TokenPrefix: plaintext[:12],
+		TokenHash:   hashAgentToken(plaintext),
+		CreatedBy:   userID,
+	}
+
+	if req.ExpiresInDays != nil {
+		expiresAt := time.Now().Add(time.Duration(*req.ExpiresInDays) * 24 * time.Hour)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to create agent token: %w", err)
+	}
+
+	return &models.CreateAgentTokenResponse{
+		AgentToken: *token,
+		Token:      plaintext,
+	}, nil
+}
+
+// GetTokens lists the tokens issued for an agent
+func (s *AgentTokenService) GetTokens(ctx context.Context, agentID int) (*models.AgentTokenListResponse, error) {
+	if _, err := s.agentRepo.GetByID(ctx, agentID); err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	tokens, err := s.tokenRepo.GetByAgentID(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent tokens: %w", err)
+	}
+
+	if tokens == nil {
+		tokens = []models.AgentToken{}
+	}
+
+	return &models.AgentTokenListResponse{
+		Tokens:     tokens,
+		TotalCount: len(tokens),
+	}, nil
+}
+
+// RevokeToken revokes one of an agent's tokens
+func (s *AgentTokenService) RevokeToken(ctx context.Context, agentID int, tokenID int) error {
+	revoked, err := s.tokenRepo.Revoke(ctx, agentID, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke agent token: %w", err)
+	}
+	if !revoked {
+		return ErrAgentTokenNotFound
+	}
+
+	return nil
+}
+
+// AuthenticateAgentToken resolves a plaintext agent token to the agent it belongs to
+func (s *AgentTokenService) AuthenticateAgentToken(ctx context.Context, plaintext string) (*models.Agent, error) {
+	token, err := s.tokenRepo.GetByHash(ctx, hashAgentToken(plaintext))
+	if err != nil {
+		return nil, ErrInvalidAgentToken
+	}
+
+	if token.RevokedAt != nil {
+		return nil, ErrInvalidAgentToken
+	}
+
+	if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+		return nil, ErrInvalidAgentToken
+	}
+
+	agent, err := s.agentRepo.GetByID(ctx, token.AgentID)
+	if err != nil {
+		return nil, ErrInvalidAgentToken
+	}
+
+	if !agent.IsActive {
+		return nil, ErrAgentInactive
+	}
+
+	_ = s.tokenRepo.TouchLastUsed(ctx, token.ID)
+
+	agent.APIKey = ""
+	return agent, nil
+}
+
+// hashAgentToken returns the hex SHA-256 of a token; tokens are high-entropy so no salt is needed
+func hashAgentToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
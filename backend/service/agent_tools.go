@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/berkkaradalan/stackflow/llm"
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+var ErrInvalidToolArguments = errors.New("invalid tool arguments")
+
+// AgentToolTasks is the part of TaskService the agent tools use
+type AgentToolTasks interface {
+	GetAllTasks(ctx context.Context, filters *models.TaskFilters) (*models.TaskListResponse, error)
+	GetTaskByID(ctx context.Context, id int) (*models.TaskWithDetails, error)
+	CreateTask(ctx context.Context, projectID int, req *models.CreateTaskRequest, creatorID int, creatorType string) (*models.TaskWithDetails, error)
+	StartTask(ctx context.Context, taskID int, message string, actorID int, actorType string) (*models.TaskWithDetails, error)
+	AddProgress(ctx context.Context, taskID int, message string, actorID int, actorType string) (*models.TaskActivityWithDetails, error)
+}
+
+// AgentToolPlans is the part of ExecutionPlanService the agent tools use
+type AgentToolPlans interface {
+	GetNextTask(ctx context.Context, agentID int) (*models.NextTaskResponse, error)
+	GetAgentContext(ctx context.Context, agentID int) (*models.AgentContextResponse, error)
+	CompleteTask(ctx context.Context, agentID int, req *models.TaskCompleteRequest) (*models.AgentAssignment, error)
+}
+
+// AgentTools are the Stackflow operations an agent's model can call while chatting.
+// They run server-side as the agent and only reach tasks of the agent's project.
+type AgentTools struct {
+	taskService          AgentToolTasks
+	executionPlanService AgentToolPlans
+	tools                []agentTool
+}
+
+type agentTool struct {
+	definition llm.Tool
+	run        func(ctx context.Context, agent *models.Agent, args json.RawMessage) (any, error)
+}
+
+func NewAgentTools(taskService AgentToolTasks, executionPlanService AgentToolPlans) *AgentTools {
+	t := &AgentTools{
+		taskService:          taskService,
+		executionPlanService: executionPlanService,
+	}
+
+	t.tools = []agentTool{
+		{
+			definition: llm.Tool{
+				Name:        "get_next_task",
+				Description: "Get the next task assigned to you by the execution plan and start working on it. Returns the assignment and the plan's constraints.",
+				Parameters:  json.RawMessage(`{"type":"object","properties":{}}`),
+			},
+			run: t.getNextTask,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "get_context",
+				Description: "Read the constraints, focus areas and notes of the project's active execution plan.",
+				Parameters:  json.RawMessage(`{"type":"object","properties":{}}`),
+			},
+			run: t.getContext,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "list_tasks",
+				Description: "List the tasks of your project, optionally filtered by status and priority.",
+				Parameters: json.RawMessage(`{"type":"object","properties":{
+					"status":{"type":"string","enum":["open","in_progress","in_review","done","closed","wont_do"]},
+					"priority":{"type":"string","enum":["low","medium","high","critical"]},
+					"assigned_to_me":{"type":"boolean","description":"Only tasks assigned to you"}}}`),
+			},
+			run: t.listTasks,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "get_task",
+				Description: "Read a task of your project.",
+				Parameters:  json.RawMessage(`{"type":"object","properties":{"task_id":{"type":"integer"}},"required":["task_id"]}`),
+			},
+			run: t.getTask,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "create_task",
+				Description: "Create a task in your project.",
+				Parameters: json.RawMessage(`{"type":"object","properties":{
+					"title":{"type":"string","description":"3 to 200 characters"},
+					"description":{"type":"string"},
+					"priority":{"type":"string","enum":["low","medium","high","critical"]},
+					"tags":{"type":"array","items":{"type":"string"}}},"required":["title"]}`),
+			},
+			run: t.createTask,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "start_task",
+				Description: "Move an open task to in_progress.",
+				Parameters:  json.RawMessage(`{"type":"object","properties":{"task_id":{"type":"integer"},"message":{"type":"string"}},"required":["task_id"]}`),
+			},
+			run: t.startTask,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "add_progress",
+				Description: "Add a progress update to a task.",
+				Parameters:  json.RawMessage(`{"type":"object","properties":{"task_id":{"type":"integer"},"message":{"type":"string"}},"required":["task_id","message"]}`),
+			},
+			run: t.addProgress,
+		},
+		{
+			definition: llm.Tool{
+				Name:        "complete_assignment",
+				Description: "Report that you finished your assignment for a task. The task is marked as done, or sent to review when the plan requires code review or test coverage you did not report.",
+				Parameters: json.RawMessage(`{"type":"object","properties":{
+					"task_id":{"type":"integer"},
+					"message":{"type":"string","description":"Summary of the work"},
+					"report_data":{"type":"object","description":"Structured results, such as changed files or test results"},
+					"test_coverage":{"type":"number","description":"Percentage of your work covered by tests, from 0 to 100"}},"required":["task_id"]}`),
+			},
+			run: t.completeAssignment,
+		},
+	}
+
+	return t
+}
+
+// Definitions returns the tool definitions sent to the model
+func (t *AgentTools) Definitions() []llm.Tool {
+	definitions := make([]llm.Tool, 0, len(t.tools))
+	for _, tool := range t.tools {
+		definitions = append(definitions, tool.definition)
+	}
+	return definitions
+}
+
+// Has reports whether name is a built-in tool
+func (t *AgentTools) Has(name string) bool {
+	return t.find(name) != nil
+}
+
+// Execute runs a built-in tool call as the agent. Failures are reported in the
+// execution rather than returned, so the model can see them and recover.
+func (t *AgentTools) Execute(ctx context.Context, agent *models.Agent, call models.ChatToolCall) models.ChatToolExecution {
+	execution := models.ChatToolExecution{ID: call.ID, Name: call.Name, Arguments: call.Arguments}
+
+	tool := t.find(call.Name)
+	if tool == nil {
+		execution.Error = fmt.Sprintf("unknown tool %q", call.Name)
+		return execution
+	}
+
+	result, err := tool.run(ctx, agent, call.Arguments)
+	if err != nil {
+		execution.Error = err.Error()
+		return execution
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		execution.Error = fmt.Sprintf("failed to encode result: %v", err)
+		return execution
+	}
+	execution.Result = encoded
+	return execution
+}
+
+func (t *AgentTools) find(name string) *agentTool {
+	for i := range t.tools {
+		if t.tools[i].definition.Name == name {
+			return &t.tools[i]
+		}
+	}
+	return nil
+}
+
+func (t *AgentTools) getNextTask(ctx context.Context, agent *models.Agent, _ json.RawMessage) (any, error) {
+	return t.executionPlanService.GetNextTask(ctx, agent.ID)
+}
+
+func (t *AgentTools) getContext(ctx context.Context, agent *models.Agent, _ json.RawMessage) (any, error) {
+	return t.executionPlanService.GetAgentContext(ctx, agent.ID)
+}
+
+func (t *AgentTools) listTasks(ctx context.Context, agent *models.Agent, raw json.RawMessage) (any, error) {
+	var args struct {
+		Status       *string `json:"status"`
+		Priority     *string `json:"priority"`
+		AssignedToMe bool    `json:"assigned_to_me"`
+	}
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+
+	filters := &models.TaskFilters{ProjectID: &agent.ProjectID, Status: args.Status, Priority: args.Priority}
+	if args.AssignedToMe {
+		filters.AssignedAgentID = &agent.ID
+	}
+	return t.taskService.GetAllTasks(ctx, filters)
+}
+
+func (t *AgentTools) getTask(ctx context.Context, agent *models.Agent, raw json.RawMessage) (any, error) {
+	var args struct {
+		TaskID int `json:"task_id"`
+	}
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	return t.projectTask(ctx, agent, args.TaskID)
+}
+
+func (t *AgentTools) createTask(ctx context.Context, agent *models.Agent, raw json.RawMessage) (any, error) {
+	var args models.CreateTaskRequest
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+
+	args.Title = strings.TrimSpace(args.Title)
+	if len(args.Title) < 3 || len(args.Title) > 200 {
+		return nil, fmt.Errorf("%w: title must be 3 to 200 characters", ErrInvalidToolArguments)
+	}
+	if len(args.Description) > 2000 {
+		return nil, fmt.Errorf("%w: description must be at most 2000 characters", ErrInvalidToolArguments)
+	}
+	switch args.Priority {
+	case "", models.TaskPriorityLow, models.TaskPriorityMedium, models.TaskPriorityHigh, models.TaskPriorityCritical:
+	default:
+		return nil, fmt.Errorf("%w: unknown priority %q", ErrInvalidToolArguments, args.Priority)
+	}
+
+	return t.taskService.CreateTask(ctx, agent.ProjectID, &args, agent.ID, models.CreatorTypeAgent)
+}
+
+func (t *AgentTools) startTask(ctx context.Context, agent *models.Agent, raw json.RawMessage) (any, error) {
+	var args struct {
+		TaskID  int    `json:"task_id"`
+		Message string `json:"message"`
+	}
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	if _, err := t.projectTask(ctx, agent, args.TaskID); err != nil {
+		return nil, err
+	}
+	return t.taskService.StartTask(ctx, args.TaskID, args.Message, agent.ID, models.CreatorTypeAgent)
+}
+
+func (t *AgentTools) addProgress(ctx context.Context, agent *models.Agent, raw json.RawMessage) (any, error) {
+	var args struct {
+		TaskID  int    `json:"task_id"`
+		Message string `json:"message"`
+	}
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Message == "" || len(args.Message) > 2000 {
+		return nil, fmt.Errorf("%w: message must be 1 to 2000 characters", ErrInvalidToolArguments)
+	}
+	if _, err := t.projectTask(ctx, agent, args.TaskID); err != nil {
+		return nil, err
+	}
+	return t.taskService.AddProgress(ctx, args.TaskID, args.Message, agent.ID, models.CreatorTypeAgent)
+}
+
+func (t *AgentTools) completeAssignment(ctx context.Context, agent *models.Agent, raw json.RawMessage) (any, error) {
+	var args models.TaskCompleteRequest
+	if err := decodeToolArguments(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.TaskID == 0 {
+		return nil, fmt.Errorf("%w: task_id is required", ErrInvalidToolArguments)
+	}
+	if len(args.Message) > 2000 {
+		return nil, fmt.Errorf("%w: message must be at most 2000 characters", ErrInvalidToolArguments)
+	}
+	if args.TestCoverage != nil && (*args.TestCoverage < 0 || *args.TestCoverage > 100) {
+		return nil, fmt.Errorf("%w: test_coverage must be between 0 and 100", ErrInvalidToolArguments)
+	}
+	return t.executionPlanService.CompleteTask(ctx, agent.ID, &args)
+}
+
+// projectTask loads a task, treating tasks of other projects as missing
+func (t *AgentTools) projectTask(ctx context.Context, agent *models.Agent, taskID int) (*models.TaskWithDetails, error) {
+	if taskID == 0 {
+		return nil, fmt.Errorf("%w: task_id is required", ErrInvalidToolArguments)
+	}
+	task, err := t.taskService.GetTaskByID(ctx, taskID)
+	if err != nil || task.ProjectID != agent.ProjectID {
+		return nil, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+// decodeToolArguments unmarshals the JSON arguments of a tool call
+func decodeToolArguments(raw json.RawMessage, out any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToolArguments, err)
+	}
+	return nil
+}
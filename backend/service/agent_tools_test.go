@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/berkkaradalan/stackflow/llm"
+	"github.com/berkkaradalan/stackflow/models"
+)
+
+// fakeTasks keeps tasks in memory and records the progress the agent reports
+type fakeTasks struct {
+	tasks    map[int]*models.TaskWithDetails
+	progress []string
+}
+
+func (f *fakeTasks) GetAllTasks(ctx context.Context, filters *models.TaskFilters) (*models.TaskListResponse, error) {
+	list := &models.TaskListResponse{}
+	for _, task := range f.tasks {
+		if filters.ProjectID == nil || task.ProjectID == *filters.ProjectID {
+			list.Tasks = append(list.Tasks, *task)
+		}
+	}
+	list.TotalCount = len(list.Tasks)
+	return list, nil
+}
+
+func (f *fakeTasks) GetTaskByID(ctx context.Context, id int) (*models.TaskWithDetails, error) {
+	task, ok := f.tasks[id]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (f *fakeTasks) CreateTask(ctx context.Context, projectID int, req *models.CreateTaskRequest, creatorID int, creatorType string) (*models.TaskWithDetails, error) {
+	task := &models.TaskWithDetails{Task: models.Task{ID: len(f.tasks) + 1, ProjectID: projectID, Title: req.Title, Status: models.TaskStatusOpen}}
+	f.tasks[task.ID] = task
+	return task, nil
+}
+
+func (f *fakeTasks) StartTask(ctx context.Context, taskID int, message string, actorID int, actorType string) (*models.TaskWithDetails, error) {
+	task := f.tasks[taskID]
+	task.Status = models.TaskStatusInProgress
+	return task, nil
+}
+
+func (f *fakeTasks) AddProgress(ctx context.Context, taskID int, message string, actorID int, actorType string) (*models.TaskActivityWithDetails, error) {
+	f.progress = append(f.progress, message)
+	return &models.TaskActivityWithDetails{TaskActivity: models.TaskActivity{ID: len(f.progress), TaskID: taskID, ActorID: actorID, ActorType: actorType, Message: message}}, nil
+}
+
+// fakePlans hands out a single assignment and records its completion
+type fakePlans struct {
+	assignment *models.AgentAssignment
+	completed  *models.TaskCompleteRequest
+}
+
+func (f *fakePlans) GetNextTask(ctx context.Context, agentID int) (*models.NextTaskResponse, error) {
+	if f.assignment.Status != models.AssignmentStatusPending {
+		return &models.NextTaskResponse{Message: "No pending tasks available"}, nil
+	}
+	f.assignment.Status = models.AssignmentStatusInProgress
+	return &models.NextTaskResponse{
+		Assignment: &models.AgentAssignmentWithDetails{AgentAssignment: *f.assignment},
+		Message:    "Task assigned",
+	}, nil
+}
+
+func (f *fakePlans) GetAgentContext(ctx context.Context, agentID int) (*models.AgentContextResponse, error) {
+	return &models.AgentContextResponse{}, nil
+}
+
+func (f *fakePlans) CompleteTask(ctx context.Context, agentID int, req *models.TaskCompleteRequest) (*models.AgentAssignment, error) {
+	if req.TaskID != f.assignment.TaskID || f.assignment.Status != models.AssignmentStatusInProgress {
+		return nil, ErrAssignmentNotFound
+	}
+	f.completed = req
+	f.assignment.Status = models.AssignmentStatusCompleted
+	return f.assignment, nil
+}
+
+// chatRound sends prompt to the mock provider, executes the tool calls it answers with and
+// sends their results back, like ChatService does for built-in tools
+func chatRound(t *testing.T, provider llm.Provider, tools *AgentTools, agent *models.Agent, prompt string) []models.ChatToolExecution {
+	t.Helper()
+	ctx := context.Background()
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: prompt}}
+	resp, err := provider.Chat(ctx, &llm.ChatRequest{Model: "mock", Messages: messages, Tools: tools.Definitions()})
+	if err != nil {
+		t.Fatalf("Chat(%q): %v", prompt, err)
+	}
+	if len(resp.ToolCalls) == 0 {
+		t.Fatalf("Chat(%q) answered %q without calling a tool", prompt, resp.Content)
+	}
+
+	messages = append(messages, llm.Message{Role: llm.RoleAssistant, ToolCalls: resp.ToolCalls})
+	var executions []models.ChatToolExecution
+	for _, call := range resp.ToolCalls {
+		if !tools.Has(call.Name) {
+			t.Fatalf("model called unknown tool %q", call.Name)
+		}
+		execution := tools.Execute(ctx, agent, models.ChatToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+		executions = append(executions, execution)
+		messages = append(messages, llm.Message{Role: llm.RoleTool, ToolCallID: call.ID, Name: call.Name, Content: toolResultContent(execution)})
+	}
+
+	resp, err = provider.Chat(ctx, &llm.ChatRequest{Model: "mock", Messages: messages, Tools: tools.Definitions()})
+	if err != nil {
+		t.Fatalf("Chat with tool results: %v", err)
+	}
+	if len(resp.ToolCalls) != 0 {
+		t.Errorf("model kept calling tools after the results: %v", resp.ToolCalls)
+	}
+	return executions
+}
+
+func TestAgentToolsTaskFlowWithMockProvider(t *testing.T) {
+	provider, err := llm.New(&models.ProviderConfig{Name: "mock", APIFormat: llm.FormatMock}, "", nil)
+	if err != nil {
+		t.Fatalf("llm.New: %v", err)
+	}
+
+	agent := &models.Agent{ID: 3, ProjectID: 1}
+	tasks := &fakeTasks{tasks: map[int]*models.TaskWithDetails{
+		7: {Task: models.Task{ID: 7, ProjectID: 1, Title: "Add login", Status: models.TaskStatusOpen}},
+		8: {Task: models.Task{ID: 8, ProjectID: 2, Title: "Other project", Status: models.TaskStatusOpen}},
+	}}
+	plans := &fakePlans{assignment: &models.AgentAssignment{ID: 11, AgentID: agent.ID, TaskID: 7, Status: models.AssignmentStatusPending}}
+	tools := NewAgentTools(tasks, plans)
+
+	executions := chatRound(t, provider, tools, agent, "What should I work on next?")
+	if len(executions) != 1 || executions[0].Name != "get_next_task" || executions[0].Error != "" {
+		t.Fatalf("next task executions = %+v", executions)
+	}
+	var next models.NextTaskResponse
+	if err := json.Unmarshal(executions[0].Result, &next); err != nil || next.Assignment == nil || next.Assignment.TaskID != 7 {
+		t.Fatalf("next task result = %s, %v", executions[0].Result, err)
+	}
+
+	executions = chatRound(t, provider, tools, agent, "Report progress on task 7: login form done")
+	if len(executions) != 1 || executions[0].Name != "add_progress" || executions[0].Error != "" {
+		t.Fatalf("progress executions = %+v", executions)
+	}
+	if len(tasks.progress) != 1 || tasks.progress[0] != "login form done" {
+		t.Errorf("progress = %q, want the reported message", tasks.progress)
+	}
+
+	// Tasks of other projects are out of the agent's reach
+	executions = chatRound(t, provider, tools, agent, "Report progress on task 8: sneaky")
+	if executions[0].Error != ErrTaskNotFound.Error() || len(tasks.progress) != 1 {
+		t.Errorf("progress on another project's task = %+v, progress %q", executions[0], tasks.progress)
+	}
+
+	executions = chatRound(t, provider, tools, agent, "Complete task 7")
+	if len(executions) != 1 || executions[0].Name != "complete_assignment" || executions[0].Error != "" {
+		t.Fatalf("complete executions = %+v", executions)
+	}
+	if plans.completed == nil || plans.completed.TaskID != 7 || !strings.Contains(plans.completed.Message, "mock provider") {
+		t.Errorf("completion = %+v", plans.completed)
+	}
+	if plans.assignment.Status != models.AssignmentStatusCompleted {
+		t.Errorf("assignment status = %s, want completed", plans.assignment.Status)
+	}
+
+	executions = chatRound(t, provider, tools, agent, "What should I work on next?")
+	var none models.NextTaskResponse
+	if err := json.Unmarshal(executions[0].Result, &none); err != nil || none.Assignment != nil {
+		t.Errorf("next task after completion = %s, %v, want none", executions[0].Result, err)
+	}
+}
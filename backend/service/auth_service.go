@@ -10,21 +10,27 @@ import (
 	"github.com/berkkaradalan/stackflow/utils"
 )
 
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, all sessions from this login were revoked")
+)
+
 type AuthService struct {
-	userRepo    *repository.UserRepository
-	// cacheRepo   *redis.CacheRepository
-	jwtManager  *utils.JWTManager
+	userRepo         *repository.UserRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
+	jwtManager       *utils.JWTManager
 }
 
 func NewAuthService(
-	userRepo *repository.UserRepository, 
-	// cacheRepo *redis.CacheRepository,
+	userRepo *repository.UserRepository,
+	refreshTokenRepo *repository.RefreshTokenRepository,
 	jwtManager *utils.JWTManager,
 ) *AuthService {
 	return &AuthService{
-		userRepo:   userRepo,
-		// cacheRepo:  cacheRepo,
-		jwtManager: jwtManager,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		jwtManager:       jwtManager,
 	}
 }
 
@@ -52,11 +58,10 @@ func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*Auth
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
-	// // Store refresh token in Redis (for logout/blacklist functionality)
-	// err = s.cacheRepo.SetRefreshToken(ctx, user.ID, tokens.RefreshToken, s.jwtManager.GetRefreshExpiry())
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed to store refresh token: %w", err)
-	// }
+	// Store refresh token (for rotation, reuse detection and logout)
+	if err := s.storeRefreshToken(ctx, user.ID, tokens); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
 
 	user.PasswordHash = ""
 
@@ -70,40 +75,77 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*u
 	// Validate refresh token
 	claims, err := s.jwtManager.ValidateRefreshToken(refreshToken)
 	if err != nil {
-		return nil, errors.New("invalid refresh token")
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Check the token against the server-side store
+	stored, err := s.refreshTokenRepo.GetByTokenID(ctx, claims.ID)
+	if err != nil || stored.UserID != claims.UserID {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	// Check if refresh token exists in Redis (not revoked)
-	// storedToken, err := s.cacheRepo.GetRefreshToken(ctx, claims.UserID)
-	// if err != nil || storedToken != refreshToken {
-	// 	return nil, errors.New("refresh token revoked or expired")
-	// }
+	if stored.RevokedAt != nil {
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	// A refresh token can only be used once; presenting a rotated token means it leaked
+	if stored.UsedAt != nil {
+		_ = s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
 
 	// Get user to ensure they still exist and are active
-	user, err := s.userRepo.GetByID(ctx, claims.UserID) // You need to implement GetByID
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil || !user.IsActive {
 		return nil, errors.New("user not found or inactive")
 	}
 
-	// Generate new token pair
-	newTokens, err := s.jwtManager.GenerateTokenPair(user.ID, user.Email, user.Role)
+	// Generate new token pair in the same family
+	newTokens, err := s.jwtManager.GenerateTokenPairForFamily(user.ID, user.Email, user.Role, stored.FamilyID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Replace old refresh token with new one (rotation)
-	// err = s.cacheRepo.SetRefreshToken(ctx, user.ID, newTokens.RefreshToken, s.jwtManager.GetRefreshExpiry())
-	// if err != nil {
-	// 	return nil, err
-	// }
+	// Consume the old token and store its replacement atomically; losing the race is treated as reuse
+	rotated, err := s.refreshTokenRepo.Rotate(ctx, stored.TokenID, refreshTokenRecord(user.ID, newTokens))
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		_ = s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
 
 	return newTokens, nil
 }
 
-func (s *AuthService) Logout(ctx context.Context, userID int) error {
-	// TODO: Implement token blacklisting with Redis when cacheRepo is enabled
-	// For now, client-side token removal is sufficient
-	return nil
+// Logout revokes the session of the given refresh token, or every session of the user when none
+// is given. A token that is invalid, expired or another user's is rejected rather than taken as
+// a request to end every session.
+func (s *AuthService) Logout(ctx context.Context, userID int, refreshToken string) error {
+	if refreshToken == "" {
+		return s.refreshTokenRepo.RevokeAllForUser(ctx, userID)
+	}
+
+	claims, err := s.jwtManager.ValidateRefreshToken(refreshToken)
+	if err != nil || claims.UserID != userID {
+		return ErrInvalidRefreshToken
+	}
+	return s.refreshTokenRepo.RevokeFamily(ctx, claims.FamilyID)
+}
+
+func (s *AuthService) storeRefreshToken(ctx context.Context, userID int, tokens *utils.TokenPair) error {
+	return s.refreshTokenRepo.Create(ctx, refreshTokenRecord(userID, tokens))
+}
+
+// refreshTokenRecord is the server-side record of a token pair's refresh token
+func refreshTokenRecord(userID int, tokens *utils.TokenPair) *models.RefreshToken {
+	return &models.RefreshToken{
+		UserID:    userID,
+		TokenID:   tokens.RefreshTokenID,
+		FamilyID:  tokens.FamilyID,
+		ExpiresAt: tokens.RefreshExpiresAt,
+	}
 }
 
 func (s *AuthService) GetProfile(ctx context.Context, userID int) (*models.User, error) {
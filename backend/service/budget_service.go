@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+)
+
+var (
+	ErrBudgetNotFound     = errors.New("budget not found")
+	ErrBudgetLimitMissing = errors.New("a budget needs max_tokens, max_cost or both")
+	ErrAgentDisabled      = errors.New("agent is disabled")
+)
+
+// BudgetExceededError is returned when a call is refused because a budget is used up
+type BudgetExceededError struct {
+	Budgets []models.BudgetStatus
+}
+
+func (e *BudgetExceededError) Error() string {
+	caps := make([]string, 0, len(e.Budgets))
+	for _, b := range e.Budgets {
+		caps = append(caps, b.Scope+" "+b.Period)
+	}
+	return "budget exceeded: " + strings.Join(caps, ", ")
+}
+
+// BudgetService manages spending caps and enforces them before provider calls
+type BudgetService struct {
+	budgetRepo *repository.BudgetRepository
+	usageRepo  *repository.UsageRepository
+	agentRepo  *repository.AgentRepository
+	eventRepo  *repository.AgentEventRepository
+}
+
+func NewBudgetService(
+	budgetRepo *repository.BudgetRepository,
+	usageRepo *repository.UsageRepository,
+	agentRepo *repository.AgentRepository,
+	eventRepo *repository.AgentEventRepository,
+) *BudgetService {
+	return &BudgetService{
+		budgetRepo: budgetRepo,
+		usageRepo:  usageRepo,
+		agentRepo:  agentRepo,
+		eventRepo:  eventRepo,
+	}
+}
+
+// SetAgentBudget creates or replaces an agent's budget for a period
+func (s *BudgetService) SetAgentBudget(ctx context.Context, agentID int, req *models.SetBudgetRequest, userID int) (*models.Budget, error) {
+	if req.MaxTokens == nil && req.MaxCost == nil {
+		return nil, ErrBudgetLimitMissing
+	}
+	if _, err := s.agentRepo.GetByID(ctx, agentID); err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	budget := &models.Budget{
+		AgentID:   &agentID,
+		Period:    req.Period,
+		MaxTokens: req.MaxTokens,
+		MaxCost:   req.MaxCost,
+		CreatedBy: userID,
+	}
+	if err := s.budgetRepo.UpsertForAgent(ctx, budget); err != nil {
+		return nil, fmt.Errorf("failed to save budget: %w", err)
+	}
+
+	return budget, nil
+}
+
+// SetProjectBudget creates or replaces a project's budget for a period
+func (s *BudgetService) SetProjectBudget(ctx context.Context, projectID int, req *models.SetBudgetRequest, userID int) (*models.Budget, error) {
+	if req.MaxTokens == nil && req.MaxCost == nil {
+		return nil, ErrBudgetLimitMissing
+	}
+
+	budget := &models.Budget{
+		ProjectID: &projectID,
+		Period:    req.Period,
+		MaxTokens: req.MaxTokens,
+		MaxCost:   req.MaxCost,
+		CreatedBy: userID,
+	}
+	if err := s.budgetRepo.UpsertForProject(ctx, budget); err != nil {
+		return nil, fmt.Errorf("failed to save budget: %w", err)
+	}
+
+	return budget, nil
+}
+
+// DeleteAgentBudget removes an agent's budget for a period
+func (s *BudgetService) DeleteAgentBudget(ctx context.Context, agentID int, period string) error {
+	deleted, err := s.budgetRepo.DeleteForAgent(ctx, agentID, period)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	if !deleted {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// DeleteProjectBudget removes a project's budget for a period
+func (s *BudgetService) DeleteProjectBudget(ctx context.Context, projectID int, period string) error {
+	deleted, err := s.budgetRepo.DeleteForProject(ctx, projectID, period)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	if !deleted {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// GetAgentBudget returns the remaining budget of an agent, covering its own and its project's budgets
+func (s *BudgetService) GetAgentBudget(ctx context.Context, agentID int) (*models.BudgetResponse, error) {
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	return s.agentBudget(ctx, agent)
+}
+
+// GetProjectBudget returns the remaining budget of a project
+func (s *BudgetService) GetProjectBudget(ctx context.Context, projectID int) (*models.BudgetResponse, error) {
+	budgets, err := s.budgetRepo.GetForProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %w", err)
+	}
+
+	response := &models.BudgetResponse{ProjectID: projectID, Budgets: []models.BudgetStatus{}}
+	if len(budgets) == 0 {
+		return response, nil
+	}
+
+	totals, err := s.usageRepo.GetTotals(ctx, nil, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	for _, b := range budgets {
+		status := budgetStatus(b, totals)
+		response.Budgets = append(response.Budgets, status)
+		response.Exceeded = response.Exceeded || status.Exceeded
+	}
+	return response, nil
+}
+
+// Enforce refuses calls for agents that are disabled or over any budget. The first call
+// that finds a budget used up disables the agent and records a budget_exceeded event;
+// the agent stays disabled until its status is changed back.
+func (s *BudgetService) Enforce(ctx context.Context, agent *models.Agent) error {
+	if agent.Status == models.AgentStatusDisabled {
+		return ErrAgentDisabled
+	}
+
+	budget, err := s.agentBudget(ctx, agent)
+	if err != nil {
+		return err
+	}
+	if !budget.Exceeded {
+		return nil
+	}
+
+	exceeded := &BudgetExceededError{}
+	for _, b := range budget.Budgets {
+		if b.Exceeded {
+			exceeded.Budgets = append(exceeded.Budgets, b)
+		}
+	}
+
+	if err := s.agentRepo.UpdateStatus(ctx, agent.ID, models.AgentStatusDisabled); err != nil {
+		log.Printf("Failed to disable agent %d after exceeding its budget: %v", agent.ID, err)
+	}
+	event := &models.AgentEvent{
+		AgentID:   agent.ID,
+		EventType: models.AgentEventBudgetExceeded,
+		Message:   "Agent disabled: " + exceeded.Error(),
+		Data:      exceeded.Budgets,
+	}
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		log.Printf("Failed to record budget event for agent %d: %v", agent.ID, err)
+	}
+
+	return exceeded
+}
+
+// EnforceProject refuses project-billed calls once any of the project's budgets is used up.
+// Unlike Enforce there is no agent to disable, so nothing is changed.
+func (s *BudgetService) EnforceProject(ctx context.Context, projectID int) error {
+	budget, err := s.GetProjectBudget(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if !budget.Exceeded {
+		return nil
+	}
+
+	exceeded := &BudgetExceededError{}
+	for _, b := range budget.Budgets {
+		if b.Exceeded {
+			exceeded.Budgets = append(exceeded.Budgets, b)
+		}
+	}
+	return exceeded
+}
+
+func (s *BudgetService) agentBudget(ctx context.Context, agent *models.Agent) (*models.BudgetResponse, error) {
+	budgets, err := s.budgetRepo.GetForAgent(ctx, agent.ID, agent.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %w", err)
+	}
+
+	response := &models.BudgetResponse{AgentID: &agent.ID, ProjectID: agent.ProjectID, Budgets: []models.BudgetStatus{}}
+	if len(budgets) == 0 {
+		return response, nil
+	}
+
+	agentTotals, err := s.usageRepo.GetTotals(ctx, &agent.ID, agent.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	var projectTotals *models.UsageTotals
+	for _, b := range budgets {
+		totals := agentTotals
+		if b.AgentID == nil {
+			if projectTotals == nil {
+				if projectTotals, err = s.usageRepo.GetTotals(ctx, nil, agent.ProjectID); err != nil {
+					return nil, fmt.Errorf("failed to get usage: %w", err)
+				}
+			}
+			totals = projectTotals
+		}
+
+		status := budgetStatus(b, totals)
+		response.Budgets = append(response.Budgets, status)
+		response.Exceeded = response.Exceeded || status.Exceeded
+	}
+	return response, nil
+}
+
+// budgetStatus compares a budget with the spending in its period
+func budgetStatus(b models.Budget, totals *models.UsageTotals) models.BudgetStatus {
+	var used models.PeriodUsage
+	switch b.Period {
+	case models.BudgetPeriodDaily:
+		used = totals.Daily
+	case models.BudgetPeriodMonthly:
+		used = totals.Monthly
+	default:
+		used = totals.Lifetime
+	}
+
+	status := models.BudgetStatus{
+		Scope:      models.BudgetScopeProject,
+		Period:     b.Period,
+		MaxTokens:  b.MaxTokens,
+		MaxCost:    b.MaxCost,
+		UsedTokens: used.Tokens,
+		UsedCost:   used.Cost,
+	}
+	if b.AgentID != nil {
+		status.Scope = models.BudgetScopeAgent
+	}
+
+	if b.MaxTokens != nil {
+		remaining := max(*b.MaxTokens-used.Tokens, 0)
+		status.RemainingTokens = &remaining
+		status.Exceeded = status.Exceeded || remaining == 0
+	}
+	if b.MaxCost != nil {
+		remaining := max(*b.MaxCost-used.Cost, 0)
+		status.RemainingCost = &remaining
+		status.Exceeded = status.Exceeded || remaining == 0
+	}
+
+	return status
+}
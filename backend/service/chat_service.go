@@ -0,0 +1,655 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/config"
+	"github.com/berkkaradalan/stackflow/llm"
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/berkkaradalan/stackflow/utils"
+)
+
+var (
+	ErrProviderNotConfigured = errors.New("provider is not configured")
+	ErrStreamingNotSupported = errors.New("model does not support streaming")
+	ErrInvalidTool           = errors.New("invalid tool")
+	ErrVisionNotSupported    = errors.New("model does not support images")
+)
+
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ChatService sends chat completions through an agent's provider and model
+type ChatService struct {
+	agentRepo         *repository.AgentRepository
+	budgetService     *BudgetService
+	taskRepo          *repository.TaskRepository
+	executionPlanRepo *repository.ExecutionPlanRepository
+	usageRepo         *repository.UsageRepository
+	attachmentRepo    *repository.TaskAttachmentRepository
+	keyService        *AgentAPIKeyService
+	agentTools        *AgentTools
+	promptService     *PromptTemplateService
+	keyRing           *utils.KeyRing
+}
+
+func NewChatService(
+	agentRepo *repository.AgentRepository,
+	budgetService *BudgetService,
+	taskRepo *repository.TaskRepository,
+	executionPlanRepo *repository.ExecutionPlanRepository,
+	usageRepo *repository.UsageRepository,
+	attachmentRepo *repository.TaskAttachmentRepository,
+	keyService *AgentAPIKeyService,
+	agentTools *AgentTools,
+	promptService *PromptTemplateService,
+	keyRing *utils.KeyRing,
+) *ChatService {
+	return &ChatService{
+		agentRepo:         agentRepo,
+		budgetService:     budgetService,
+		taskRepo:          taskRepo,
+		executionPlanRepo: executionPlanRepo,
+		usageRepo:         usageRepo,
+		attachmentRepo:    attachmentRepo,
+		keyService:        keyService,
+		agentTools:        agentTools,
+		promptService:     promptService,
+		keyRing:           keyRing,
+	}
+}
+
+// chatTarget is a provider and model a call can be sent to: the agent's primary model or a fallback
+type chatTarget struct {
+	providerConfig *models.ProviderConfig
+	model          string
+	provider       llm.Provider
+	// servedBy is the key the target's last call went out with
+	servedBy *llm.PoolKey
+}
+
+// maxToolRounds bounds how often built-in tool results are fed back to the model in one request
+const maxToolRounds = 8
+
+// Chat applies the agent's config, calls its provider and records token usage and cost.
+// When the primary model is unavailable the agent's fallback models are tried in order.
+// With built-in tools enabled, tool calls are executed and answered until the model replies.
+func (s *ChatService) Chat(ctx context.Context, agentID int, req *models.ChatRequest) (*models.ChatResponse, error) {
+	agent, targets, err := s.prepare(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.newUsage(ctx, agent, req)
+	if err != nil {
+		return nil, err
+	}
+	ctx = llm.WithCassetteKey(ctx, agent.ID, usage.AssignmentID)
+
+	tools, err := s.requestTools(req)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, prompt, err := s.promptMessages(ctx, agent, req, usage)
+	if err != nil {
+		return nil, err
+	}
+	if targets, err = visionTargets(targets, messages); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.runTools(ctx, agent, req, messages, func(messages []llm.Message) (*models.ChatResponse, error) {
+		var lastErr error
+		for i, target := range targets {
+			started := time.Now()
+			resp, err := target.provider.Chat(ctx, buildChatRequest(agent, target, messages, tools))
+			result := s.recordUsage(context.WithoutCancel(ctx), usageFor(usage, target), target.providerConfig, resp, err, started)
+			if err == nil {
+				result.Fallback = i > 0
+				return result, nil
+			}
+
+			lastErr = err
+			if !llm.Unavailable(err) || ctx.Err() != nil {
+				break
+			}
+		}
+
+		return nil, lastErr
+	})
+	return withPromptTemplate(resp, prompt), err
+}
+
+// ChatStream is the streaming variant of Chat. onDelta is called for every chunk of output;
+// usage is recorded once the stream ends, including when the client goes away mid-stream.
+// Fallbacks are only tried while no output of the current round has been relayed.
+func (s *ChatService) ChatStream(ctx context.Context, agentID int, req *models.ChatRequest, onDelta func(delta string) error) (*models.ChatResponse, error) {
+	agent, targets, err := s.prepare(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if model := findModel(targets[0].providerConfig, agent.Model); model != nil && !model.SupportsStreaming {
+		return nil, fmt.Errorf("%w: %s", ErrStreamingNotSupported, agent.Model)
+	}
+
+	usage, err := s.newUsage(ctx, agent, req)
+	if err != nil {
+		return nil, err
+	}
+	ctx = llm.WithCassetteKey(ctx, agent.ID, usage.AssignmentID)
+
+	tools, err := s.requestTools(req)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, prompt, err := s.promptMessages(ctx, agent, req, usage)
+	if err != nil {
+		return nil, err
+	}
+	if targets, err = visionTargets(targets, messages); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.runTools(ctx, agent, req, messages, func(messages []llm.Message) (*models.ChatResponse, error) {
+		delivered := false
+		var lastErr error
+		for i, target := range targets {
+			if model := findModel(target.providerConfig, target.model); model != nil && !model.SupportsStreaming {
+				continue
+			}
+
+			started := time.Now()
+			resp, streamErr := target.provider.ChatStream(ctx, buildChatRequest(agent, target, messages, tools), func(chunk llm.StreamChunk) error {
+				delivered = true
+				return onDelta(chunk.Delta)
+			})
+
+			// Tokens consumed before a failure or cancellation are still billed
+			result := s.recordUsage(context.WithoutCancel(ctx), usageFor(usage, target), target.providerConfig, resp, streamErr, started)
+			if result != nil {
+				result.Fallback = i > 0
+			}
+			if streamErr == nil || delivered || !llm.Unavailable(streamErr) || ctx.Err() != nil {
+				return result, streamErr
+			}
+			lastErr = streamErr
+		}
+
+		return nil, lastErr
+	})
+	return withPromptTemplate(resp, prompt), err
+}
+
+// GetBuiltinTools lists the Stackflow operations the model can call with builtin_tools
+func (s *ChatService) GetBuiltinTools() *models.BuiltinToolListResponse {
+	definitions := s.agentTools.Definitions()
+	tools := make([]models.ChatTool, 0, len(definitions))
+	for _, d := range definitions {
+		tools = append(tools, models.ChatTool{Name: d.Name, Description: d.Description, Parameters: d.Parameters})
+	}
+	return &models.BuiltinToolListResponse{Tools: tools}
+}
+
+// requestTools returns the tools offered to the model: the client's tools and, when
+// requested, the built-in ones. Client tools may not reuse a built-in tool's name.
+func (s *ChatService) requestTools(req *models.ChatRequest) ([]llm.Tool, error) {
+	var tools []llm.Tool
+	if req.BuiltinTools {
+		tools = append(tools, s.agentTools.Definitions()...)
+	}
+
+	seen := make(map[string]bool)
+	for _, tool := range tools {
+		seen[tool.Name] = true
+	}
+	for _, tool := range req.Tools {
+		if !toolNamePattern.MatchString(tool.Name) {
+			return nil, fmt.Errorf("%w: tool name %q must be 1-64 letters, digits, '_' or '-'", ErrInvalidTool, tool.Name)
+		}
+		if seen[tool.Name] {
+			return nil, fmt.Errorf("%w: duplicate tool %q", ErrInvalidTool, tool.Name)
+		}
+		if len(tool.Parameters) > 0 && !isJSONObject(tool.Parameters) {
+			return nil, fmt.Errorf("%w: parameters of %q must be a JSON Schema object", ErrInvalidTool, tool.Name)
+		}
+		seen[tool.Name] = true
+		tools = append(tools, llm.Tool{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters})
+	}
+
+	return tools, nil
+}
+
+// runTools sends the conversation through round and, while the model calls built-in tools,
+// executes them as the agent and sends their results back. Calls to client tools end the
+// loop and are returned for the client to execute. The agent's budgets are checked again
+// before each further round, and a used-up budget ends the loop.
+func (s *ChatService) runTools(ctx context.Context, agent *models.Agent, req *models.ChatRequest, messages []llm.Message, round func([]llm.Message) (*models.ChatResponse, error)) (*models.ChatResponse, error) {
+	var total *models.ChatResponse
+	var executions []models.ChatToolExecution
+	for i := 1; ; i++ {
+		result, err := round(messages)
+		if err != nil {
+			return result, err
+		}
+		total = addRound(total, result)
+
+		if !req.BuiltinTools || len(result.ToolCalls) == 0 {
+			break
+		}
+
+		var pending []models.ChatToolCall
+		var results []llm.Message
+		for _, call := range result.ToolCalls {
+			if !s.agentTools.Has(call.Name) {
+				pending = append(pending, call)
+				continue
+			}
+
+			execution := s.agentTools.Execute(ctx, agent, call)
+			executions = append(executions, execution)
+			results = append(results, llm.Message{Role: llm.RoleTool, ToolCallID: call.ID, Name: call.Name, Content: toolResultContent(execution)})
+		}
+		total.ToolCalls = pending
+
+		if len(results) == 0 || len(pending) > 0 {
+			break
+		}
+		if i == maxToolRounds {
+			total.FinishReason = "max_tool_rounds"
+			break
+		}
+		// Every round is billed, so the spending of the rounds so far counts against the budgets
+		if err := s.budgetService.Enforce(ctx, agent); err != nil {
+			var exceeded *BudgetExceededError
+			if !errors.As(err, &exceeded) {
+				return nil, err
+			}
+			total.FinishReason = "budget_exceeded"
+			break
+		}
+
+		assistant := llm.Message{Role: llm.RoleAssistant, Content: result.Content}
+		for _, call := range result.ToolCalls {
+			assistant.ToolCalls = append(assistant.ToolCalls, llm.ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+		}
+		messages = append(append(messages, assistant), results...)
+	}
+
+	total.ToolExecutions = executions
+	return total, nil
+}
+
+// addRound folds a round of a tool conversation into the running response
+func addRound(total, round *models.ChatResponse) *models.ChatResponse {
+	if total == nil {
+		return round
+	}
+
+	round.Usage.InputTokens += total.Usage.InputTokens
+	round.Usage.OutputTokens += total.Usage.OutputTokens
+	round.Usage.TotalTokens += total.Usage.TotalTokens
+	round.Cost += total.Cost
+	round.Fallback = round.Fallback || total.Fallback
+	return round
+}
+
+// toolResultContent is the tool message sent back to the model for an execution
+func toolResultContent(execution models.ChatToolExecution) string {
+	if execution.Error != "" {
+		content, _ := json.Marshal(map[string]string{"error": execution.Error})
+		return string(content)
+	}
+	return string(execution.Result)
+}
+
+// promptMessages converts the request's messages to provider messages, preceded by the agent's
+// rendered system prompt. The template version used is recorded on the call's ledger entries.
+// Requests bringing their own system message are sent as they are.
+func (s *ChatService) promptMessages(ctx context.Context, agent *models.Agent, req *models.ChatRequest, usage *models.LLMUsage) ([]llm.Message, *models.RenderedPrompt, error) {
+	messages := chatMessages(req.Messages)
+	if err := s.attachImages(ctx, agent, req.Messages, messages); err != nil {
+		return nil, nil, err
+	}
+	for _, m := range req.Messages {
+		if m.Role == llm.RoleSystem {
+			return messages, nil, nil
+		}
+	}
+
+	prompt, err := s.promptService.Render(ctx, agent, usage.TaskID)
+	if err != nil || prompt == nil {
+		return messages, nil, err
+	}
+
+	usage.PromptTemplateID = &prompt.Template.ID
+	return append([]llm.Message{{Role: llm.RoleSystem, Content: prompt.Content}}, messages...), prompt, nil
+}
+
+// attachImages loads the task attachments named on user messages as image parts of the
+// corresponding provider messages. Attachments must belong to tasks of the agent's project.
+func (s *ChatService) attachImages(ctx context.Context, agent *models.Agent, reqMessages []models.ChatMessage, messages []llm.Message) error {
+	taskProjects := map[int]int{}
+	for i, m := range reqMessages {
+		if len(m.AttachmentIDs) == 0 {
+			continue
+		}
+		if m.Role != llm.RoleUser {
+			return fmt.Errorf("%w: only user messages can carry attachments", ErrInvalidAttachment)
+		}
+
+		attachments, err := s.attachmentRepo.GetWithData(ctx, m.AttachmentIDs)
+		if err != nil {
+			return fmt.Errorf("failed to get attachments: %w", err)
+		}
+		if len(attachments) != len(m.AttachmentIDs) {
+			return ErrAttachmentNotFound
+		}
+
+		for _, a := range attachments {
+			projectID, ok := taskProjects[a.TaskID]
+			if !ok {
+				task, err := s.taskRepo.GetByID(ctx, a.TaskID)
+				if err != nil {
+					return ErrAttachmentNotFound
+				}
+				projectID = task.ProjectID
+				taskProjects[a.TaskID] = projectID
+			}
+			if projectID != agent.ProjectID {
+				return ErrAttachmentNotFound
+			}
+
+			messages[i].Images = append(messages[i].Images, llm.Image{MediaType: a.ContentType, Data: a.Data})
+		}
+	}
+	return nil
+}
+
+// visionTargets refuses messages with images when the agent's primary model cannot see them,
+// and leaves out fallbacks that cannot
+func visionTargets(targets []chatTarget, messages []llm.Message) ([]chatTarget, error) {
+	hasImages := false
+	for _, m := range messages {
+		hasImages = hasImages || len(m.Images) > 0
+	}
+	if !hasImages {
+		return targets, nil
+	}
+
+	sees := func(target chatTarget) bool {
+		model := findModel(target.providerConfig, target.model)
+		return model != nil && model.SupportsVision
+	}
+	if !sees(targets[0]) {
+		return nil, fmt.Errorf("%w: %s", ErrVisionNotSupported, targets[0].model)
+	}
+
+	supported := make([]chatTarget, 0, len(targets))
+	for _, target := range targets {
+		if sees(target) {
+			supported = append(supported, target)
+		}
+	}
+	return supported, nil
+}
+
+// withPromptTemplate records on the response which template version prompted it
+func withPromptTemplate(resp *models.ChatResponse, prompt *models.RenderedPrompt) *models.ChatResponse {
+	if resp != nil && prompt != nil {
+		resp.PromptTemplate = &prompt.Template
+	}
+	return resp
+}
+
+// chatMessages converts the request's messages to provider messages
+func chatMessages(reqMessages []models.ChatMessage) []llm.Message {
+	messages := make([]llm.Message, 0, len(reqMessages))
+	for _, m := range reqMessages {
+		message := llm.Message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID, Name: m.Name}
+		for _, call := range m.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, llm.ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func isJSONObject(raw json.RawMessage) bool {
+	var object map[string]any
+	return json.Unmarshal(raw, &object) == nil && object != nil
+}
+
+// prepare loads the agent, enforces its budgets and builds provider clients for its
+// primary model followed by its fallbacks. Fallbacks whose provider is no longer
+// registered or that have no usable key are left out.
+func (s *ChatService) prepare(ctx context.Context, agentID int) (*models.Agent, []chatTarget, error) {
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, nil, ErrAgentNotFound
+	}
+	if !agent.IsActive {
+		return nil, nil, ErrAgentInactive
+	}
+	if err := s.budgetService.Enforce(ctx, agent); err != nil {
+		return nil, nil, err
+	}
+
+	providerConfig := config.GetProviderByName(agent.Provider)
+	if providerConfig == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrProviderNotConfigured, agent.Provider)
+	}
+
+	// The primary model rotates over the agent's key pool
+	pool, primaryKey, err := s.keyService.Pool(ctx, agent)
+	if err != nil {
+		return nil, nil, err
+	}
+	servedBy := &llm.PoolKey{}
+	provider, err := llm.NewPooled(providerConfig, pool, nil, func(key llm.PoolKey, _ error, cooldownUntil *time.Time) {
+		*servedBy = key
+		if cooldownUntil != nil {
+			s.keyService.RecordRateLimit(key, *cooldownUntil)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	targets := []chatTarget{{
+		providerConfig: providerConfig,
+		model:          agent.Model,
+		provider:       llm.WithResilience(provider, llm.DefaultRetryPolicy),
+		servedBy:       servedBy,
+	}}
+
+	for _, f := range agent.FallbackModels {
+		fallbackConfig := config.GetProviderByName(f.Provider)
+		if fallbackConfig == nil {
+			continue
+		}
+
+		fallbackKey := llm.PoolKey{}
+		switch {
+		case f.APIKey != "":
+			if fallbackKey.Key, err = s.keyRing.Decrypt(f.APIKey, f.APIKeyVersion); err != nil {
+				log.Printf("Skipping fallback %s/%s of agent %d: %v", f.Provider, f.Model, agent.ID, err)
+				continue
+			}
+			fallbackKey.Masked = utils.MaskSecret(f.APIKeyHint)
+		case f.Provider == agent.Provider:
+			fallbackKey = primaryKey
+		case fallbackConfig.RequiresAPIKey:
+			continue
+		}
+
+		target, err := newChatTarget(fallbackConfig, f.Model, fallbackKey)
+		if err != nil {
+			continue
+		}
+		targets = append(targets, target)
+	}
+
+	return agent, targets, nil
+}
+
+// newChatTarget builds a provider client for a single key with retries and the key's circuit breaker
+func newChatTarget(providerConfig *models.ProviderConfig, model string, key llm.PoolKey) (chatTarget, error) {
+	provider, err := llm.New(providerConfig, key.Key, nil)
+	if err != nil {
+		return chatTarget{}, err
+	}
+
+	return chatTarget{
+		providerConfig: providerConfig,
+		model:          model,
+		provider:       llm.WithResilience(provider, llm.DefaultRetryPolicy),
+		servedBy:       &key,
+	}, nil
+}
+
+// usageFor copies the call's ledger entry for the provider, model and key of a target
+func usageFor(base *models.LLMUsage, target chatTarget) *models.LLMUsage {
+	usage := *base
+	usage.Provider = target.providerConfig.Name
+	usage.Model = target.model
+	if target.servedBy != nil {
+		usage.APIKeyMasked = target.servedBy.Masked
+		if keyID := target.servedBy.ID; keyID != 0 {
+			usage.APIKeyID = &keyID
+		}
+	}
+	return &usage
+}
+
+// newUsage starts a ledger entry for a call, attributing it to the task or assignment
+// named in the request. Both must belong to the agent.
+func (s *ChatService) newUsage(ctx context.Context, agent *models.Agent, req *models.ChatRequest) (*models.LLMUsage, error) {
+	usage := &models.LLMUsage{
+		AgentID:   agent.ID,
+		ProjectID: agent.ProjectID,
+		Provider:  agent.Provider,
+		Model:     agent.Model,
+		Operation: models.UsageOperationChat,
+	}
+
+	if req.AssignmentID != nil {
+		assignment, err := s.executionPlanRepo.GetAssignmentByID(ctx, *req.AssignmentID)
+		if err != nil || assignment.AgentID != agent.ID {
+			return nil, ErrAssignmentNotFound
+		}
+		usage.AssignmentID = &assignment.ID
+		usage.TaskID = &assignment.TaskID
+	}
+
+	if req.TaskID != nil {
+		if usage.TaskID != nil && *usage.TaskID != *req.TaskID {
+			return nil, fmt.Errorf("%w: task does not match the assignment", ErrTaskNotFound)
+		}
+		task, err := s.taskRepo.GetByID(ctx, *req.TaskID)
+		if err != nil || task.ProjectID != agent.ProjectID {
+			return nil, ErrTaskNotFound
+		}
+		usage.TaskID = &task.ID
+	}
+
+	return usage, nil
+}
+
+// recordUsage prices the response, appends the call to the usage ledger and builds the API response.
+// Failed calls are recorded too; the response is nil when the provider returned nothing.
+func (s *ChatService) recordUsage(ctx context.Context, usage *models.LLMUsage, providerConfig *models.ProviderConfig, resp *llm.ChatResponse, callErr error, started time.Time) *models.ChatResponse {
+	// The circuit breaker refused the call, so the provider was never reached
+	if errors.Is(callErr, llm.ErrCircuitOpen) {
+		return nil
+	}
+
+	usage.LatencyMs = time.Since(started).Milliseconds()
+	usage.Status = models.UsageStatusSuccess
+	if callErr != nil {
+		usage.Status = models.UsageStatusError
+		usage.Error = callErr.Error()
+	}
+	if resp != nil {
+		usage.InputTokens = resp.Usage.InputTokens
+		usage.OutputTokens = resp.Usage.OutputTokens
+		usage.TotalTokens = resp.Usage.TotalTokens
+		usage.Cost = calculateCost(providerConfig, usage.Model, resp.Usage)
+	}
+
+	if err := s.usageRepo.Create(ctx, usage); err != nil {
+		log.Printf("Failed to record llm usage for agent %d: %v", usage.AgentID, err)
+	}
+
+	if resp == nil {
+		return nil
+	}
+
+	var toolCalls []models.ChatToolCall
+	for _, call := range resp.ToolCalls {
+		toolCalls = append(toolCalls, models.ChatToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+	}
+
+	return &models.ChatResponse{
+		AgentID:      usage.AgentID,
+		Provider:     usage.Provider,
+		Model:        resp.Model,
+		Content:      resp.Content,
+		ToolCalls:    toolCalls,
+		FinishReason: resp.FinishReason,
+		Usage: models.ChatUsage{
+			InputTokens:  resp.Usage.InputTokens,
+			OutputTokens: resp.Usage.OutputTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		},
+		Cost: usage.Cost,
+	}
+}
+
+// buildChatRequest maps the agent's config onto a provider request for the target's model.
+// max_tokens is capped at the model's limit, which may be lower for a fallback.
+func buildChatRequest(agent *models.Agent, target chatTarget, messages []llm.Message, tools []llm.Tool) *llm.ChatRequest {
+	maxTokens := agent.Config.MaxTokens
+	if model := findModel(target.providerConfig, target.model); model != nil && model.MaxTokens > 0 && maxTokens > model.MaxTokens {
+		maxTokens = model.MaxTokens
+	}
+
+	return &llm.ChatRequest{
+		Model:            target.model,
+		Messages:         messages,
+		Tools:            tools,
+		MaxTokens:        maxTokens,
+		Temperature:      agent.Config.Temperature,
+		TopP:             agent.Config.TopP,
+		FrequencyPenalty: agent.Config.FrequencyPenalty,
+		PresencePenalty:  agent.Config.PresencePenalty,
+	}
+}
+
+// calculateCost prices token usage with the model's per-million-token rates.
+// Models missing from the catalog are not priced.
+func calculateCost(providerConfig *models.ProviderConfig, modelID string, usage llm.Usage) float64 {
+	model := findModel(providerConfig, modelID)
+	if model == nil {
+		return 0
+	}
+	return float64(usage.InputTokens)*model.InputPricePerMToken/1_000_000 +
+		float64(usage.OutputTokens)*model.OutputPricePerMToken/1_000_000
+}
+
+// findModel looks a model up in the provider's catalog
+func findModel(providerConfig *models.ProviderConfig, modelID string) *models.ModelConfig {
+	for i := range providerConfig.Models {
+		if providerConfig.Models[i].ID == modelID {
+			return &providerConfig.Models[i]
+		}
+	}
+	return nil
+}
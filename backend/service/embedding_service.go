@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/config"
+	"github.com/berkkaradalan/stackflow/llm"
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/berkkaradalan/stackflow/utils"
+)
+
+var (
+	ErrInvalidEmbeddingRequest = errors.New("invalid embedding request")
+	ErrNotEmbeddingModel       = errors.New("model is not an embedding model")
+)
+
+// EmbeddingService creates embeddings through the provider layer and bills them to an agent or a project
+type EmbeddingService struct {
+	agentRepo     *repository.AgentRepository
+	projectRepo   *repository.ProjectRepository
+	budgetService *BudgetService
+	usageRepo     *repository.UsageRepository
+	keyService    *AgentAPIKeyService
+	keyRing       *utils.KeyRing
+}
+
+func NewEmbeddingService(
+	agentRepo *repository.AgentRepository,
+	projectRepo *repository.ProjectRepository,
+	budgetService *BudgetService,
+	usageRepo *repository.UsageRepository,
+	keyService *AgentAPIKeyService,
+	keyRing *utils.KeyRing,
+) *EmbeddingService {
+	return &EmbeddingService{
+		agentRepo:     agentRepo,
+		projectRepo:   projectRepo,
+		budgetService: budgetService,
+		usageRepo:     usageRepo,
+		keyService:    keyService,
+		keyRing:       keyRing,
+	}
+}
+
+// Embed embeds a batch of inputs. Agent-billed calls default to the agent's provider and
+// rotate over its key pool there; project-billed calls name the provider and bring their own key.
+// Each request is one ledger entry, failed or not.
+func (s *EmbeddingService) Embed(ctx context.Context, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	if (req.AgentID == nil) == (req.ProjectID == nil) {
+		return nil, fmt.Errorf("%w: set exactly one of agent_id and project_id", ErrInvalidEmbeddingRequest)
+	}
+
+	usage := &models.LLMUsage{Provider: req.Provider, Model: req.Model, Operation: models.UsageOperationEmbedding}
+	apiKey := req.APIKey
+	// Calls on the agent's own provider rotate over its key pool
+	var pool *llm.KeyPool
+
+	if req.AgentID != nil {
+		agent, err := s.agentRepo.GetByID(ctx, *req.AgentID)
+		if err != nil {
+			return nil, ErrAgentNotFound
+		}
+		if !agent.IsActive {
+			return nil, ErrAgentInactive
+		}
+		if err := s.budgetService.Enforce(ctx, agent); err != nil {
+			return nil, err
+		}
+
+		usage.AgentID = agent.ID
+		usage.ProjectID = agent.ProjectID
+		if usage.Provider == "" {
+			usage.Provider = agent.Provider
+		}
+		if usage.Provider == agent.Provider && apiKey == "" {
+			var primaryKey llm.PoolKey
+			if pool, primaryKey, err = s.keyService.Pool(ctx, agent); err != nil {
+				return nil, err
+			}
+			apiKey = primaryKey.Key
+		}
+	} else {
+		if _, err := s.projectRepo.GetByID(ctx, *req.ProjectID); err != nil {
+			return nil, ErrProjectNotFound
+		}
+		if usage.Provider == "" {
+			return nil, fmt.Errorf("%w: provider is required for project-billed calls", ErrInvalidEmbeddingRequest)
+		}
+		if err := s.budgetService.EnforceProject(ctx, *req.ProjectID); err != nil {
+			return nil, err
+		}
+		usage.ProjectID = *req.ProjectID
+	}
+
+	providerConfig := config.GetProviderByName(usage.Provider)
+	if providerConfig == nil {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotConfigured, usage.Provider)
+	}
+	if providerConfig.RequiresAPIKey && apiKey == "" {
+		return nil, fmt.Errorf("%w: api_key is required for provider %s", ErrInvalidEmbeddingRequest, providerConfig.Name)
+	}
+
+	model, err := embeddingModel(providerConfig, usage.Model)
+	if err != nil {
+		return nil, err
+	}
+	usage.Model = model.ID
+
+	servedBy := llm.PoolKey{Key: apiKey, Masked: utils.MaskSecret(utils.SecretHint(apiKey))}
+	var provider llm.Provider
+	if pool != nil {
+		provider, err = llm.NewPooled(providerConfig, pool, nil, func(key llm.PoolKey, _ error, cooldownUntil *time.Time) {
+			servedBy = key
+			if cooldownUntil != nil {
+				s.keyService.RecordRateLimit(key, *cooldownUntil)
+			}
+		})
+	} else {
+		provider, err = llm.New(providerConfig, apiKey, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	resp, err := llm.Embed(ctx, llm.WithResilience(provider, llm.DefaultRetryPolicy), &llm.EmbeddingRequest{
+		Model:      model.ID,
+		Input:      req.Input,
+		Dimensions: req.Dimensions,
+	})
+	if errors.Is(err, llm.ErrEmbeddingsNotSupported) {
+		return nil, err
+	}
+	usage.APIKeyMasked = servedBy.Masked
+	if servedBy.ID != 0 {
+		usage.APIKeyID = &servedBy.ID
+	}
+	s.recordUsage(ctx, usage, providerConfig, resp, err, started)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.EmbeddingResponse{
+		ProjectID:  usage.ProjectID,
+		Provider:   usage.Provider,
+		Model:      usage.Model,
+		Embeddings: make([]models.Embedding, 0, len(resp.Embeddings)),
+		Usage: models.ChatUsage{
+			InputTokens: resp.Usage.InputTokens,
+			TotalTokens: resp.Usage.TotalTokens,
+		},
+		Cost: usage.Cost,
+	}
+	if req.AgentID != nil {
+		result.AgentID = &usage.AgentID
+	}
+	for i, vector := range resp.Embeddings {
+		result.Embeddings = append(result.Embeddings, models.Embedding{Index: i, Embedding: vector})
+	}
+	if len(resp.Embeddings) > 0 {
+		result.Dimensions = len(resp.Embeddings[0])
+	}
+
+	return result, nil
+}
+
+// recordUsage appends the call to the usage ledger. Embeddings are priced by input tokens only.
+func (s *EmbeddingService) recordUsage(ctx context.Context, usage *models.LLMUsage, providerConfig *models.ProviderConfig, resp *llm.EmbeddingResponse, callErr error, started time.Time) {
+	// The circuit breaker refused the call, so the provider was never reached
+	if errors.Is(callErr, llm.ErrCircuitOpen) {
+		return
+	}
+
+	usage.LatencyMs = time.Since(started).Milliseconds()
+	usage.Status = models.UsageStatusSuccess
+	if callErr != nil {
+		usage.Status = models.UsageStatusError
+		usage.Error = callErr.Error()
+	}
+	if resp != nil {
+		usage.InputTokens = resp.Usage.InputTokens
+		usage.TotalTokens = resp.Usage.TotalTokens
+		usage.Cost = calculateCost(providerConfig, usage.Model, resp.Usage)
+	}
+
+	if err := s.usageRepo.Create(ctx, usage); err != nil {
+		log.Printf("Failed to record embedding usage for project %d: %v", usage.ProjectID, err)
+	}
+}
+
+// embeddingModel looks up an embedding model in the provider's catalog, defaulting to
+// the first one when modelID is empty
+func embeddingModel(providerConfig *models.ProviderConfig, modelID string) (*models.ModelConfig, error) {
+	var valid []string
+	for i := range providerConfig.Models {
+		m := &providerConfig.Models[i]
+		if !m.Embedding {
+			continue
+		}
+		if modelID == "" || m.ID == modelID {
+			return m, nil
+		}
+		valid = append(valid, m.ID)
+	}
+
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("%w: provider %s has no embedding models", ErrNotEmbeddingModel, providerConfig.Name)
+	}
+	return nil, fmt.Errorf("%w: %q (valid embedding models: %v)", ErrNotEmbeddingModel, modelID, valid)
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/berkkaradalan/stackflow/models"
 	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/jackc/pgx/v5"
 )
 
 var (
@@ -14,13 +19,21 @@ var (
 	ErrNoActivePlan          = errors.New("no active execution plan found")
 	ErrAssignmentNotFound    = errors.New("assignment not found")
 	ErrNoTaskAvailable       = errors.New("no pending task available for agent")
+	ErrInvalidExecutionPlan  = errors.New("invalid execution plan")
+	ErrAssignmentNotClaimed  = errors.New("assignment is not in progress with this agent")
+	ErrTestCoverageTooLow    = errors.New("test coverage is below the plan's minimum")
 )
 
+// defaultAssignmentLease is used when no positive lease duration is configured
+const defaultAssignmentLease = 15 * time.Minute
+
 type ExecutionPlanService struct {
 	planRepo    *repository.ExecutionPlanRepository
 	projectRepo *repository.ProjectRepository
 	agentRepo   *repository.AgentRepository
 	taskRepo    *repository.TaskRepository
+	// leaseDuration is how long a claimed assignment stays with its agent between heartbeats
+	leaseDuration time.Duration
 }
 
 func NewExecutionPlanService(
@@ -28,12 +41,17 @@ func NewExecutionPlanService(
 	projectRepo *repository.ProjectRepository,
 	agentRepo *repository.AgentRepository,
 	taskRepo *repository.TaskRepository,
+	leaseDuration time.Duration,
 ) *ExecutionPlanService {
+	if leaseDuration <= 0 {
+		leaseDuration = defaultAssignmentLease
+	}
 	return &ExecutionPlanService{
-		planRepo:    planRepo,
-		projectRepo: projectRepo,
-		agentRepo:   agentRepo,
-		taskRepo:    taskRepo,
+		planRepo:      planRepo,
+		projectRepo:   projectRepo,
+		agentRepo:     agentRepo,
+		taskRepo:      taskRepo,
+		leaseDuration: leaseDuration,
 	}
 }
 
@@ -56,13 +74,22 @@ func (s *ExecutionPlanService) CreatePlan(ctx context.Context, projectID int, re
 	if plan.PlanData.FocusAreas == nil {
 		plan.PlanData.FocusAreas = []string{}
 	}
+	if err := s.validatePriorityOrder(ctx, projectID, plan.PlanData.PriorityOrder); err != nil {
+		return nil, err
+	}
 
-	err = s.planRepo.CreatePlan(ctx, plan)
+	// The plan and its assignments are written together
+	sync, err := s.planRepo.CreatePlanWithAssignments(ctx, plan)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create execution plan: %w", err)
 	}
 
-	return s.planRepo.GetPlanByIDWithDetails(ctx, plan.ID)
+	created, err := s.planRepo.GetPlanByIDWithDetails(ctx, plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	created.AssignmentSync = sync
+	return created, nil
 }
 
 // GetActivePlan retrieves the active execution plan for a project
@@ -117,57 +144,300 @@ func (s *ExecutionPlanService) UpdatePlan(ctx context.Context, projectID int, re
 		updates["status"] = *req.Status
 	}
 
-	err = s.planRepo.UpdatePlan(ctx, plan.ID, updates)
+	// A plan leaving active stops handing out work, and a new priority order is reconciled
+	// with the plan's assignments, each in the same transaction as the update
+	var sync *models.AssignmentSyncResult
+	if req.PlanData != nil {
+		if err := s.validatePriorityOrder(ctx, projectID, req.PlanData.PriorityOrder); err != nil {
+			return nil, err
+		}
+	}
+	if req.Status != nil && *req.Status != models.ExecutionPlanStatusActive {
+		var skipped int
+		skipped, err = s.planRepo.UpdatePlanAndSkipAssignments(ctx, plan.ID, updates)
+		sync = &models.AssignmentSyncResult{Skipped: skipped}
+	} else if req.PlanData != nil {
+		sync, err = s.planRepo.UpdatePlanWithAssignments(ctx, plan.ID, updates, req.PlanData.PriorityOrder, actorID, actorType)
+	} else {
+		err = s.planRepo.UpdatePlan(ctx, plan.ID, updates)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update plan: %w", err)
 	}
 
-	return s.planRepo.GetPlanByIDWithDetails(ctx, plan.ID)
+	updated, err := s.planRepo.GetPlanByIDWithDetails(ctx, plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	updated.AssignmentSync = sync
+	return updated, nil
+}
+
+// validatePriorityOrder checks that every task in a priority order appears once and belongs to
+// the project, and that assigned agents are agents of the project
+func (s *ExecutionPlanService) validatePriorityOrder(ctx context.Context, projectID int, items []models.TaskPriorityItem) error {
+	seen := make(map[int]bool, len(items))
+	agents := make(map[int]bool)
+	for i, item := range items {
+		if seen[item.TaskID] {
+			return fmt.Errorf("%w: priority_order[%d]: task %d is listed more than once", ErrInvalidExecutionPlan, i, item.TaskID)
+		}
+		seen[item.TaskID] = true
+
+		task, err := s.taskRepo.GetByID(ctx, item.TaskID)
+		if err != nil || task.ProjectID != projectID {
+			return fmt.Errorf("%w: priority_order[%d]: task %d is not a task of this project", ErrInvalidExecutionPlan, i, item.TaskID)
+		}
+
+		if item.AssignedAgentID == nil || agents[*item.AssignedAgentID] {
+			continue
+		}
+		agent, err := s.agentRepo.GetByID(ctx, *item.AssignedAgentID)
+		if err != nil || agent.ProjectID != projectID {
+			return fmt.Errorf("%w: priority_order[%d]: agent %d is not an agent of this project", ErrInvalidExecutionPlan, i, *item.AssignedAgentID)
+		}
+		agents[agent.ID] = true
+	}
+	return nil
 }
 
 // --- Agent Task Flow ---
 
-// GetNextTask finds and returns the next pending task for an agent
+// GetNextTask claims the agent's most urgent unblocked pending task and leases it to the agent.
+// The claim is atomic, so concurrent or retried requests never hand out the same assignment.
+// Nothing is handed out while the project already has the plan's MaxParallelTasks in progress.
 func (s *ExecutionPlanService) GetNextTask(ctx context.Context, agentID int) (*models.NextTaskResponse, error) {
 	// Verify agent exists
-	_, err := s.agentRepo.GetByID(ctx, agentID)
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
 	if err != nil {
 		return nil, ErrAgentNotFound
 	}
 
-	assignment, err := s.planRepo.GetNextAssignmentForAgent(ctx, agentID)
+	// Work abandoned in this project by a lapsed lease is claimable again right away and no
+	// longer holds a MaxParallelTasks slot. Other projects are left to RunLeaseReclaimer.
+	if _, err := s.planRepo.ReclaimExpiredAssignments(ctx, &agent.ProjectID); err != nil {
+		return nil, fmt.Errorf("failed to reclaim expired assignments: %w", err)
+	}
+
+	pending, err := s.planRepo.GetPendingAssignmentsForAgent(ctx, agentID)
 	if err != nil {
+		return nil, fmt.Errorf("failed to get pending assignments: %w", err)
+	}
+	if len(pending) == 0 {
 		return &models.NextTaskResponse{
 			Message: "No pending tasks available",
 		}, nil
 	}
 
-	// Mark the assignment as in_progress
-	_ = s.planRepo.StartAssignment(ctx, assignment.ID)
-	assignment.Status = models.AssignmentStatusInProgress
-
-	// Get the plan context for this assignment
-	plan, err := s.planRepo.GetPlanByID(ctx, assignment.PlanID)
+	ready, blocked, err := s.rankAssignments(ctx, pending)
 	if err != nil {
+		return nil, err
+	}
+	if len(ready) == 0 {
 		return &models.NextTaskResponse{
-			Assignment: assignment,
-			Message:    "Task assigned",
+			Message:   fmt.Sprintf("All %d pending tasks are blocked by unfinished dependencies", len(blocked)),
+			BlockedBy: blocked,
 		}, nil
 	}
 
+	// Claim the first ready assignment no concurrent request got to first. Each plan's
+	// candidates are claimed under that plan's own MaxParallelTasks, most urgent plan first.
+	var planOrder []int
+	candidatesByPlan := make(map[int][]int)
+	constraints := make(map[int]models.PlanConstraints)
+	for _, p := range ready {
+		if _, ok := candidatesByPlan[p.PlanID]; !ok {
+			planOrder = append(planOrder, p.PlanID)
+			constraints[p.PlanID] = p.PlanData.Constraints
+		}
+		candidatesByPlan[p.PlanID] = append(candidatesByPlan[p.PlanID], p.ID)
+	}
+
+	var claimed *models.AgentAssignment
+	limitMessage := ""
+	for _, planID := range planOrder {
+		maxParallel := constraints[planID].MaxParallelTasks
+		assignment, inProgress, err := s.planRepo.ClaimAssignment(ctx, agentID, agent.ProjectID, candidatesByPlan[planID], s.leaseDuration, maxParallel)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim assignment: %w", err)
+		}
+		if assignment == nil {
+			if limitMessage == "" {
+				limitMessage = fmt.Sprintf("Project already has %d tasks in progress, the plan allows at most %d in parallel", inProgress, maxParallel)
+			}
+			continue
+		}
+		claimed = assignment
+		break
+	}
+	if claimed == nil {
+		if limitMessage != "" {
+			return &models.NextTaskResponse{
+				Message: limitMessage,
+			}, nil
+		}
+		return &models.NextTaskResponse{
+			Message: "No pending tasks available",
+		}, nil
+	}
+
+	var p *models.PendingAssignment
+	for _, candidate := range ready {
+		if candidate.ID == claimed.ID {
+			p = candidate
+			break
+		}
+	}
+	assignment := &models.AgentAssignmentWithDetails{
+		AgentAssignment: *claimed,
+		AgentName:       p.AgentName,
+		TaskTitle:       p.TaskTitle,
+	}
+
 	return &models.NextTaskResponse{
 		Assignment: assignment,
 		Context: &models.AgentContextResponse{
-			PlanID:      plan.ID,
-			Constraints: plan.PlanData.Constraints,
-			FocusAreas:  plan.PlanData.FocusAreas,
-			Notes:       plan.PlanData.Notes,
+			PlanID:      p.PlanID,
+			Constraints: p.PlanData.Constraints,
+			FocusAreas:  p.PlanData.FocusAreas,
+			Notes:       p.PlanData.Notes,
 		},
 		Message: "Task assigned",
 	}, nil
 }
 
-// CompleteTask handles an agent reporting task completion
+// HeartbeatAssignment extends the lease of an assignment the agent is working on
+func (s *ExecutionPlanService) HeartbeatAssignment(ctx context.Context, agentID, assignmentID int) (*models.AgentAssignment, error) {
+	assignment, err := s.planRepo.HeartbeatAssignment(ctx, agentID, assignmentID, s.leaseDuration)
+	if err == nil {
+		return assignment, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to extend assignment lease: %w", err)
+	}
+
+	// Tell a lost lease apart from an assignment that is not the agent's
+	existing, err := s.planRepo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil || existing.AgentID != agentID {
+		return nil, ErrAssignmentNotFound
+	}
+	return nil, fmt.Errorf("%w: assignment %d is %s", ErrAssignmentNotClaimed, assignmentID, existing.Status)
+}
+
+// RunLeaseReclaimer puts assignments whose lease lapsed back to pending on each tick of
+// interval until ctx is done
+func (s *ExecutionPlanService) RunLeaseReclaimer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reclaimed, err := s.planRepo.ReclaimExpiredAssignments(ctx, nil)
+			if err != nil {
+				log.Printf("Failed to reclaim expired assignments: %v", err)
+			} else if reclaimed > 0 {
+				log.Printf("Reclaimed %d assignments with a lapsed lease", reclaimed)
+			}
+		}
+	}
+}
+
+// rankAssignments orders the pending assignments whose dependencies are all finished from
+// most to least urgent: by the priority the plan gives their task, or the task's own priority,
+// then by their position in the plan's priority order. Blocked assignments are returned apart
+// with what each one waits on. A dependency is finished once it is done, closed or dropped as
+// wont_do; one in review still blocks, since the review may send it back for changes.
+func (s *ExecutionPlanService) rankAssignments(ctx context.Context, pending []models.PendingAssignment) ([]*models.PendingAssignment, []models.BlockedAssignment, error) {
+	type candidate struct {
+		assignment *models.PendingAssignment
+		rank       int
+		position   int
+		item       *models.TaskPriorityItem
+	}
+
+	candidates := make([]candidate, 0, len(pending))
+	for i := range pending {
+		p := &pending[i]
+		c := candidate{assignment: p, rank: models.TaskPriorityRank(p.TaskPriority), position: len(p.PlanData.PriorityOrder)}
+		for pos := range p.PlanData.PriorityOrder {
+			item := &p.PlanData.PriorityOrder[pos]
+			if item.TaskID != p.TaskID {
+				continue
+			}
+			c.item, c.position = item, pos
+			if rank := models.TaskPriorityRank(item.Priority); rank > 0 {
+				c.rank = rank
+			}
+			break
+		}
+		candidates = append(candidates, c)
+	}
+
+	// Pending assignments come oldest first, which stays the tie-breaker
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].rank != candidates[j].rank {
+			return candidates[i].rank > candidates[j].rank
+		}
+		return candidates[i].position < candidates[j].position
+	})
+
+	var ready []*models.PendingAssignment
+	var blocked []models.BlockedAssignment
+	dependencies := make(map[int]*models.Task)
+	for _, c := range candidates {
+		var waitingOn []models.BlockingTask
+		if c.item != nil {
+			for _, depID := range c.item.Dependencies {
+				dep, ok := dependencies[depID]
+				if !ok {
+					// Dependencies that no longer exist do not block
+					var err error
+					dep, err = s.taskRepo.GetByID(ctx, depID)
+					if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+						return nil, nil, fmt.Errorf("failed to get dependency task %d: %w", depID, err)
+					}
+					dependencies[depID] = dep
+				}
+				if dep != nil && !dependencyFinished(dep.Status) {
+					waitingOn = append(waitingOn, models.BlockingTask{TaskID: dep.ID, Title: dep.Title, Status: dep.Status})
+				}
+			}
+		}
+
+		if len(waitingOn) == 0 {
+			ready = append(ready, c.assignment)
+			continue
+		}
+		blocked = append(blocked, models.BlockedAssignment{
+			AssignmentID: c.assignment.ID,
+			TaskID:       c.assignment.TaskID,
+			TaskTitle:    c.assignment.TaskTitle,
+			WaitingOn:    waitingOn,
+		})
+	}
+
+	return ready, blocked, nil
+}
+
+// dependencyFinished reports whether a dependency in status no longer holds up dependent tasks
+func dependencyFinished(status string) bool {
+	switch status {
+	case models.TaskStatusDone, models.TaskStatusClosed, models.TaskStatusWontDo:
+		return true
+	}
+	return false
+}
+
+// CompleteTask handles an agent reporting task completion. The plan's constraints decide where
+// the task goes: a report below TestCoverageMin is rejected and the assignment stays in
+// progress, while plans requiring code review, or completions that do not report coverage the
+// plan asks for, send the task to review instead of done.
 func (s *ExecutionPlanService) CompleteTask(ctx context.Context, agentID int, req *models.TaskCompleteRequest) (*models.AgentAssignment, error) {
 	// Verify agent exists
 	_, err := s.agentRepo.GetByID(ctx, agentID)
@@ -181,22 +451,50 @@ func (s *ExecutionPlanService) CompleteTask(ctx context.Context, agentID int, re
 		return nil, ErrAssignmentNotFound
 	}
 
-	// Complete the assignment
-	err = s.planRepo.CompleteAssignment(ctx, assignment.ID, req.ReportData)
+	plan, err := s.planRepo.GetPlanByID(ctx, assignment.PlanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution plan: %w", err)
+	}
+	constraints := plan.PlanData.Constraints
+
+	newStatus := models.TaskStatusDone
+	var reviewReasons []string
+	if constraints.CodeReviewRequired {
+		reviewReasons = append(reviewReasons, "the plan requires code review")
+	}
+	if constraints.TestCoverageMin > 0 {
+		if req.TestCoverage == nil {
+			reviewReasons = append(reviewReasons, fmt.Sprintf("test coverage was not reported, the plan requires at least %d%%", constraints.TestCoverageMin))
+		} else if *req.TestCoverage < float64(constraints.TestCoverageMin) {
+			return nil, fmt.Errorf("%w: reported %.1f%%, the plan requires at least %d%%", ErrTestCoverageTooLow, *req.TestCoverage, constraints.TestCoverageMin)
+		}
+	}
+	if len(reviewReasons) > 0 {
+		newStatus = models.TaskStatusInReview
+	}
+
+	// Complete the assignment, which only the agent holding its lease can do
+	completed, err := s.planRepo.CompleteAssignment(ctx, assignment.ID, agentID, req.ReportData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to complete assignment: %w", err)
 	}
+	if !completed {
+		return nil, fmt.Errorf("%w: assignment %d must be claimed through next-task before it is completed", ErrAssignmentNotClaimed, assignment.ID)
+	}
 
-	// Also update the task status to done
+	// Also move the task to done, or to review when the plan asks for one. Tasks handed back
+	// after a review are open again.
 	task, err := s.taskRepo.GetByID(ctx, req.TaskID)
-	if err == nil && task.Status == models.TaskStatusInProgress {
-		_ = s.taskRepo.UpdateStatus(ctx, req.TaskID, models.TaskStatusDone)
+	if err == nil && (task.Status == models.TaskStatusInProgress || task.Status == models.TaskStatusOpen) {
+		_ = s.taskRepo.UpdateStatus(ctx, req.TaskID, newStatus)
 
 		// Log activity on the task
 		if req.Message == "" {
 			req.Message = "Task completed by agent"
 		}
-		newStatus := models.TaskStatusDone
+		if len(reviewReasons) > 0 {
+			req.Message += " (sent to review: " + strings.Join(reviewReasons, "; ") + ")"
+		}
 		activity := &models.TaskActivity{
 			TaskID:    req.TaskID,
 			ActorID:   agentID,
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrProjectNotFound  = errors.New("project not found")
+	ErrMemberNotFound   = errors.New("project member not found")
+	ErrMemberExists     = errors.New("user is already a member of this project")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrLastProjectOwner = errors.New("a project must keep at least one owner")
+)
+
+type ProjectMemberService struct {
+	memberRepo  *repository.ProjectMemberRepository
+	projectRepo *repository.ProjectRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewProjectMemberService(memberRepo *repository.ProjectMemberRepository, projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *ProjectMemberService {
+	return &ProjectMemberService{
+		memberRepo:  memberRepo,
+		projectRepo: projectRepo,
+		userRepo:    userRepo,
+	}
+}
+
+func (s *ProjectMemberService) GetMembers(ctx context.Context, projectID int) (*models.ProjectMemberListResponse, error) {
+	if _, err := s.projectRepo.GetByID(ctx, projectID); err != nil {
+		return nil, ErrProjectNotFound
+	}
+
+	members, err := s.memberRepo.GetByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project members: %w", err)
+	}
+
+	return &models.ProjectMemberListResponse{
+		Members:    members,
+		TotalCount: len(members),
+	}, nil
+}
+
+func (s *ProjectMemberService) AddMember(ctx context.Context, projectID int, req *models.AddProjectMemberRequest) (*models.ProjectMember, error) {
+	if _, err := s.projectRepo.GetByID(ctx, projectID); err != nil {
+		return nil, ErrProjectNotFound
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, req.UserID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if _, err := s.memberRepo.GetByProjectAndUser(ctx, projectID, req.UserID); err == nil {
+		return nil, ErrMemberExists
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check membership: %w", err)
+	}
+
+	member := &models.ProjectMember{
+		ProjectID: projectID,
+		UserID:    req.UserID,
+		Role:      req.Role,
+	}
+
+	if err := s.memberRepo.Create(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to add project member: %w", err)
+	}
+
+	return member, nil
+}
+
+func (s *ProjectMemberService) UpdateMemberRole(ctx context.Context, projectID int, userID int, req *models.UpdateProjectMemberRequest) (*models.ProjectMember, error) {
+	if _, err := s.getMember(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	// The owner check and the update run in one transaction
+	kept, err := s.memberRepo.UpdateRole(ctx, projectID, userID, req.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update project member: %w", err)
+	}
+	if !kept {
+		return nil, ErrLastProjectOwner
+	}
+
+	return s.memberRepo.GetByProjectAndUser(ctx, projectID, userID)
+}
+
+func (s *ProjectMemberService) RemoveMember(ctx context.Context, projectID int, userID int) error {
+	if _, err := s.getMember(ctx, projectID, userID); err != nil {
+		return err
+	}
+
+	// The owner check and the delete run in one transaction
+	kept, err := s.memberRepo.Delete(ctx, projectID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove project member: %w", err)
+	}
+	if !kept {
+		return ErrLastProjectOwner
+	}
+
+	return nil
+}
+
+func (s *ProjectMemberService) getMember(ctx context.Context, projectID int, userID int) (*models.ProjectMember, error) {
+	member, err := s.memberRepo.GetByProjectAndUser(ctx, projectID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, fmt.Errorf("failed to get project member: %w", err)
+	}
+	return member, nil
+}
@@ -38,8 +38,15 @@ func (s *ProjectService) CreateProject(ctx context.Context, req *models.CreatePr
 	return project, nil
 }
 
-func (s *ProjectService) GetAllProjects(ctx context.Context) (*models.ProjectListResponse, error) {
-	projects, err := s.projectRepo.GetAll(ctx)
+// GetAllProjects lists every project for admins and only the user's own memberships otherwise
+func (s *ProjectService) GetAllProjects(ctx context.Context, userID int, role string) (*models.ProjectListResponse, error) {
+	var projects []models.Project
+	var err error
+	if role == "admin" {
+		projects, err = s.projectRepo.GetAll(ctx)
+	} else {
+		projects, err = s.projectRepo.GetAllForUser(ctx, userID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get projects: %w", err)
 	}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrPromptTemplateNotFound = errors.New("prompt template not found")
+	ErrInvalidPromptTemplate  = errors.New("invalid prompt template")
+)
+
+// promptFuncs are the functions available to prompt templates besides the text/template builtins
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// PromptTemplateService manages the versioned system prompts of agents and renders them for runs
+type PromptTemplateService struct {
+	templateRepo      *repository.PromptTemplateRepository
+	agentRepo         *repository.AgentRepository
+	projectRepo       *repository.ProjectRepository
+	taskRepo          *repository.TaskRepository
+	executionPlanRepo *repository.ExecutionPlanRepository
+}
+
+func NewPromptTemplateService(
+	templateRepo *repository.PromptTemplateRepository,
+	agentRepo *repository.AgentRepository,
+	projectRepo *repository.ProjectRepository,
+	taskRepo *repository.TaskRepository,
+	executionPlanRepo *repository.ExecutionPlanRepository,
+) *PromptTemplateService {
+	return &PromptTemplateService{
+		templateRepo:      templateRepo,
+		agentRepo:         agentRepo,
+		projectRepo:       projectRepo,
+		taskRepo:          taskRepo,
+		executionPlanRepo: executionPlanRepo,
+	}
+}
+
+// GetTemplates lists the newest version of each role and level of a project's overrides,
+// or of the defaults when projectID is nil
+func (s *PromptTemplateService) GetTemplates(ctx context.Context, projectID *int) (*models.PromptTemplateListResponse, error) {
+	templates, err := s.templateRepo.GetLatest(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PromptTemplateListResponse{Templates: templates, Total: len(templates)}, nil
+}
+
+// GetVersions lists every version of a role and level, newest first
+func (s *PromptTemplateService) GetVersions(ctx context.Context, projectID *int, query *models.PromptTemplateQuery) (*models.PromptTemplateListResponse, error) {
+	templates, err := s.templateRepo.GetVersions(ctx, projectID, query.Role, query.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PromptTemplateListResponse{Templates: templates, Total: len(templates)}, nil
+}
+
+// CreateTemplate validates a template and saves it as the next version of its role and level.
+// With projectID nil it is a new default, otherwise an override for the project.
+func (s *PromptTemplateService) CreateTemplate(ctx context.Context, projectID *int, req *models.CreatePromptTemplateRequest, userID int) (*models.PromptTemplate, error) {
+	if projectID != nil {
+		if _, err := s.projectRepo.GetByID(ctx, *projectID); err != nil {
+			return nil, ErrProjectNotFound
+		}
+	}
+
+	if err := validatePromptTemplate(req.Content); err != nil {
+		return nil, err
+	}
+
+	t := &models.PromptTemplate{
+		ProjectID:  projectID,
+		Role:       req.Role,
+		Level:      req.Level,
+		Content:    req.Content,
+		ChangeNote: req.ChangeNote,
+		CreatedBy:  userID,
+	}
+	if err := s.templateRepo.Create(ctx, t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Diff compares two template versions line by line. Within a project its overrides and the
+// defaults can be compared; without one only the defaults.
+func (s *PromptTemplateService) Diff(ctx context.Context, projectID *int, fromID, toID int) (*models.PromptTemplateDiff, error) {
+	from, err := s.visibleTemplate(ctx, projectID, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.visibleTemplate(ctx, projectID, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, added, removed := utils.LineDiff(from.Content, to.Content)
+	return &models.PromptTemplateDiff{
+		From:    *from,
+		To:      *to,
+		Diff:    diff,
+		Added:   added,
+		Removed: removed,
+	}, nil
+}
+
+// visibleTemplate loads a template, treating overrides of other projects as missing
+func (s *PromptTemplateService) visibleTemplate(ctx context.Context, projectID *int, id int) (*models.PromptTemplate, error) {
+	t, err := s.templateRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrPromptTemplateNotFound
+	}
+	if t.ProjectID != nil && (projectID == nil || *t.ProjectID != *projectID) {
+		return nil, ErrPromptTemplateNotFound
+	}
+	return t, nil
+}
+
+// PreviewAgentPrompt renders the system prompt an agent would get for a run on the task
+func (s *PromptTemplateService) PreviewAgentPrompt(ctx context.Context, agentID int, taskID *int) (*models.RenderedPrompt, error) {
+	agent, err := s.agentRepo.GetByID(ctx, agentID)
+	if err != nil {
+		return nil, ErrAgentNotFound
+	}
+
+	prompt, err := s.Render(ctx, agent, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if prompt == nil {
+		return nil, ErrPromptTemplateNotFound
+	}
+	return prompt, nil
+}
+
+// Render resolves the agent's template, preferring the project's override over the default
+// and a template for the agent's level over one for its whole role, and renders it with the
+// project, its active plan and the task. It returns nil when no template applies.
+func (s *PromptTemplateService) Render(ctx context.Context, agent *models.Agent, taskID *int) (*models.RenderedPrompt, error) {
+	t, err := s.templateRepo.Resolve(ctx, agent.ProjectID, agent.Role, agent.Level)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.promptContext(ctx, agent, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := renderPromptTemplate(t.Content, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt template %d: %w", t.ID, err)
+	}
+
+	return &models.RenderedPrompt{
+		Template: models.PromptTemplateRef{ID: t.ID, Version: t.Version, Source: t.Source()},
+		Role:     t.Role,
+		Level:    t.Level,
+		Content:  content,
+	}, nil
+}
+
+// promptContext gathers the variables of a run. A task must belong to the agent's project.
+func (s *PromptTemplateService) promptContext(ctx context.Context, agent *models.Agent, taskID *int) (*models.PromptContext, error) {
+	project, err := s.projectRepo.GetByID(ctx, agent.ProjectID)
+	if err != nil {
+		return nil, ErrProjectNotFound
+	}
+
+	data := &models.PromptContext{
+		Agent:   models.PromptAgent{ID: agent.ID, Name: agent.Name, Role: agent.Role, Level: agent.Level},
+		Project: models.PromptProject{ID: project.ID, Name: project.Name, Description: project.Description},
+	}
+
+	var plan *models.ExecutionPlanWithDetails
+	if plan, err = s.executionPlanRepo.GetActivePlanByProjectID(ctx, agent.ProjectID); err == nil {
+		data.Plan = &models.PromptPlan{
+			FocusAreas:  plan.PlanData.FocusAreas,
+			Constraints: plan.PlanData.Constraints,
+			Notes:       plan.PlanData.Notes,
+		}
+	}
+
+	if taskID != nil {
+		task, err := s.taskRepo.GetByID(ctx, *taskID)
+		if err != nil || task.ProjectID != agent.ProjectID {
+			return nil, ErrTaskNotFound
+		}
+
+		data.Task = &models.PromptTask{
+			ID:          task.ID,
+			Title:       task.Title,
+			Description: task.Description,
+			Priority:    task.Priority,
+			Tags:        task.Tags,
+		}
+		if data.Plan != nil {
+			for _, item := range plan.PlanData.PriorityOrder {
+				if item.TaskID == task.ID {
+					data.Task.Dependencies = item.Dependencies
+					data.Task.EstimatedEffort = item.EstimatedEffort
+					data.Task.Notes = item.Notes
+					break
+				}
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// validatePromptTemplate parses a template and renders it against sample data, with and
+// without a plan and task, so that unknown variables are rejected when the template is saved
+func validatePromptTemplate(content string) error {
+	samples := []*models.PromptContext{
+		{
+			Agent:   models.PromptAgent{ID: 1, Name: "Agent", Role: models.AgentRoleBackendDeveloper, Level: models.AgentLevelMid},
+			Project: models.PromptProject{ID: 1, Name: "Project"},
+		},
+		{
+			Agent:   models.PromptAgent{ID: 1, Name: "Agent", Role: models.AgentRoleBackendDeveloper, Level: models.AgentLevelMid},
+			Project: models.PromptProject{ID: 1, Name: "Project", Description: "Description"},
+			Plan: &models.PromptPlan{
+				FocusAreas:  []string{"api"},
+				Constraints: models.PlanConstraints{MaxParallelTasks: 1, CodeReviewRequired: true, TestCoverageMin: 80},
+				Notes:       "Notes",
+			},
+			Task: &models.PromptTask{ID: 1, Title: "Task", Priority: models.TaskPriorityMedium, Tags: []string{"tag"}, Dependencies: []int{2}},
+		},
+	}
+
+	for _, sample := range samples {
+		content, err := renderPromptTemplate(content, sample)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPromptTemplate, err)
+		}
+		if content == "" {
+			return fmt.Errorf("%w: template renders to an empty prompt", ErrInvalidPromptTemplate)
+		}
+	}
+	return nil
+}
+
+func renderPromptTemplate(content string, data *models.PromptContext) (string, error) {
+	t, err := template.New("prompt").Funcs(promptFuncs).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/berkkaradalan/stackflow/config"
+	"github.com/berkkaradalan/stackflow/llm"
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/berkkaradalan/stackflow/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrProviderCredentialNotFound = errors.New("provider credential not found")
+	ErrInvalidProviderCredential  = errors.New("invalid provider credential")
+	ErrProviderCredentialInUse    = errors.New("provider credential is used by agents")
+)
+
+// ProviderCredentialService manages the provider keys agents share, per project or for the
+// whole organization, and resolves the key an agent calls its provider with
+type ProviderCredentialService struct {
+	credentialRepo *repository.ProviderCredentialRepository
+	projectRepo    *repository.ProjectRepository
+	keyRing        *utils.KeyRing
+}
+
+func NewProviderCredentialService(credentialRepo *repository.ProviderCredentialRepository, projectRepo *repository.ProjectRepository, keyRing *utils.KeyRing) *ProviderCredentialService {
+	return &ProviderCredentialService{
+		credentialRepo: credentialRepo,
+		projectRepo:    projectRepo,
+		keyRing:        keyRing,
+	}
+}
+
+// CreateCredential stores a credential for a project, or for the organization when projectID is nil
+func (s *ProviderCredentialService) CreateCredential(ctx context.Context, projectID *int, req *models.CreateProviderCredentialRequest, userID int) (*models.ProviderCredential, error) {
+	if projectID != nil {
+		if _, err := s.projectRepo.GetByID(ctx, *projectID); err != nil {
+			return nil, ErrProjectNotFound
+		}
+	}
+
+	providerConfig := config.GetProviderByName(req.Provider)
+	if providerConfig == nil {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotConfigured, req.Provider)
+	}
+	if !providerConfig.RequiresAPIKey {
+		return nil, fmt.Errorf("%w: provider %s does not use api keys", ErrInvalidProviderCredential, req.Provider)
+	}
+
+	encryptedKey, keyVersion, err := s.keyRing.Encrypt(req.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt api key: %w", err)
+	}
+
+	credential := &models.ProviderCredential{
+		ProjectID:     projectID,
+		Name:          req.Name,
+		Provider:      req.Provider,
+		APIKey:        encryptedKey,
+		APIKeyVersion: keyVersion,
+		APIKeyHint:    utils.SecretHint(req.APIKey),
+		CreatedBy:     userID,
+	}
+	if err := s.credentialRepo.Create(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to create provider credential: %w", err)
+	}
+
+	maskCredential(credential)
+	return credential, nil
+}
+
+// GetCredentials lists the credentials a project's agents can use, its own and the
+// organization-wide ones, or only the organization-wide ones when projectID is nil
+func (s *ProviderCredentialService) GetCredentials(ctx context.Context, projectID *int) (*models.ProviderCredentialListResponse, error) {
+	var credentials []models.ProviderCredential
+	var err error
+	if projectID == nil {
+		credentials, err = s.credentialRepo.GetOrganization(ctx)
+	} else {
+		if _, err := s.projectRepo.GetByID(ctx, *projectID); err != nil {
+			return nil, ErrProjectNotFound
+		}
+		credentials, err = s.credentialRepo.GetAvailableToProject(ctx, *projectID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider credentials: %w", err)
+	}
+
+	for i := range credentials {
+		maskCredential(&credentials[i])
+	}
+
+	return &models.ProviderCredentialListResponse{Credentials: credentials, TotalCount: len(credentials)}, nil
+}
+
+// GetCredential returns a credential of the given scope
+func (s *ProviderCredentialService) GetCredential(ctx context.Context, projectID *int, id int) (*models.ProviderCredential, error) {
+	credential, err := s.credentialRepo.GetInScope(ctx, projectID, id)
+	if err != nil {
+		return nil, ErrProviderCredentialNotFound
+	}
+
+	maskCredential(credential)
+	return credential, nil
+}
+
+// UpdateCredential renames a credential or rotates its key. Agents using it pick up a new key
+// on their next call.
+func (s *ProviderCredentialService) UpdateCredential(ctx context.Context, projectID *int, id int, req *models.UpdateProviderCredentialRequest) (*models.ProviderCredential, error) {
+	credential, err := s.credentialRepo.GetInScope(ctx, projectID, id)
+	if err != nil {
+		return nil, ErrProviderCredentialNotFound
+	}
+
+	if req.Name != nil {
+		credential.Name = *req.Name
+	}
+	if req.APIKey != nil {
+		encryptedKey, keyVersion, err := s.keyRing.Encrypt(*req.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt api key: %w", err)
+		}
+		credential.APIKey = encryptedKey
+		credential.APIKeyVersion = keyVersion
+		credential.APIKeyHint = utils.SecretHint(*req.APIKey)
+	}
+	if err := s.credentialRepo.Update(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to update provider credential: %w", err)
+	}
+
+	maskCredential(credential)
+	return credential, nil
+}
+
+// DeleteCredential removes a credential no agent references any more
+func (s *ProviderCredentialService) DeleteCredential(ctx context.Context, projectID *int, id int) error {
+	if _, err := s.credentialRepo.GetInScope(ctx, projectID, id); err != nil {
+		return ErrProviderCredentialNotFound
+	}
+
+	count, err := s.credentialRepo.DeleteUnused(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrProviderCredentialNotFound
+	}
+	// An agent that still got linked trips the foreign key (foreign_key_violation)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("%w: agents still reference it", ErrProviderCredentialInUse)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete provider credential: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %d agent(s) still reference it", ErrProviderCredentialInUse, count)
+	}
+	return nil
+}
+
+// TestCredential sends a short test message to the credential's provider and records the outcome.
+// A failing provider is reported in the response, not as an error.
+func (s *ProviderCredentialService) TestCredential(ctx context.Context, projectID *int, id int, req *models.TestProviderCredentialRequest) (*models.ProviderCredentialTestResponse, error) {
+	credential, err := s.credentialRepo.GetInScope(ctx, projectID, id)
+	if err != nil {
+		return nil, ErrProviderCredentialNotFound
+	}
+
+	providerConfig := config.GetProviderByName(credential.Provider)
+	if providerConfig == nil {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotConfigured, credential.Provider)
+	}
+
+	model := req.Model
+	if model == "" {
+		for _, m := range providerConfig.Models {
+			if !m.Embedding {
+				model = m.ID
+				break
+			}
+		}
+	}
+	if model == "" {
+		return nil, fmt.Errorf("%w: model is required for provider %s", ErrInvalidProviderCredential, credential.Provider)
+	}
+	if len(providerConfig.Models) > 0 && findModel(providerConfig, model) == nil {
+		return nil, fmt.Errorf("%w: model %q is not available for provider %s", ErrInvalidProviderCredential, model, credential.Provider)
+	}
+
+	apiKey, err := s.keyRing.Decrypt(credential.APIKey, credential.APIKeyVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt api key: %w", err)
+	}
+
+	result := &models.ProviderCredentialTestResponse{
+		CredentialID: credential.ID,
+		Provider:     credential.Provider,
+		Model:        model,
+	}
+
+	started := time.Now()
+	resp, err := s.testCall(ctx, providerConfig, model, apiKey)
+	result.LatencyMs = time.Since(started).Milliseconds()
+	switch {
+	case err != nil:
+		result.Message = "Credential test failed: " + err.Error()
+	case resp.Content == "":
+		result.Message = "Credential test failed: unable to extract AI response from API"
+	default:
+		result.Healthy = true
+		result.Message = "Credential is valid - API test successful"
+		result.TestResponse = resp.Content
+	}
+
+	if err := s.credentialRepo.RecordTest(ctx, credential.ID, result.Healthy); err != nil {
+		return nil, fmt.Errorf("failed to record credential test: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *ProviderCredentialService) testCall(ctx context.Context, providerConfig *models.ProviderConfig, model, apiKey string) (*llm.ChatResponse, error) {
+	provider, err := llm.New(providerConfig, apiKey, &http.Client{Timeout: 15 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.Chat(ctx, &llm.ChatRequest{
+		Model: model,
+		Messages: []llm.Message{
+			{
+				Role:    llm.RoleUser,
+				Content: "This is a credential test. Please respond with a brief confirmation that you are operational.",
+			},
+		},
+		MaxTokens:   50,
+		Temperature: 0.7,
+	})
+}
+
+// ForAgent returns the credential an agent of projectID may reference: one of the project's own
+// or an organization-wide one
+func (s *ProviderCredentialService) ForAgent(ctx context.Context, projectID, id int) (*models.ProviderCredential, error) {
+	credential, err := s.credentialRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrProviderCredentialNotFound
+	}
+	if credential.ProjectID != nil && *credential.ProjectID != projectID {
+		return nil, ErrProviderCredentialNotFound
+	}
+	return credential, nil
+}
+
+// AgentKey returns the decrypted key an agent calls its own provider with: its credential's
+// key when it references one and its own key otherwise
+func (s *ProviderCredentialService) AgentKey(ctx context.Context, agent *models.Agent) (llm.PoolKey, error) {
+	encrypted, version, hint := agent.APIKey, agent.APIKeyVersion, agent.APIKeyHint
+	if agent.CredentialID != nil {
+		credential, err := s.credentialRepo.GetByID(ctx, *agent.CredentialID)
+		if err != nil {
+			return llm.PoolKey{}, fmt.Errorf("failed to get provider credential %d: %w", *agent.CredentialID, err)
+		}
+		encrypted, version, hint = credential.APIKey, credential.APIKeyVersion, credential.APIKeyHint
+	}
+
+	apiKey, err := s.keyRing.Decrypt(encrypted, version)
+	if err != nil {
+		return llm.PoolKey{}, fmt.Errorf("failed to decrypt api key: %w", err)
+	}
+	return llm.PoolKey{Key: apiKey, Masked: utils.MaskSecret(hint)}, nil
+}
+
+// ReencryptKeys moves every credential to the current master key version. It returns how many
+// were re-encrypted and the IDs of those that failed.
+func (s *ProviderCredentialService) ReencryptKeys(ctx context.Context) (int, []int, error) {
+	credentials, err := s.credentialRepo.GetKeysNotAtVersion(ctx, s.keyRing.CurrentVersion())
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get provider credentials: %w", err)
+	}
+
+	reencrypted := 0
+	failed := []int{}
+	for _, credential := range credentials {
+		hint := credential.APIKeyHint
+		if credential.APIKeyVersion == utils.PlaintextKeyVersion {
+			hint = utils.SecretHint(credential.APIKey)
+		}
+
+		encryptedKey, keyVersion, err := s.keyRing.Reencrypt(credential.APIKey, credential.APIKeyVersion)
+		if err == nil {
+			var updated bool
+			updated, err = s.credentialRepo.UpdateAPIKey(ctx, credential.ID, credential.APIKeyVersion, encryptedKey, keyVersion, hint)
+			if updated {
+				reencrypted++
+			}
+		}
+		if err != nil {
+			failed = append(failed, credential.ID)
+		}
+	}
+
+	return reencrypted, failed, nil
+}
+
+// maskCredential strips the stored key and exposes only its masked form
+func maskCredential(credential *models.ProviderCredential) {
+	credential.APIKey = ""
+	credential.APIKeyMasked = utils.MaskSecret(credential.APIKeyHint)
+}
@@ -1,14 +1,35 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
 	"github.com/berkkaradalan/stackflow/config"
 	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+	"github.com/jackc/pgx/v5"
 )
 
-type ProviderService struct{}
+var (
+	ErrInvalidProvider = errors.New("invalid provider definition")
+	ErrProviderExists  = errors.New("provider already exists")
+	ErrBuiltInProvider = errors.New("provider is defined by the registry file")
+	ErrProviderInUse   = errors.New("provider is used by agents")
+)
 
-func NewProviderService() *ProviderService {
-	return &ProviderService{}
+type ProviderService struct {
+	customProviderRepo *repository.CustomProviderRepository
+	agentRepo          *repository.AgentRepository
+}
+
+func NewProviderService(customProviderRepo *repository.CustomProviderRepository, agentRepo *repository.AgentRepository) *ProviderService {
+	return &ProviderService{
+		customProviderRepo: customProviderRepo,
+		agentRepo:          agentRepo,
+	}
 }
 
 // GetAllProviders returns all available providers
@@ -41,6 +62,179 @@ func (s *ProviderService) GetProviderByName(providerName string) (*models.Provid
 	return provider, nil
 }
 
+// GetCustomProviders returns the providers registered by admins
+func (s *ProviderService) GetCustomProviders(ctx context.Context) (*models.ProviderListResponse, error) {
+	providers, err := s.customProviderRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom providers: %w", err)
+	}
+
+	return &models.ProviderListResponse{
+		Providers: providers,
+	}, nil
+}
+
+// CreateCustomProvider registers an OpenAI-compatible provider and adds it to the live registry
+func (s *ProviderService) CreateCustomProvider(ctx context.Context, req *models.CustomProviderRequest, userID int) (*models.ProviderConfig, error) {
+	provider, err := buildCustomProvider(req.Name, req)
+	if err != nil {
+		return nil, err
+	}
+	if config.GetProviderByName(provider.Name) != nil {
+		return nil, ErrProviderExists
+	}
+
+	if err := s.customProviderRepo.Create(ctx, provider, userID); err != nil {
+		return nil, fmt.Errorf("failed to create custom provider: %w", err)
+	}
+
+	if err := s.RefreshCustomProviders(ctx); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// UpdateCustomProvider replaces a custom provider's definition. The name cannot change.
+func (s *ProviderService) UpdateCustomProvider(ctx context.Context, name string, req *models.CustomProviderRequest) (*models.ProviderConfig, error) {
+	if _, err := s.getCustomProvider(ctx, name); err != nil {
+		return nil, err
+	}
+
+	provider, err := buildCustomProvider(name, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.customProviderRepo.Update(ctx, provider); err != nil {
+		return nil, fmt.Errorf("failed to update custom provider: %w", err)
+	}
+
+	if err := s.RefreshCustomProviders(ctx); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// DeleteCustomProvider removes a custom provider that no agent uses
+func (s *ProviderService) DeleteCustomProvider(ctx context.Context, name string) error {
+	if _, err := s.getCustomProvider(ctx, name); err != nil {
+		return err
+	}
+
+	count, err := s.agentRepo.CountByProvider(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check provider usage: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %d agent(s)", ErrProviderInUse, count)
+	}
+
+	if err := s.customProviderRepo.Delete(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete custom provider: %w", err)
+	}
+
+	return s.RefreshCustomProviders(ctx)
+}
+
+// RefreshCustomProviders loads custom providers from the database into the live registry.
+// Providers that clash with the registry file are skipped.
+func (s *ProviderService) RefreshCustomProviders(ctx context.Context) error {
+	providers, err := s.customProviderRepo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load custom providers: %w", err)
+	}
+
+	registry := config.Providers()
+	custom := make([]models.ProviderConfig, 0, len(providers))
+	for _, p := range providers {
+		if registry.IsBuiltIn(p.Name) {
+			log.Printf("Skipping custom provider %q: the registry file defines a provider with the same name", p.Name)
+			continue
+		}
+		custom = append(custom, p)
+	}
+
+	registry.SetCustomProviders(custom)
+	return nil
+}
+
+// Watch reloads the registry file when it changes and refreshes custom providers,
+// so changes made by other instances are picked up. It runs until ctx is done.
+func (s *ProviderService) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := config.Providers().ReloadIfChanged()
+			if err != nil {
+				log.Printf("Keeping previous provider registry: %v", err)
+			} else if reloaded {
+				log.Println("Provider registry reloaded")
+			}
+
+			if err := s.RefreshCustomProviders(ctx); err != nil {
+				log.Printf("Failed to refresh custom providers: %v", err)
+			}
+		}
+	}
+}
+
+func (s *ProviderService) getCustomProvider(ctx context.Context, name string) (*models.ProviderConfig, error) {
+	provider, err := s.customProviderRepo.GetByName(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if config.Providers().IsBuiltIn(name) {
+			return nil, ErrBuiltInProvider
+		}
+		return nil, &ProviderNotFoundError{Provider: name}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom provider: %w", err)
+	}
+	return provider, nil
+}
+
+// buildCustomProvider applies defaults and validates a custom provider request
+func buildCustomProvider(name string, req *models.CustomProviderRequest) (*models.ProviderConfig, error) {
+	provider := &models.ProviderConfig{
+		Name:               name,
+		DisplayName:        req.DisplayName,
+		BaseURL:            req.BaseURL,
+		ChatCompletionPath: req.ChatCompletionPath,
+		HealthCheckPath:    req.HealthCheckPath,
+		EmbeddingPath:      req.EmbeddingPath,
+		APIFormat:          "openai",
+		RequiresAPIKey:     true,
+		Models:             req.Models,
+		Custom:             true,
+	}
+	if provider.ChatCompletionPath == "" {
+		provider.ChatCompletionPath = "/chat/completions"
+	}
+	if provider.HealthCheckPath == "" {
+		provider.HealthCheckPath = "/models"
+	}
+	for _, m := range req.Models {
+		if m.Embedding && provider.EmbeddingPath == "" {
+			provider.EmbeddingPath = "/embeddings"
+		}
+	}
+	if req.RequiresAPIKey != nil {
+		provider.RequiresAPIKey = *req.RequiresAPIKey
+	}
+	if provider.Models == nil {
+		provider.Models = []models.ModelConfig{}
+	}
+
+	if err := config.ValidateProvider(provider); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProvider, err)
+	}
+	return provider, nil
+}
+
 // ProviderNotFoundError represents a provider not found error
 type ProviderNotFoundError struct {
 	Provider string
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
 
 	"github.com/berkkaradalan/stackflow/models"
 	repository "github.com/berkkaradalan/stackflow/repository/postgres"
@@ -15,13 +18,18 @@ var (
 	ErrUnauthorized          = errors.New("unauthorized to perform this action")
 	ErrAgentNotFound         = errors.New("agent not found")
 	ErrReviewerNotFound      = errors.New("reviewer not found")
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrInvalidAttachment     = errors.New("invalid attachment")
+	ErrReviewNotAllowed      = errors.New("only the task's reviewer or a project maintainer can approve a review")
 )
 
 type TaskService struct {
-	taskRepo    *repository.TaskRepository
-	agentRepo   *repository.AgentRepository
-	userRepo    *repository.UserRepository
-	projectRepo *repository.ProjectRepository
+	taskRepo       *repository.TaskRepository
+	agentRepo      *repository.AgentRepository
+	userRepo       *repository.UserRepository
+	projectRepo    *repository.ProjectRepository
+	attachmentRepo *repository.TaskAttachmentRepository
+	planRepo       *repository.ExecutionPlanRepository
 }
 
 func NewTaskService(
@@ -29,12 +37,16 @@ func NewTaskService(
 	agentRepo *repository.AgentRepository,
 	userRepo *repository.UserRepository,
 	projectRepo *repository.ProjectRepository,
+	attachmentRepo *repository.TaskAttachmentRepository,
+	planRepo *repository.ExecutionPlanRepository,
 ) *TaskService {
 	return &TaskService{
-		taskRepo:    taskRepo,
-		agentRepo:   agentRepo,
-		userRepo:    userRepo,
-		projectRepo: projectRepo,
+		taskRepo:       taskRepo,
+		agentRepo:      agentRepo,
+		userRepo:       userRepo,
+		projectRepo:    projectRepo,
+		attachmentRepo: attachmentRepo,
+		planRepo:       planRepo,
 	}
 }
 
@@ -287,17 +299,28 @@ func (s *TaskService) StartTask(ctx context.Context, taskID int, message string,
 	return s.taskRepo.GetByIDWithDetails(ctx, taskID)
 }
 
-// CompleteTask moves task to done status
-func (s *TaskService) CompleteTask(ctx context.Context, taskID int, message string, actorID int, actorType string) (*models.TaskWithDetails, error) {
+// CompleteTask moves task to done status. Approving a task in review takes a human: its
+// reviewer or a maintainer of the project, whose projectRole the caller passes in.
+func (s *TaskService) CompleteTask(ctx context.Context, taskID int, message string, actorID int, actorType string, projectRole string) (*models.TaskWithDetails, error) {
 	task, err := s.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
 		return nil, ErrTaskNotFound
 	}
 
-	// Validate status transition: in_progress -> done
-	if task.Status != models.TaskStatusInProgress {
+	// Validate status transition: in_progress or in_review -> done
+	if task.Status != models.TaskStatusInProgress && task.Status != models.TaskStatusInReview {
 		return nil, ErrInvalidStatusTransition
 	}
+	if task.Status == models.TaskStatusInReview {
+		// Agents never approve reviews, not even their own submissions
+		if actorType != models.CreatorTypeUser {
+			return nil, ErrReviewNotAllowed
+		}
+		isReviewer := task.ReviewerID != nil && *task.ReviewerID == actorID
+		if !isReviewer && models.ProjectRoleRank(projectRole) < models.ProjectRoleRank(models.ProjectRoleMaintainer) {
+			return nil, ErrReviewNotAllowed
+		}
+	}
 
 	oldStatus := task.Status
 	newStatus := models.TaskStatusDone
@@ -401,15 +424,16 @@ func (s *TaskService) WontDoTask(ctx context.Context, taskID int, message string
 	return s.taskRepo.GetByIDWithDetails(ctx, taskID)
 }
 
-// ReopenTask moves task back to open status
+// ReopenTask moves task back to open status. Reopening a task in review requests changes: the
+// agent that completed it gets a new pending assignment for it.
 func (s *TaskService) ReopenTask(ctx context.Context, taskID int, message string, actorID int, actorType string) (*models.TaskWithDetails, error) {
 	task, err := s.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
 		return nil, ErrTaskNotFound
 	}
 
-	// Can reopen from closed, done, or wont_do
-	if task.Status != models.TaskStatusClosed && task.Status != models.TaskStatusDone && task.Status != models.TaskStatusWontDo {
+	// Can reopen from closed, done, wont_do, or in_review when a review asks for changes
+	if task.Status != models.TaskStatusClosed && task.Status != models.TaskStatusDone && task.Status != models.TaskStatusWontDo && task.Status != models.TaskStatusInReview {
 		return nil, ErrInvalidStatusTransition
 	}
 
@@ -425,6 +449,15 @@ func (s *TaskService) ReopenTask(ctx context.Context, taskID int, message string
 	if message == "" {
 		message = "Task reopened"
 	}
+	if oldStatus == models.TaskStatusInReview {
+		requeued, err := s.planRepo.RequeueCompletedAssignment(ctx, taskID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to requeue assignment: %w", err)
+		}
+		if requeued {
+			message += " (changes requested, handed back to the agent)"
+		}
+	}
 	activity := &models.TaskActivity{
 		TaskID:    taskID,
 		ActorID:   actorID,
@@ -489,3 +522,88 @@ func (s *TaskService) AddProgress(ctx context.Context, taskID int, message strin
 
 	return &activities[0], nil
 }
+
+// AddAttachment attaches an image to a task. The type is sniffed from the data rather
+// than trusted from the upload, and the upload is recorded in the task's activity.
+func (s *TaskService) AddAttachment(ctx context.Context, taskID int, fileName string, data []byte, actorID int, actorType string) (*models.TaskAttachment, error) {
+	if _, err := s.taskRepo.GetByID(ctx, taskID); err != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidAttachment)
+	}
+	if len(data) > models.MaxAttachmentBytes {
+		return nil, fmt.Errorf("%w: file is larger than %d MB", ErrInvalidAttachment, models.MaxAttachmentBytes>>20)
+	}
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(models.AttachmentContentTypes, contentType) {
+		return nil, fmt.Errorf("%w: %s is not a supported image type %v", ErrInvalidAttachment, contentType, models.AttachmentContentTypes)
+	}
+
+	attachment := &models.TaskAttachment{
+		TaskID:       taskID,
+		FileName:     filepath.Base(fileName),
+		ContentType:  contentType,
+		SizeBytes:    len(data),
+		Data:         data,
+		UploadedBy:   actorID,
+		UploaderType: actorType,
+	}
+	if err := s.attachmentRepo.Create(ctx, attachment); err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	activity := &models.TaskActivity{
+		TaskID:    taskID,
+		ActorID:   actorID,
+		ActorType: actorType,
+		Action:    models.TaskActionAttached,
+		NewValue:  &attachment.FileName,
+		Message:   "Attached " + attachment.FileName,
+	}
+	if err := s.taskRepo.CreateActivity(ctx, activity); err != nil {
+		return nil, fmt.Errorf("failed to create activity: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// GetAttachments lists a task's attachments without their data
+func (s *TaskService) GetAttachments(ctx context.Context, taskID int) (*models.TaskAttachmentListResponse, error) {
+	if _, err := s.taskRepo.GetByID(ctx, taskID); err != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	attachments, err := s.attachmentRepo.GetByTaskID(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+
+	return &models.TaskAttachmentListResponse{Attachments: attachments, TotalCount: len(attachments)}, nil
+}
+
+// GetAttachment loads one of a task's attachments with its data
+func (s *TaskService) GetAttachment(ctx context.Context, taskID, attachmentID int) (*models.TaskAttachment, error) {
+	attachments, err := s.attachmentRepo.GetWithData(ctx, []int{attachmentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	if len(attachments) == 0 || attachments[0].TaskID != taskID {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return &attachments[0], nil
+}
+
+// DeleteAttachment removes one of a task's attachments
+func (s *TaskService) DeleteAttachment(ctx context.Context, taskID, attachmentID int) error {
+	deleted, err := s.attachmentRepo.Delete(ctx, taskID, attachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if !deleted {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
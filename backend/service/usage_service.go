@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/berkkaradalan/stackflow/models"
+	repository "github.com/berkkaradalan/stackflow/repository/postgres"
+)
+
+// UsageService reports on the LLM usage ledger
+type UsageService struct {
+	usageRepo *repository.UsageRepository
+}
+
+func NewUsageService(usageRepo *repository.UsageRepository) *UsageService {
+	return &UsageService{
+		usageRepo: usageRepo,
+	}
+}
+
+// GetUsage aggregates the ledger rows matching the query, by day unless another grouping is requested
+func (s *UsageService) GetUsage(ctx context.Context, query *models.UsageQuery) (*models.UsageReportResponse, error) {
+	if query.GroupBy == "" {
+		query.GroupBy = models.UsageGroupByDay
+	}
+
+	buckets, totals, err := s.usageRepo.Aggregate(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate usage: %w", err)
+	}
+
+	return &models.UsageReportResponse{
+		GroupBy: query.GroupBy,
+		Buckets: buckets,
+		Totals:  *totals,
+	}, nil
+}
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PlaintextKeyVersion marks secrets stored before encryption was enabled
+const PlaintextKeyVersion = 0
+
+var (
+	ErrUnknownKeyVersion = errors.New("unknown encryption key version")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// KeyRing performs envelope encryption of secrets at rest.
+// Each secret is sealed with its own random data key (AES-256-GCM), and the
+// data key is wrapped with a versioned master key. Rotating the master key
+// only requires re-wrapping the data keys.
+type KeyRing struct {
+	current int
+	keys    map[int][]byte
+}
+
+// NewKeyRing builds a key ring from base64 encoded 32-byte master keys.
+// currentKey is used for new secrets; previousKeys is a comma separated
+// list of "version:base64key" pairs that can still be decrypted.
+func NewKeyRing(currentKey string, currentVersion int, previousKeys string) (*KeyRing, error) {
+	if currentKey == "" {
+		return nil, errors.New("encryption master key is required")
+	}
+	if currentVersion <= PlaintextKeyVersion {
+		return nil, fmt.Errorf("encryption key version must be greater than %d", PlaintextKeyVersion)
+	}
+
+	kr := &KeyRing{
+		current: currentVersion,
+		keys:    make(map[int][]byte),
+	}
+
+	key, err := decodeMasterKey(currentKey)
+	if err != nil {
+		return nil, err
+	}
+	kr.keys[currentVersion] = key
+
+	for _, entry := range strings.Split(previousKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid previous key entry %q, expected version:key", entry)
+		}
+
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= PlaintextKeyVersion {
+			return nil, fmt.Errorf("invalid previous key version %q", parts[0])
+		}
+		if version == currentVersion {
+			return nil, fmt.Errorf("previous key version %d clashes with the current key version", version)
+		}
+
+		key, err := decodeMasterKey(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		kr.keys[version] = key
+	}
+
+	return kr, nil
+}
+
+// CurrentVersion returns the key version used for new secrets
+func (kr *KeyRing) CurrentVersion() int {
+	return kr.current
+}
+
+// Encrypt seals plaintext under the current master key
+func (kr *KeyRing) Encrypt(plaintext string) (string, int, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", 0, err
+	}
+
+	sealed, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", 0, err
+	}
+
+	wrapped, err := seal(kr.keys[kr.current], dataKey)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return encodeEnvelope(wrapped, sealed), kr.current, nil
+}
+
+// Decrypt opens a secret sealed with the given key version.
+// Secrets at PlaintextKeyVersion are returned as-is.
+func (kr *KeyRing) Decrypt(ciphertext string, version int) (string, error) {
+	if version == PlaintextKeyVersion {
+		return ciphertext, nil
+	}
+
+	dataKey, sealed, err := kr.unwrap(ciphertext, version)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataKey, sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// Reencrypt moves a secret to the current master key. Encrypted secrets only
+// have their data key re-wrapped; plaintext secrets are encrypted.
+func (kr *KeyRing) Reencrypt(ciphertext string, version int) (string, int, error) {
+	if version == PlaintextKeyVersion {
+		return kr.Encrypt(ciphertext)
+	}
+	if version == kr.current {
+		return ciphertext, version, nil
+	}
+
+	dataKey, sealed, err := kr.unwrap(ciphertext, version)
+	if err != nil {
+		return "", 0, err
+	}
+
+	wrapped, err := seal(kr.keys[kr.current], dataKey)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return encodeEnvelope(wrapped, sealed), kr.current, nil
+}
+
+func (kr *KeyRing) unwrap(ciphertext string, version int) ([]byte, []byte, error) {
+	masterKey, ok := kr.keys[version]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnknownKeyVersion, version)
+	}
+
+	parts := strings.SplitN(ciphertext, ".", 2)
+	if len(parts) != 2 {
+		return nil, nil, ErrInvalidCiphertext
+	}
+
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, ErrInvalidCiphertext
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, ErrInvalidCiphertext
+	}
+
+	dataKey, err := open(masterKey, wrapped)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dataKey, sealed, nil
+}
+
+// MaskSecret renders a stored secret hint for display, e.g. "****abcd"
+func MaskSecret(hint string) string {
+	if hint == "" {
+		return ""
+	}
+	return "****" + hint
+}
+
+// SecretHint returns the part of a secret that is safe to store for masking
+func SecretHint(secret string) string {
+	if len(secret) <= 8 {
+		return ""
+	}
+	return secret[len(secret)-4:]
+}
+
+func decodeMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("encryption master key must be base64 encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption master key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+func encodeEnvelope(wrapped, sealed []byte) string {
+	return base64.RawStdEncoding.EncodeToString(wrapped) + "." + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+// seal encrypts with AES-GCM and prefixes the random nonce
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testMasterKey returns a base64 encoded 32-byte master key filled with b
+func testMasterKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func mustKeyRing(t *testing.T, currentKey string, currentVersion int, previousKeys string) *KeyRing {
+	t.Helper()
+
+	kr, err := NewKeyRing(currentKey, currentVersion, previousKeys)
+	if err != nil {
+		t.Fatalf("NewKeyRing: %v", err)
+	}
+	return kr
+}
+
+func TestNewKeyRing(t *testing.T) {
+	tests := []struct {
+		name         string
+		currentKey   string
+		version      int
+		previousKeys string
+		wantErr      string
+	}{
+		{name: "current key only", currentKey: testMasterKey(1), version: 1},
+		{name: "previous keys with spaces and empty entries", currentKey: testMasterKey(2), version: 2, previousKeys: " 1:" + testMasterKey(1) + " ,, "},
+		{name: "missing current key", version: 1, wantErr: "master key is required"},
+		{name: "plaintext version", currentKey: testMasterKey(1), version: PlaintextKeyVersion, wantErr: "version must be greater"},
+		{name: "current key not base64", currentKey: "not base64!", version: 1, wantErr: "base64"},
+		{name: "current key too short", currentKey: base64.StdEncoding.EncodeToString([]byte("short")), version: 1, wantErr: "32 bytes"},
+		{name: "previous entry without version", currentKey: testMasterKey(2), version: 2, previousKeys: testMasterKey(1), wantErr: "expected version:key"},
+		{name: "previous version not a number", currentKey: testMasterKey(2), version: 2, previousKeys: "one:" + testMasterKey(1), wantErr: "invalid previous key version"},
+		{name: "previous plaintext version", currentKey: testMasterKey(2), version: 2, previousKeys: "0:" + testMasterKey(1), wantErr: "invalid previous key version"},
+		{name: "previous version clashes with current", currentKey: testMasterKey(2), version: 2, previousKeys: "2:" + testMasterKey(1), wantErr: "clashes"},
+		{name: "previous key invalid", currentKey: testMasterKey(2), version: 2, previousKeys: "1:abc", wantErr: "master key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kr, err := NewKeyRing(tt.currentKey, tt.version, tt.previousKeys)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewKeyRing: %v", err)
+				}
+				if kr.CurrentVersion() != tt.version {
+					t.Errorf("current version = %d, want %d", kr.CurrentVersion(), tt.version)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyRingRoundTrip(t *testing.T) {
+	kr := mustKeyRing(t, testMasterKey(1), 1, "")
+
+	for _, plaintext := range []string{"", "sk-test-1234567890", strings.Repeat("long secret ", 100), "ünïcødé 🔑"} {
+		ciphertext, version, err := kr.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if version != 1 {
+			t.Errorf("version = %d, want 1", version)
+		}
+		if plaintext != "" && strings.Contains(ciphertext, plaintext) {
+			t.Errorf("ciphertext %q contains the plaintext", ciphertext)
+		}
+
+		got, err := kr.Decrypt(ciphertext, version)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if got != plaintext {
+			t.Errorf("Decrypt = %q, want %q", got, plaintext)
+		}
+	}
+
+	// Every secret gets its own data key and nonce
+	first, _, _ := kr.Encrypt("same")
+	second, _, _ := kr.Encrypt("same")
+	if first == second {
+		t.Error("encrypting the same plaintext twice gave the same ciphertext")
+	}
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	old := mustKeyRing(t, testMasterKey(1), 1, "")
+	ciphertext, version, err := old.Encrypt("sk-rotated")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated := mustKeyRing(t, testMasterKey(2), 2, "1:"+testMasterKey(1))
+
+	got, err := rotated.Decrypt(ciphertext, version)
+	if err != nil || got != "sk-rotated" {
+		t.Fatalf("Decrypt with previous version = %q, %v", got, err)
+	}
+
+	reencrypted, newVersion, err := rotated.Reencrypt(ciphertext, version)
+	if err != nil {
+		t.Fatalf("Reencrypt: %v", err)
+	}
+	if newVersion != 2 {
+		t.Errorf("reencrypted version = %d, want 2", newVersion)
+	}
+	// Only the data key is re-wrapped, the sealed secret is kept
+	if strings.SplitN(reencrypted, ".", 2)[1] != strings.SplitN(ciphertext, ".", 2)[1] {
+		t.Error("Reencrypt resealed the secret instead of re-wrapping its data key")
+	}
+	if got, err := rotated.Decrypt(reencrypted, newVersion); err != nil || got != "sk-rotated" {
+		t.Errorf("Decrypt after Reencrypt = %q, %v", got, err)
+	}
+
+	// Once the previous key is dropped its secrets can no longer be opened
+	current := mustKeyRing(t, testMasterKey(2), 2, "")
+	if _, err := current.Decrypt(ciphertext, version); !errors.Is(err, ErrUnknownKeyVersion) {
+		t.Errorf("Decrypt with a dropped version: got %v, want ErrUnknownKeyVersion", err)
+	}
+	if got, err := current.Decrypt(reencrypted, newVersion); err != nil || got != "sk-rotated" {
+		t.Errorf("Decrypt of the reencrypted secret = %q, %v", got, err)
+	}
+
+	// Secrets already on the current version are left as they are
+	same, sameVersion, err := rotated.Reencrypt(reencrypted, newVersion)
+	if err != nil || same != reencrypted || sameVersion != newVersion {
+		t.Errorf("Reencrypt on the current version = %q, %d, %v", same, sameVersion, err)
+	}
+}
+
+func TestKeyRingPlaintextVersion(t *testing.T) {
+	kr := mustKeyRing(t, testMasterKey(1), 1, "")
+
+	got, err := kr.Decrypt("sk-legacy", PlaintextKeyVersion)
+	if err != nil || got != "sk-legacy" {
+		t.Fatalf("Decrypt of a plaintext secret = %q, %v", got, err)
+	}
+
+	ciphertext, version, err := kr.Reencrypt("sk-legacy", PlaintextKeyVersion)
+	if err != nil {
+		t.Fatalf("Reencrypt: %v", err)
+	}
+	if version != 1 || ciphertext == "sk-legacy" {
+		t.Errorf("Reencrypt of a plaintext secret = %q, %d, want it encrypted under version 1", ciphertext, version)
+	}
+	if got, err := kr.Decrypt(ciphertext, version); err != nil || got != "sk-legacy" {
+		t.Errorf("Decrypt after Reencrypt = %q, %v", got, err)
+	}
+}
+
+func TestKeyRingRejectsBadCiphertext(t *testing.T) {
+	kr := mustKeyRing(t, testMasterKey(1), 1, "")
+	ciphertext, _, err := kr.Encrypt("sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	wrapped, sealed, _ := strings.Cut(ciphertext, ".")
+
+	// flip changes one byte of a base64 envelope part
+	flip := func(part string) string {
+		raw, err := base64.RawStdEncoding.DecodeString(part)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		raw[len(raw)-1] ^= 0xff
+		return base64.RawStdEncoding.EncodeToString(raw)
+	}
+	other, _, _ := kr.Encrypt("sk-other")
+	_, otherSealed, _ := strings.Cut(other, ".")
+
+	tests := []struct {
+		name       string
+		keyRing    *KeyRing
+		ciphertext string
+		version    int
+		wantErr    error
+	}{
+		{name: "wrong master key", keyRing: mustKeyRing(t, testMasterKey(9), 1, ""), ciphertext: ciphertext, version: 1, wantErr: ErrInvalidCiphertext},
+		{name: "unknown version", keyRing: kr, ciphertext: ciphertext, version: 7, wantErr: ErrUnknownKeyVersion},
+		{name: "tampered data key", keyRing: kr, ciphertext: flip(wrapped) + "." + sealed, version: 1, wantErr: ErrInvalidCiphertext},
+		{name: "tampered secret", keyRing: kr, ciphertext: wrapped + "." + flip(sealed), version: 1, wantErr: ErrInvalidCiphertext},
+		{name: "secret swapped from another envelope", keyRing: kr, ciphertext: wrapped + "." + otherSealed, version: 1, wantErr: ErrInvalidCiphertext},
+		{name: "missing separator", keyRing: kr, ciphertext: wrapped, version: 1, wantErr: ErrInvalidCiphertext},
+		{name: "not base64", keyRing: kr, ciphertext: "!!!." + sealed, version: 1, wantErr: ErrInvalidCiphertext},
+		{name: "truncated", keyRing: kr, ciphertext: wrapped[:4] + "." + sealed, version: 1, wantErr: ErrInvalidCiphertext},
+		{name: "empty", keyRing: kr, ciphertext: "", version: 1, wantErr: ErrInvalidCiphertext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.keyRing.Decrypt(tt.ciphertext, tt.version); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decrypt = %q, %v, want %v", got, err, tt.wantErr)
+			}
+			if _, _, err := tt.keyRing.Reencrypt(tt.ciphertext, tt.version); tt.version != tt.keyRing.CurrentVersion() && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Reencrypt: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
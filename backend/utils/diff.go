@@ -0,0 +1,55 @@
+package utils
+
+import "strings"
+
+// LineDiff compares two texts line by line using their longest common subsequence.
+// Each output line is prefixed with "+ " when added, "- " when removed and two spaces when unchanged.
+// An empty text has no lines, and a final newline ends the last line rather than starting another.
+func LineDiff(from, to string) (diff string, added int, removed int) {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + a[i] + "\n")
+			removed++
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			added++
+			j++
+		}
+	}
+
+	return out.String(), added, removed
+}
+
+// splitLines splits text into its lines without their newlines
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,90 @@
+package utils
+
+import "testing"
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		from, to    string
+		wantDiff    string
+		wantAdded   int
+		wantRemoved int
+	}{
+		{
+			name:     "identical",
+			from:     "one\ntwo\nthree",
+			to:       "one\ntwo\nthree",
+			wantDiff: "  one\n  two\n  three\n",
+		},
+		{
+			name:      "pure insert",
+			from:      "one\nthree",
+			to:        "one\ntwo\nthree",
+			wantDiff:  "  one\n+ two\n  three\n",
+			wantAdded: 1,
+		},
+		{
+			name:        "pure delete",
+			from:        "one\ntwo\nthree",
+			to:          "one\nthree",
+			wantDiff:    "  one\n- two\n  three\n",
+			wantRemoved: 1,
+		},
+		{
+			name:        "changed line",
+			from:        "one\ntwo\nthree",
+			to:          "one\n2\nthree",
+			wantDiff:    "  one\n- two\n+ 2\n  three\n",
+			wantAdded:   1,
+			wantRemoved: 1,
+		},
+		{
+			name:     "trailing newlines on both",
+			from:     "one\ntwo\n",
+			to:       "one\ntwo\n",
+			wantDiff: "  one\n  two\n",
+		},
+		{
+			name:     "trailing newline only on one side",
+			from:     "one\ntwo",
+			to:       "one\ntwo\n",
+			wantDiff: "  one\n  two\n",
+		},
+		{
+			name:      "blank line added at the end",
+			from:      "one\n",
+			to:        "one\n\n",
+			wantDiff:  "  one\n+ \n",
+			wantAdded: 1,
+		},
+		{
+			name:      "empty old version",
+			from:      "",
+			to:        "one\ntwo\n",
+			wantDiff:  "+ one\n+ two\n",
+			wantAdded: 2,
+		},
+		{
+			name:        "empty new version",
+			from:        "one\ntwo",
+			to:          "",
+			wantDiff:    "- one\n- two\n",
+			wantRemoved: 2,
+		},
+		{
+			name: "both empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, added, removed := LineDiff(tt.from, tt.to)
+			if diff != tt.wantDiff {
+				t.Errorf("diff = %q, want %q", diff, tt.wantDiff)
+			}
+			if added != tt.wantAdded || removed != tt.wantRemoved {
+				t.Errorf("added, removed = %d, %d, want %d, %d", added, removed, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
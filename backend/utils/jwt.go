@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -12,6 +14,11 @@ import (
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+
+	// Refresh token metadata, used to persist the token server-side
+	RefreshTokenID   string    `json:"-"`
+	FamilyID         string    `json:"-"`
+	RefreshExpiresAt time.Time `json:"-"`
 }
 
 type JWTManager struct {
@@ -26,6 +33,8 @@ type Claims struct {
 	Email  string `json:"email"`
 	Role   string `json:"role"`
 	Type   string `json:"type"` // "access" or "refresh"
+	// FamilyID groups every refresh token issued from the same login
+	FamilyID string `json:"fid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -43,31 +52,52 @@ func NewJWTManager(secret string, accessExpiryHours, refreshExpiryDays int) (*JW
 	}, nil
 }
 
+// GenerateTokenPair issues a token pair that starts a new refresh token family
 func (j *JWTManager) GenerateTokenPair(userID int, email, role string) (*TokenPair, error) {
-	accessToken, err := j.generateToken(userID, email, role, "access", j.accessTokenExpiry)
+	familyID, err := NewTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token family: %w", err)
+	}
+
+	return j.GenerateTokenPairForFamily(userID, email, role, familyID)
+}
+
+// GenerateTokenPairForFamily issues a token pair whose refresh token belongs to an existing family (rotation)
+func (j *JWTManager) GenerateTokenPairForFamily(userID int, email, role, familyID string) (*TokenPair, error) {
+	accessToken, _, err := j.generateToken(userID, email, role, "access", familyID, j.accessTokenExpiry)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := j.generateToken(userID, email, role, "refresh", j.refreshTokenExpiry)
+	refreshToken, refreshClaims, err := j.generateToken(userID, email, role, "refresh", familyID, j.refreshTokenExpiry)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
 	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		RefreshTokenID:   refreshClaims.ID,
+		FamilyID:         familyID,
+		RefreshExpiresAt: refreshClaims.ExpiresAt.Time,
 	}, nil
 }
 
-func (j *JWTManager) generateToken(userID int, email, role, tokenType string, expiry time.Duration) (string, error) {
+func (j *JWTManager) generateToken(userID int, email, role, tokenType, familyID string, expiry time.Duration) (string, *Claims, error) {
+	tokenID, err := NewTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
 	now := time.Now()
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		Type:   tokenType,
+	claims := &Claims{
+		UserID:   userID,
+		Email:    email,
+		Role:     role,
+		Type:     tokenType,
+		FamilyID: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -77,7 +107,12 @@ func (j *JWTManager) generateToken(userID int, email, role, tokenType string, ex
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+	signed, err := token.SignedString(j.secret)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signed, claims, nil
 }
 
 func (j *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
@@ -102,6 +137,10 @@ func (j *JWTManager) ValidateRefreshToken(tokenString string) (*Claims, error) {
 	if claims.Type != "refresh" {
 		return nil, errors.New("invalid token type: expected refresh token")
 	}
+
+	if claims.ID == "" || claims.FamilyID == "" {
+		return nil, errors.New("refresh token is missing its token ID")
+	}
 	
 	return claims, nil
 }
@@ -124,4 +163,13 @@ func (j *JWTManager) validateToken(tokenString string) (*Claims, error) {
 	}
 
 	return claims, nil
-}
\ No newline at end of file
+}
+
+// NewTokenID generates a random identifier for the jti claim
+func NewTokenID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
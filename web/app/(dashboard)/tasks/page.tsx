@@ -52,6 +52,7 @@ import { TaskDetailDialog } from "@/components/task-detail-dialog";
 const KANBAN_COLUMNS: { status: TaskStatus; label: string; color: string }[] = [
   { status: "open", label: "Open", color: "bg-blue-500" },
   { status: "in_progress", label: "In Progress", color: "bg-yellow-500" },
+  { status: "in_review", label: "In Review", color: "bg-purple-500" },
   { status: "done", label: "Done", color: "bg-green-500" },
   { status: "closed", label: "Closed", color: "bg-gray-500" },
 ];
@@ -258,6 +259,7 @@ export default function TasksPage() {
     const grouped: Record<TaskStatus, TaskWithDetails[]> = {
       open: [],
       in_progress: [],
+      in_review: [],
       done: [],
       closed: [],
       wont_do: [],
@@ -450,6 +452,7 @@ export default function TasksPage() {
                       <SelectItem value="all">All Statuses</SelectItem>
                       <SelectItem value="open">Open</SelectItem>
                       <SelectItem value="in_progress">In Progress</SelectItem>
+                      <SelectItem value="in_review">In Review</SelectItem>
                       <SelectItem value="done">Done</SelectItem>
                       <SelectItem value="closed">Closed</SelectItem>
                       <SelectItem value="wont_do">Won&apos;t Do</SelectItem>
@@ -492,7 +495,7 @@ export default function TasksPage() {
       ) : (
         <>
           {/* Main Kanban Columns */}
-          <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-4">
+          <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-5 gap-4">
             {KANBAN_COLUMNS.map(({ status, label, color }) => (
               <div key={status} className="space-y-3">
                 {/* Column Header */}
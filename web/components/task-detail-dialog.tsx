@@ -84,6 +84,20 @@ function getAvailableActions(status: TaskStatus) {
         variant: "destructive",
       });
       break;
+    case "in_review":
+      actions.push({
+        action: "complete",
+        label: "Approve",
+        icon: <CheckCircle className="h-4 w-4" />,
+        variant: "default",
+      });
+      actions.push({
+        action: "reopen",
+        label: "Request Changes",
+        icon: <RotateCcw className="h-4 w-4" />,
+        variant: "outline",
+      });
+      break;
     case "done":
       actions.push({
         action: "close",
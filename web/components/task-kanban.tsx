@@ -41,6 +41,7 @@ interface TaskKanbanProps {
 const KANBAN_COLUMNS: { status: TaskStatus; label: string }[] = [
   { status: "open", label: "Open" },
   { status: "in_progress", label: "In Progress" },
+  { status: "in_review", label: "In Review" },
   { status: "done", label: "Done" },
   { status: "closed", label: "Closed" },
 ];
@@ -242,7 +243,7 @@ export function TaskKanban({ projectId }: TaskKanbanProps) {
       </div>
 
       {/* Kanban Board */}
-      <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-4">
+      <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-5 gap-4">
         {KANBAN_COLUMNS.map(({ status, label }) => (
           <div key={status} className="space-y-3">
             {/* Column Header */}
@@ -254,6 +255,8 @@ export function TaskKanban({ projectId }: TaskKanbanProps) {
                       ? "bg-blue-500"
                       : status === "in_progress"
                       ? "bg-yellow-500"
+                      : status === "in_review"
+                      ? "bg-purple-500"
                       : status === "done"
                       ? "bg-green-500"
                       : "bg-gray-500"
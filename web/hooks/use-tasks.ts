@@ -128,6 +128,7 @@ export function useTasks(projectId: number) {
     const grouped: Record<TaskStatus, TaskWithDetails[]> = {
       open: [],
       in_progress: [],
+      in_review: [],
       done: [],
       closed: [],
       wont_do: [],
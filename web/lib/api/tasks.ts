@@ -1,7 +1,7 @@
 import { api } from "../api-client";
 
 // Task status types
-export type TaskStatus = "open" | "in_progress" | "done" | "closed" | "wont_do";
+export type TaskStatus = "open" | "in_progress" | "in_review" | "done" | "closed" | "wont_do";
 export type TaskPriority = "low" | "medium" | "high" | "critical";
 export type CreatorType = "user" | "agent";
 
@@ -179,6 +179,7 @@ export const getStatusLabel = (status: TaskStatus): string => {
   const labels: Record<TaskStatus, string> = {
     open: "Open",
     in_progress: "In Progress",
+    in_review: "In Review",
     done: "Done",
     closed: "Closed",
     wont_do: "Won't Do",
@@ -190,6 +191,7 @@ export const getStatusColor = (status: TaskStatus): string => {
   const colors: Record<TaskStatus, string> = {
     open: "bg-blue-100 text-blue-800",
     in_progress: "bg-yellow-100 text-yellow-800",
+    in_review: "bg-purple-100 text-purple-800",
     done: "bg-green-100 text-green-800",
     closed: "bg-gray-100 text-gray-800",
     wont_do: "bg-red-100 text-red-800",
@@ -1,6 +1,6 @@
 import { api } from "../api-client";
 import type { LoginCredentials, LoginResponse, User } from "./types";
-import { setAuthData, clearAuthData } from "./storage";
+import { setAuthData, clearAuthData, getRefreshToken } from "./storage";
 
 const AUTH_ENDPOINTS = {
   LOGIN: "/api/auth/login",
@@ -33,8 +33,9 @@ export async function login(credentials: LoginCredentials): Promise<User> {
  */
 export async function logout(): Promise<void> {
   try {
-    // Call logout endpoint if needed
-    await api.post(AUTH_ENDPOINTS.LOGOUT, {});
+    // Only end this device's session; without a token every session is revoked
+    const refreshToken = getRefreshToken();
+    await api.post(AUTH_ENDPOINTS.LOGOUT, refreshToken ? { refresh_token: refreshToken } : {});
   } catch {
     // Ignore errors, clear local data anyway
   } finally {